@@ -0,0 +1,83 @@
+package application_auth
+
+import (
+	"context"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+)
+
+// RequiresAuthentication is implemented by commands and queries that need an authenticated principal.
+type RequiresAuthentication interface {
+	RequiresAuthentication() bool
+}
+
+// RequiresPermissions is implemented by commands and queries that need every listed permission.
+type RequiresPermissions interface {
+	RequiredPermissions() []string
+}
+
+// RequiresRoles is implemented by commands and queries that need at least one of the listed roles.
+type RequiresRoles interface {
+	RequiredRoles() []string
+}
+
+// Policy is a custom authorization rule, principal is nil for anonymous callers.
+type Policy func(ctx context.Context, principal *Principal, dto application.Dto) error
+
+// Authorize checks the requirements declared by dto and the given policies against the principal in ctx.
+// It returns an *Unauthenticated error when a requirement exists but there is no principal and a
+// *Forbidden error when the principal doesn't meet it.
+func Authorize(ctx context.Context, dto application.Dto, policies ...Policy) error {
+	principal, authenticated := PrincipalFromContext(ctx)
+
+	var permissions, roles []string
+	needsPrincipal := false
+	if r, ok := dto.(RequiresAuthentication); ok && r.RequiresAuthentication() {
+		needsPrincipal = true
+	}
+	if r, ok := dto.(RequiresPermissions); ok {
+		permissions = r.RequiredPermissions()
+		needsPrincipal = needsPrincipal || len(permissions) > 0
+	}
+	if r, ok := dto.(RequiresRoles); ok {
+		roles = r.RequiredRoles()
+		needsPrincipal = needsPrincipal || len(roles) > 0
+	}
+
+	if needsPrincipal && !authenticated {
+		return NewUnauthenticated("authentication required")
+	}
+
+	if authenticated {
+		var missing []string
+		for _, permission := range permissions {
+			if !principal.HasPermission(permission) {
+				missing = append(missing, permission)
+			}
+		}
+		if len(missing) > 0 {
+			return NewForbidden("missing permissions", missing...)
+		}
+
+		if len(roles) > 0 && !hasAnyRole(principal, roles) {
+			return NewForbidden("missing role", roles...)
+		}
+	}
+
+	for _, policy := range policies {
+		if err := policy(ctx, principal, dto); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasAnyRole(principal *Principal, roles []string) bool {
+	for _, role := range roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
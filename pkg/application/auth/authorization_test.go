@@ -0,0 +1,93 @@
+package application_auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+)
+
+type listUsers struct{}
+
+func (listUsers) Id() string { return "list_users" }
+
+type whoAmI struct{}
+
+func (whoAmI) Id() string                   { return "who_am_i" }
+func (whoAmI) RequiresAuthentication() bool { return true }
+
+type deleteUser struct{}
+
+func (deleteUser) Id() string                    { return "delete_user" }
+func (deleteUser) RequiredPermissions() []string { return []string{"users:read", "users:delete"} }
+func (deleteUser) RequiredRoles() []string       { return []string{"admin", "support"} }
+
+func TestAuthorize(t *testing.T) {
+	errDenied := errors.New("denied by policy")
+	denyAnonymous := func(_ context.Context, principal *Principal, _ application.Dto) error {
+		if principal == nil {
+			return errDenied
+		}
+		return nil
+	}
+	allowed := &Principal{ID: "u1", Roles: []string{"support"}, Permissions: []string{"users:read", "users:delete"}}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		dto       application.Dto
+		policies  []Policy
+		// want checks the returned error
+		want func(err error) bool
+	}{
+		{"no requirement", nil, listUsers{}, nil, isNil},
+		{"authentication without principal", nil, whoAmI{}, nil, isUnauthenticated},
+		{"authentication", &Principal{ID: "u1"}, whoAmI{}, nil, isNil},
+		{"permissions without principal", nil, deleteUser{}, nil, isUnauthenticated},
+		{"missing permission", &Principal{ID: "u1", Roles: []string{"admin"}, Permissions: []string{"users:read"}}, deleteUser{}, nil,
+			isForbidden("users:delete")},
+		{"missing role", &Principal{ID: "u1", Roles: []string{"viewer"}, Permissions: []string{"users:read", "users:delete"}}, deleteUser{}, nil,
+			isForbidden("admin", "support")},
+		{"permissions and one of the roles", allowed, deleteUser{}, nil, isNil},
+		{"policy", nil, listUsers{}, []Policy{denyAnonymous}, func(err error) bool { return err == errDenied }},
+		{"policy with principal", allowed, listUsers{}, []Policy{denyAnonymous}, isNil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = ContextWithPrincipal(ctx, tt.principal)
+			}
+			if err := Authorize(ctx, tt.dto, tt.policies...); !tt.want(err) {
+				t.Errorf("Authorize = %v", err)
+			}
+		})
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	if _, ok := PrincipalFromContext(context.Background()); ok {
+		t.Error("PrincipalFromContext found a principal in an empty context")
+	}
+	if _, ok := PrincipalFromContext(ContextWithPrincipal(context.Background(), nil)); ok {
+		t.Error("PrincipalFromContext found a nil principal")
+	}
+}
+
+func isNil(err error) bool {
+	return err == nil
+}
+
+func isUnauthenticated(err error) bool {
+	var unauthenticated *Unauthenticated
+	return errors.As(err, &unauthenticated)
+}
+
+func isForbidden(missing ...string) func(error) bool {
+	return func(err error) bool {
+		var forbidden *Forbidden
+		return errors.As(err, &forbidden) && reflect.DeepEqual(forbidden.Missing, missing)
+	}
+}
@@ -0,0 +1,36 @@
+package application_auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unauthenticated is returned when the caller credentials are missing or invalid.
+type Unauthenticated struct {
+	Message string `json:"message"`
+}
+
+func NewUnauthenticated(message string) *Unauthenticated {
+	return &Unauthenticated{Message: message}
+}
+
+func (u *Unauthenticated) Error() string {
+	return u.Message
+}
+
+// Forbidden is returned when the principal isn't allowed to run a command or query.
+type Forbidden struct {
+	Message string   `json:"message"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+func NewForbidden(message string, missing ...string) *Forbidden {
+	return &Forbidden{Message: message, Missing: missing}
+}
+
+func (f *Forbidden) Error() string {
+	if len(f.Missing) == 0 {
+		return f.Message
+	}
+	return fmt.Sprintf("%s: missing %s", f.Message, strings.Join(f.Missing, ", "))
+}
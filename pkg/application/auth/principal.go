@@ -0,0 +1,45 @@
+package application_auth
+
+import "context"
+
+// Principal is the authenticated caller of a command or query.
+type Principal struct {
+	ID string
+	// Method is the authentication method that produced the principal (e.g. "jwt", "api_key", "basic").
+	Method      string
+	Roles       []string
+	Permissions []string
+	Claims      map[string]interface{}
+}
+
+// HasRole reports whether the principal has the given role.
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasPermission reports whether the principal has the given permission.
+func (p *Principal) HasPermission(permission string) bool {
+	return contains(p.Permissions, permission)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx holding the principal.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,35 @@
+package application_command
+
+import (
+	"context"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+// AuthorizingBus checks the permissions and roles declared by a command against the principal in ctx
+// before it reaches the handler.
+type AuthorizingBus struct {
+	Bus
+	policies []application_auth.Policy
+}
+
+func NewAuthorizingBus(next Bus, policies ...application_auth.Policy) *AuthorizingBus {
+	return &AuthorizingBus{Bus: next, policies: policies}
+}
+
+func (bus *AuthorizingBus) Dispatch(ctx context.Context, c application.Command) error {
+	if err := application_auth.Authorize(ctx, c, bus.policies...); err != nil {
+		return err
+	}
+
+	return bus.Bus.Dispatch(ctx, c)
+}
+
+func (bus *AuthorizingBus) DispatchAsync(ctx context.Context, c application.Command) error {
+	if err := application_auth.Authorize(ctx, c, bus.policies...); err != nil {
+		return err
+	}
+
+	return bus.Bus.DispatchAsync(ctx, c)
+}
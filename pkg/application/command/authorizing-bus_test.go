@@ -0,0 +1,94 @@
+package application_command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+type deleteUser struct {
+	UserID string
+}
+
+func (c *deleteUser) Id() string {
+	return "delete_user"
+}
+
+func (c *deleteUser) RequiredPermissions() []string {
+	return []string{"users:delete"}
+}
+
+// registeringBus records the registered commands and counts the dispatched ones.
+type registeringBus struct {
+	countingBus
+	registered []string
+}
+
+func (b *registeringBus) RegisterCommand(c application.Command, _ CommandHandler) error {
+	b.registered = append(b.registered, c.Id())
+	return nil
+}
+
+func TestAuthorizingBus(t *testing.T) {
+	anonymous := context.Background()
+	denied := application_auth.ContextWithPrincipal(anonymous, &application_auth.Principal{ID: "u1"})
+	allowed := application_auth.ContextWithPrincipal(anonymous, &application_auth.Principal{ID: "u2", Permissions: []string{"users:delete"}})
+
+	for name, dispatch := range map[string]func(Bus, context.Context, application.Command) error{
+		"Dispatch":      Bus.Dispatch,
+		"DispatchAsync": Bus.DispatchAsync,
+	} {
+		t.Run(name, func(t *testing.T) {
+			next := &registeringBus{}
+			bus := NewAuthorizingBus(next)
+
+			var unauthenticated *application_auth.Unauthenticated
+			if err := dispatch(bus, anonymous, &deleteUser{UserID: "u3"}); !errors.As(err, &unauthenticated) {
+				t.Errorf("anonymous %s = %v, want Unauthenticated", name, err)
+			}
+			var forbidden *application_auth.Forbidden
+			if err := dispatch(bus, denied, &deleteUser{UserID: "u3"}); !errors.As(err, &forbidden) {
+				t.Errorf("%s without permission = %v, want Forbidden", name, err)
+			}
+			if next.calls != 0 {
+				t.Fatalf("%d commands reached the bus, want none", next.calls)
+			}
+
+			if err := dispatch(bus, allowed, &deleteUser{UserID: "u3"}); err != nil || next.calls != 1 {
+				t.Errorf("%s with permission = %v, %d calls, want nil and 1 call", name, err, next.calls)
+			}
+		})
+	}
+}
+
+func TestAuthorizingBusPolicies(t *testing.T) {
+	errDenied := errors.New("not the owner")
+	next := &registeringBus{}
+	bus := NewAuthorizingBus(next, func(_ context.Context, principal *application_auth.Principal, dto application.Dto) error {
+		if c, ok := dto.(*deleteUser); ok && c.UserID != principal.ID {
+			return errDenied
+		}
+		return nil
+	})
+	ctx := application_auth.ContextWithPrincipal(context.Background(), &application_auth.Principal{ID: "u1", Permissions: []string{"users:delete"}})
+
+	if err := bus.Dispatch(ctx, &deleteUser{UserID: "u2"}); err != errDenied {
+		t.Errorf("Dispatch = %v, want the policy error", err)
+	}
+	if err := bus.Dispatch(ctx, &deleteUser{UserID: "u1"}); err != nil || next.calls != 1 {
+		t.Errorf("Dispatch = %v, %d calls, want nil and 1 call", err, next.calls)
+	}
+}
+
+func TestAuthorizingBusRegistersOnTheNextBus(t *testing.T) {
+	next := &registeringBus{}
+	if err := NewAuthorizingBus(next).RegisterCommand(&deleteUser{}, nil); err != nil {
+		t.Fatalf("RegisterCommand: %v", err)
+	}
+	if len(next.registered) != 1 || next.registered[0] != "delete_user" {
+		t.Errorf("registered %v, want [delete_user]", next.registered)
+	}
+}
@@ -0,0 +1,208 @@
+package application_command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// Day of month and day of week match either one when both are restricted, as in cron
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronAllHours is the hour field matching every hour.
+const cronAllHours = 1<<24 - 1
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard 5 fields cron expression, "minute hour day-of-month month day-of-week",
+// with lists, ranges, steps and month and day names, e.g. "0 2 * * *" or "*/15 9-17 * * mon-fri". The
+// @yearly, @monthly, @weekly, @daily and @hourly macros are supported too.
+func ParseCron(expr string) (*CronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, NewInvalidCronExpression(expr, "expected 5 fields")
+	}
+
+	s := &CronSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	var err error
+	for i, target := range []struct {
+		bits  *uint64
+		field cronField
+	}{{&s.minute, cronMinute}, {&s.hour, cronHour}, {&s.dom, cronDom}, {&s.month, cronMonth}, {&s.dow, cronDow}} {
+		if *target.bits, err = target.field.parse(fields[i]); err != nil {
+			return nil, NewInvalidCronExpression(expr, err.Error())
+		}
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+// parse returns the bits of the values matched by a field.
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" stands for "5-max/15"
+				high = f.max
+			}
+		}
+		if low > high {
+			return 0, fmt.Errorf("invalid range %q", rangePart)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time matching the schedule strictly after t, in the location of t, or the
+// zero time when there is none within 5 years.
+//
+// As in cron, times skipped when clocks are set forward don't match, and times repeated when they are
+// set back only match once, unless the hour field is *.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = s.advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = s.advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = s.forward(t, time.Duration(60-t.Minute())*time.Minute)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = s.forward(t, time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// advance returns next, the start of a following day or month, unless a clock change at midnight
+// moved it before t, in which case it moves t to the next hour.
+func (s *CronSchedule) advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return s.forward(t, time.Duration(60-t.Minute())*time.Minute)
+}
+
+// forward adds d to t, skipping the times repeated when clocks are set back unless the hour field is *.
+func (s *CronSchedule) forward(t time.Time, d time.Duration) time.Time {
+	next := t.Add(d)
+	if s.hour == cronAllHours {
+		return next
+	}
+
+	_, offset := t.Zone()
+	if _, nextOffset := next.Zone(); nextOffset < offset {
+		next = next.Add(time.Duration(offset-nextOffset) * time.Second)
+	}
+	return next
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+type InvalidCronExpression struct {
+	Expression string
+	Reason     string
+}
+
+func NewInvalidCronExpression(expression, reason string) InvalidCronExpression {
+	return InvalidCronExpression{Expression: expression, Reason: reason}
+}
+
+func (e InvalidCronExpression) Error() string {
+	return fmt.Sprintf("invalid cron expression %q: %s", e.Expression, e.Reason)
+}
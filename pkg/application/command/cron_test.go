@@ -0,0 +1,119 @@
+package application_command
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		_, err := ParseCron(expr)
+		var invalid InvalidCronExpression
+		if !errors.As(err, &invalid) {
+			t.Errorf("ParseCron(%q) = %v, want InvalidCronExpression", expr, err)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2026, 1, 15, 10, 7, 30, 0, time.UTC) // a Thursday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2026, 1, 16, 2, 0, 0, 0, time.UTC)},
+		{"30 9-17 * * mon-fri", time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * sat,sun", time.Date(2026, 1, 17, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week match either one when both are restricted
+		{"0 0 20 * mon", time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron: %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextAcrossDaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			// 02:30 doesn't exist on March 8, clocks going from 02:00 to 03:00
+			name: "skipped by spring forward",
+			expr: "30 2 * * *",
+			from: time.Date(2026, 3, 7, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 9, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name: "every hour across spring forward",
+			expr: "0 * * * *",
+			from: time.Date(2026, 3, 8, 0, 30, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 1, 0, 0, 0, loc),
+				time.Date(2026, 3, 8, 3, 0, 0, 0, loc),
+			},
+		},
+		{
+			// 01:30 happens twice on November 1, clocks going from 02:00 back to 01:00
+			name: "repeated by fall back",
+			expr: "30 1 * * *",
+			from: time.Date(2026, 10, 31, 12, 0, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+				time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "every hour across fall back",
+			expr: "0 * * * *",
+			from: time.Date(2026, 11, 1, 0, 30, 0, 0, loc),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC),
+				time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron: %v", err)
+			}
+
+			next := tt.from
+			for _, want := range tt.want {
+				next = schedule.Next(next)
+				if !next.Equal(want) {
+					t.Fatalf("Next = %v, want %v", next, want.In(loc))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package application_command
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+)
+
+// Deduplicatable is implemented by commands that can be deduplicated, the key identifying the command
+// instance among the commands of its type.
+type Deduplicatable interface {
+	DeduplicationKey() string
+}
+
+// DeduplicationStore remembers the commands that have been dispatched.
+type DeduplicationStore interface {
+	// Reserve records key for ttl, returning false if it was already recorded.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release forgets key so the command can be dispatched again.
+	Release(ctx context.Context, key string) error
+}
+
+// DeduplicatingBus drops Deduplicatable commands that were already dispatched within ttl, keyed on
+// their DeduplicationKey(). Other commands are passed through. Duplicates return nil without reaching
+// the handler and failed commands are released so they can be retried.
+type DeduplicatingBus struct {
+	Bus
+	store DeduplicationStore
+	ttl   time.Duration
+}
+
+func NewDeduplicatingBus(next Bus, store DeduplicationStore, ttl time.Duration) *DeduplicatingBus {
+	return &DeduplicatingBus{Bus: next, store: store, ttl: ttl}
+}
+
+func (bus *DeduplicatingBus) Dispatch(ctx context.Context, c application.Command) error {
+	key, ok := deduplicationKey(c)
+	if !ok {
+		return bus.Bus.Dispatch(ctx, c)
+	}
+
+	reserved, err := bus.store.Reserve(ctx, key, bus.ttl)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return nil
+	}
+
+	if err := bus.Bus.Dispatch(ctx, c); err != nil {
+		// The key is released even if ctx is done, the command could never be retried otherwise
+		_ = bus.store.Release(context.WithoutCancel(ctx), key)
+		return err
+	}
+
+	return nil
+}
+
+func (bus *DeduplicatingBus) DispatchAsync(ctx context.Context, c application.Command) error {
+	key, ok := deduplicationKey(c)
+	if !ok {
+		return bus.Bus.DispatchAsync(ctx, c)
+	}
+
+	reserved, err := bus.store.Reserve(ctx, key, bus.ttl)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return nil
+	}
+
+	if err := bus.Bus.DispatchAsync(ctx, c); err != nil {
+		_ = bus.store.Release(context.WithoutCancel(ctx), key)
+		return err
+	}
+
+	return nil
+}
+
+// deduplicationKey returns the key of Deduplicatable commands, scoped by the command type.
+func deduplicationKey(c application.Command) (string, bool) {
+	d, ok := c.(Deduplicatable)
+	if !ok {
+		return "", false
+	}
+	return c.Id() + ":" + d.DeduplicationKey(), true
+}
+
+// InMemoryDeduplicationStore is a DeduplicationStore for single instance deployments.
+type InMemoryDeduplicationStore struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+func NewInMemoryDeduplicationStore() *InMemoryDeduplicationStore {
+	return &InMemoryDeduplicationStore{keys: make(map[string]time.Time)}
+}
+
+func (s *InMemoryDeduplicationStore) Reserve(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, expiresAt := range s.keys {
+		if now.After(expiresAt) {
+			delete(s.keys, k)
+		}
+	}
+
+	if _, ok := s.keys[key]; ok {
+		return false, nil
+	}
+	s.keys[key] = now.Add(ttl)
+
+	return true, nil
+}
+
+func (s *InMemoryDeduplicationStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
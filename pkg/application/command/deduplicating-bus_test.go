@@ -0,0 +1,116 @@
+package application_command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+)
+
+type chargeOrder struct {
+	OrderID string
+}
+
+func (c *chargeOrder) Id() string {
+	return "charge_order"
+}
+
+func (c *chargeOrder) DeduplicationKey() string {
+	return c.OrderID
+}
+
+// countingBus counts the dispatched commands, failing while err is set.
+type countingBus struct {
+	Bus
+
+	calls int
+	err   error
+}
+
+func (b *countingBus) Dispatch(context.Context, application.Command) error {
+	b.calls++
+	return b.err
+}
+
+func (b *countingBus) DispatchAsync(ctx context.Context, c application.Command) error {
+	return b.Dispatch(ctx, c)
+}
+
+// cancelAwareStore fails the calls made with a done context, like a network store would.
+type cancelAwareStore struct {
+	DeduplicationStore
+}
+
+func (s cancelAwareStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.DeduplicationStore.Release(ctx, key)
+}
+
+func TestDeduplicatingBusDropsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	next := &countingBus{}
+	bus := NewDeduplicatingBus(next, NewInMemoryDeduplicationStore(), time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if err := bus.Dispatch(ctx, &chargeOrder{OrderID: "o1"}); err != nil {
+			t.Fatalf("Dispatch: %v", err)
+		}
+		if err := bus.DispatchAsync(ctx, &chargeOrder{OrderID: "o1"}); err != nil {
+			t.Fatalf("DispatchAsync: %v", err)
+		}
+	}
+	if err := bus.Dispatch(ctx, &chargeOrder{OrderID: "o2"}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if next.calls != 2 {
+		t.Fatalf("dispatched %d commands, want 2", next.calls)
+	}
+
+	// Commands without a deduplication key are passed through
+	for i := 0; i < 2; i++ {
+		_ = bus.Dispatch(ctx, &sendReport{})
+	}
+	if next.calls != 4 {
+		t.Errorf("dispatched %d commands, want 4", next.calls)
+	}
+}
+
+func TestDeduplicatingBusReleasesFailedCommands(t *testing.T) {
+	next := &countingBus{err: errors.New("handler failed")}
+	bus := NewDeduplicatingBus(next, cancelAwareStore{NewInMemoryDeduplicationStore()}, time.Hour)
+
+	// The caller going away doesn't keep the key reserved
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bus.Dispatch(ctx, &chargeOrder{OrderID: "o1"}); err == nil {
+		t.Fatal("Dispatch = nil, want the handler error")
+	}
+
+	next.err = nil
+	if err := bus.Dispatch(context.Background(), &chargeOrder{OrderID: "o1"}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if next.calls != 2 {
+		t.Errorf("dispatched %d commands, want the failed one retried", next.calls)
+	}
+}
+
+func TestInMemoryDeduplicationStoreExpiresKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryDeduplicationStore()
+
+	if reserved, _ := store.Reserve(ctx, "k1", 20*time.Millisecond); !reserved {
+		t.Fatal("Reserve = false, want the key reserved")
+	}
+	if reserved, _ := store.Reserve(ctx, "k1", 20*time.Millisecond); reserved {
+		t.Fatal("second Reserve = true, want false")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if reserved, _ := store.Reserve(ctx, "k1", 20*time.Millisecond); !reserved {
+		t.Error("Reserve after the ttl = false, want the key reserved again")
+	}
+}
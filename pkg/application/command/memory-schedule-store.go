@@ -0,0 +1,85 @@
+package application_command
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryScheduleStore keeps the scheduled commands in memory, they are lost on restart and only fired
+// by the process scheduling them.
+type MemoryScheduleStore struct {
+	mu       sync.Mutex
+	commands map[string]ScheduledCommand
+}
+
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{commands: make(map[string]ScheduledCommand)}
+}
+
+func (m *MemoryScheduleStore) Add(_ context.Context, c ScheduledCommand) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.commands[c.ID] = c
+	return nil
+}
+
+func (m *MemoryScheduleStore) AddRecurring(_ context.Context, c ScheduledCommand) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.commands[c.ID]; ok && stored.Cron == c.Cron {
+		c.DueAt = stored.DueAt
+	}
+	m.commands[c.ID] = c
+	return nil
+}
+
+func (m *MemoryScheduleStore) Due(_ context.Context, now time.Time, names []string, limit int) ([]ScheduledCommand, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []ScheduledCommand
+	for _, c := range m.commands {
+		if !c.DueAt.After(now) && slices.Contains(names, c.Name) {
+			due = append(due, c)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].DueAt.Before(due[j].DueAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (m *MemoryScheduleStore) Claim(_ context.Context, c ScheduledCommand, next time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.commands[c.ID]
+	if !ok || !stored.DueAt.Equal(c.DueAt) {
+		return false, nil
+	}
+
+	if next.IsZero() {
+		delete(m.commands, c.ID)
+	} else {
+		stored.DueAt = next
+		m.commands[c.ID] = stored
+	}
+	return true, nil
+}
+
+func (m *MemoryScheduleStore) Remove(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.commands, id)
+	return nil
+}
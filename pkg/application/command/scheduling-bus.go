@@ -0,0 +1,284 @@
+package application_command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+)
+
+// ScheduledCommand is a command stored until it is due.
+type ScheduledCommand struct {
+	ID string `json:"id"`
+	// Name is the ID of the command, identifying its type, Payload holding its JSON encoding
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+	DueAt   time.Time       `json:"due_at"`
+	// Cron is the cron expression of recurring commands, moved to their next occurrence once claimed
+	Cron string `json:"cron,omitempty"`
+}
+
+// ScheduleStore persists the scheduled commands, shared by the replicas firing them.
+type ScheduleStore interface {
+	// Add stores the command, replacing the one with the same ID.
+	Add(ctx context.Context, c ScheduledCommand) error
+	// AddRecurring stores the recurring command like Add, but keeps the due time of the one it replaces
+	// when their Cron is the same, so an occurrence due but not claimed yet isn't skipped.
+	AddRecurring(ctx context.Context, c ScheduledCommand) error
+	// Due returns up to limit commands due at now with one of the given names, the earliest first.
+	// Commands with other names are skipped, they are left to the replicas registering them.
+	Due(ctx context.Context, now time.Time, names []string, limit int) ([]ScheduledCommand, error)
+	// Claim atomically moves the command due time to next, or removes it when next is zero, if it is
+	// still stored with the same due time, returning false when another replica claimed it first. It
+	// locks each occurrence to a single replica.
+	Claim(ctx context.Context, c ScheduledCommand, next time.Time) (bool, error)
+	// Remove removes the command with id, if any.
+	Remove(ctx context.Context, id string) error
+}
+
+// SchedulingBus dispatches commands at a later time or on a cron schedule. Commands are stored in the
+// ScheduleStore until due, then dispatched on the next bus by the replica claiming them, so each
+// occurrence fires at most once even when every replica runs the scheduler.
+//
+// Commands are stored as JSON and must be pointers to structs, decoded into the type registered with
+// RegisterCommand or scheduled in this process.
+type SchedulingBus struct {
+	Bus
+	store    ScheduleStore
+	l        logger.Logger
+	interval time.Duration
+	location *time.Location
+
+	lock  sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewSchedulingBus creates a SchedulingBus polling the store every second.
+func NewSchedulingBus(next Bus, store ScheduleStore, l logger.Logger, options ...func(*SchedulingBus)) *SchedulingBus {
+	bus := &SchedulingBus{
+		Bus:      next,
+		store:    store,
+		l:        l,
+		interval: time.Second,
+		location: time.Local,
+		types:    make(map[string]reflect.Type),
+	}
+	for _, opt := range options {
+		opt(bus)
+	}
+	return bus
+}
+
+// WithPollInterval sets how often the store is polled for due commands, 1 second by default.
+func WithPollInterval(interval time.Duration) func(*SchedulingBus) {
+	return func(bus *SchedulingBus) {
+		bus.interval = interval
+	}
+}
+
+// WithLocation sets the time zone cron expressions are evaluated in, time.Local by default.
+func WithLocation(location *time.Location) func(*SchedulingBus) {
+	return func(bus *SchedulingBus) {
+		bus.location = location
+	}
+}
+
+// RegisterCommand registers the command type so that scheduled commands can be decoded, and the
+// handler in the next bus.
+func (bus *SchedulingBus) RegisterCommand(c application.Command, handler CommandHandler) error {
+	if _, err := bus.register(c); err != nil {
+		return err
+	}
+	return bus.Bus.RegisterCommand(c, handler)
+}
+
+// DispatchAt dispatches the command at the given time, returning the ID to cancel it with.
+func (bus *SchedulingBus) DispatchAt(ctx context.Context, c application.Command, at time.Time) (string, error) {
+	scheduled, err := bus.scheduled(newScheduleID(), c)
+	if err != nil {
+		return "", err
+	}
+	scheduled.DueAt = at
+
+	if err := bus.store.Add(ctx, scheduled); err != nil {
+		return "", err
+	}
+	return scheduled.ID, nil
+}
+
+// DispatchAfter dispatches the command once d elapsed, returning the ID to cancel it with.
+func (bus *SchedulingBus) DispatchAfter(ctx context.Context, c application.Command, d time.Duration) (string, error) {
+	return bus.DispatchAt(ctx, c, time.Now().Add(d))
+}
+
+// Every dispatches the command on the cron expression schedule, e.g. "0 2 * * *" every night at 02:00.
+// id identifies the schedule, replacing any previous one with the same ID, so every replica may
+// declare it on startup. Redeclaring a schedule with the same expression keeps its next occurrence.
+func (bus *SchedulingBus) Every(ctx context.Context, id, cron string, c application.Command) error {
+	schedule, err := ParseCron(cron)
+	if err != nil {
+		return err
+	}
+
+	scheduled, err := bus.scheduled(id, c)
+	if err != nil {
+		return err
+	}
+	scheduled.Cron = cron
+	scheduled.DueAt = schedule.Next(time.Now().In(bus.location))
+	if scheduled.DueAt.IsZero() {
+		return NewInvalidCronExpression(cron, "no occurrence within 5 years")
+	}
+
+	return bus.store.AddRecurring(ctx, scheduled)
+}
+
+// Cancel cancels the scheduled or recurring command with id.
+func (bus *SchedulingBus) Cancel(ctx context.Context, id string) error {
+	return bus.store.Remove(ctx, id)
+}
+
+// Run dispatches the due commands every poll interval until ctx is done.
+func (bus *SchedulingBus) Run(ctx context.Context) {
+	ticker := time.NewTicker(bus.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := bus.ProcessDue(ctx); err != nil {
+				bus.l.Error(ctx, "error dispatching scheduled commands", map[string]interface{}{"error": err.Error()})
+			}
+		}
+	}
+}
+
+// ProcessDue dispatches the commands due now that this replica claims. Recurring commands are moved
+// to their next occurrence when claimed, commands failing to be dispatched are not retried. Commands
+// whose type isn't registered in this replica are left to the others.
+func (bus *SchedulingBus) ProcessDue(ctx context.Context) error {
+	names := bus.names()
+	if len(names) == 0 {
+		return nil
+	}
+
+	due, err := bus.store.Due(ctx, time.Now(), names, 100)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, scheduled := range due {
+		next, err := bus.next(scheduled)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scheduled command %s: %w", scheduled.ID, err))
+		}
+		claimed, err := bus.store.Claim(ctx, scheduled, next)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := bus.fire(ctx, scheduled); err != nil {
+			errs = append(errs, fmt.Errorf("scheduled command %s: %w", scheduled.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// next returns the occurrence of recurring commands following the due one, zero for other commands
+// or when there is none.
+func (bus *SchedulingBus) next(scheduled ScheduledCommand) (time.Time, error) {
+	if scheduled.Cron == "" {
+		return time.Time{}, nil
+	}
+
+	schedule, err := ParseCron(scheduled.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Occurrences missed while no replica was running are skipped
+	next := scheduled.DueAt
+	for now := time.Now(); !next.After(now) && !next.IsZero(); {
+		next = schedule.Next(next.In(bus.location))
+	}
+	return next, nil
+}
+
+// names returns the names of the registered command types.
+func (bus *SchedulingBus) names() []string {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+
+	names := make([]string, 0, len(bus.types))
+	for name := range bus.types {
+		names = append(names, name)
+	}
+	return names
+}
+
+// fire decodes the command and dispatches it.
+func (bus *SchedulingBus) fire(ctx context.Context, scheduled ScheduledCommand) error {
+	bus.lock.RLock()
+	t, ok := bus.types[scheduled.Name]
+	bus.lock.RUnlock()
+	if !ok {
+		return NewCommandNotRegistered("Command not registered", scheduled.Name)
+	}
+
+	c := reflect.New(t.Elem()).Interface().(application.Command)
+	if err := json.Unmarshal(scheduled.Payload, c); err != nil {
+		return application.NewInvalidDto("invalid scheduled command payload: " + err.Error())
+	}
+
+	return bus.Bus.Dispatch(ctx, c)
+}
+
+// scheduled encodes the command, registering its type.
+func (bus *SchedulingBus) scheduled(id string, c application.Command) (ScheduledCommand, error) {
+	name, err := bus.register(c)
+	if err != nil {
+		return ScheduledCommand{}, err
+	}
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return ScheduledCommand{}, fmt.Errorf("failed to encode scheduled command: %w", err)
+	}
+	return ScheduledCommand{ID: id, Name: name, Payload: payload}, nil
+}
+
+// register records the type of the command by ID, returning its name.
+func (bus *SchedulingBus) register(c application.Command) (string, error) {
+	t := reflect.TypeOf(c)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return "", CommandNotValid{"only pointer to commands are allowed"}
+	}
+
+	name := c.Id()
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.types[name] = t
+
+	return name, nil
+}
+
+func newScheduleID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
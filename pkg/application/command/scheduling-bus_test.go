@@ -0,0 +1,204 @@
+package application_command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+)
+
+type sendReport struct {
+	Report string `json:"report"`
+}
+
+func (c *sendReport) Id() string {
+	return "send_report"
+}
+
+// testBus records the dispatched commands.
+type testBus struct {
+	Bus
+
+	mu         sync.Mutex
+	dispatched []string
+}
+
+func (b *testBus) RegisterCommand(application.Command, CommandHandler) error {
+	return nil
+}
+
+func (b *testBus) Dispatch(_ context.Context, c application.Command) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dispatched = append(b.dispatched, c.(*sendReport).Report)
+	return nil
+}
+
+func newTestSchedulingBus(t *testing.T, store ScheduleStore) (*SchedulingBus, *testBus) {
+	t.Helper()
+
+	next := &testBus{}
+	bus := NewSchedulingBus(next, store, nil, WithLocation(time.UTC))
+	if err := bus.RegisterCommand(&sendReport{}, nil); err != nil {
+		t.Fatalf("RegisterCommand: %v", err)
+	}
+	return bus, next
+}
+
+func TestSchedulingBusDispatchesDueCommandsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryScheduleStore()
+	bus, next := newTestSchedulingBus(t, store)
+
+	if _, err := bus.DispatchAt(ctx, &sendReport{Report: "due"}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("DispatchAt: %v", err)
+	}
+	if _, err := bus.DispatchAfter(ctx, &sendReport{Report: "later"}, time.Hour); err != nil {
+		t.Fatalf("DispatchAfter: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := bus.ProcessDue(ctx); err != nil {
+			t.Fatalf("ProcessDue: %v", err)
+		}
+	}
+	if len(next.dispatched) != 1 || next.dispatched[0] != "due" {
+		t.Errorf("dispatched %v, want [due]", next.dispatched)
+	}
+}
+
+func TestSchedulingBusMovesRecurringCommandsWhenClaimed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryScheduleStore()
+	bus, next := newTestSchedulingBus(t, store)
+
+	if err := bus.Every(ctx, "hourly-report", "0 * * * *", &sendReport{Report: "hourly"}); err != nil {
+		t.Fatalf("Every: %v", err)
+	}
+	scheduled := store.commands["hourly-report"]
+	if scheduled.Name != "send_report" {
+		t.Errorf("Name = %q, want the command ID", scheduled.Name)
+	}
+
+	// The occurrence is due, as if the process slept for a few hours
+	scheduled.DueAt = time.Now().Add(-3 * time.Hour)
+	if err := store.Add(ctx, scheduled); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := bus.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue: %v", err)
+	}
+	if len(next.dispatched) != 1 {
+		t.Fatalf("dispatched %v, want a single occurrence", next.dispatched)
+	}
+
+	moved := store.commands["hourly-report"]
+	if !moved.DueAt.After(time.Now()) || moved.DueAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("next occurrence at %v, want within the next hour", moved.DueAt)
+	}
+
+	// A replica claiming the same occurrence later gets nothing
+	if claimed, err := store.Claim(ctx, scheduled, moved.DueAt.Add(time.Hour)); err != nil || claimed {
+		t.Errorf("Claim = %v, %v, want false for an occurrence already claimed", claimed, err)
+	}
+}
+
+func TestSchedulingBusLeavesUnknownCommands(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryScheduleStore()
+	bus, next := newTestSchedulingBus(t, store)
+	if _, err := bus.DispatchAt(ctx, &sendReport{Report: "due"}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("DispatchAt: %v", err)
+	}
+
+	other := NewSchedulingBus(&testBus{}, store, nil)
+	if err := other.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue: %v", err)
+	}
+	if len(store.commands) != 1 {
+		t.Fatal("command claimed by a replica not registering its type")
+	}
+
+	if err := bus.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue: %v", err)
+	}
+	if len(next.dispatched) != 1 {
+		t.Errorf("dispatched %v, want [due]", next.dispatched)
+	}
+}
+
+func TestSchedulingBusSkipsMoreUnknownCommandsThanTheLimit(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryScheduleStore()
+	bus, next := newTestSchedulingBus(t, store)
+
+	// Commands no replica registers anymore, due before the one this replica handles
+	for i := 0; i < 150; i++ {
+		orphan := ScheduledCommand{ID: fmt.Sprintf("orphan-%d", i), Name: "removed_command", Payload: []byte(`{}`), DueAt: time.Now().Add(-time.Hour)}
+		if err := store.Add(ctx, orphan); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if _, err := bus.DispatchAt(ctx, &sendReport{Report: "due"}, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("DispatchAt: %v", err)
+	}
+
+	if err := bus.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue: %v", err)
+	}
+	if len(next.dispatched) != 1 || len(store.commands) != 150 {
+		t.Errorf("dispatched %v with %d commands left, want [due] and the orphans left", next.dispatched, len(store.commands))
+	}
+}
+
+func TestSchedulingBusEveryKeepsPendingOccurrence(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryScheduleStore()
+	bus, next := newTestSchedulingBus(t, store)
+
+	if err := bus.Every(ctx, "hourly-report", "0 * * * *", &sendReport{Report: "hourly"}); err != nil {
+		t.Fatalf("Every: %v", err)
+	}
+	scheduled := store.commands["hourly-report"]
+	scheduled.DueAt = time.Now().Add(-time.Minute)
+	if err := store.Add(ctx, scheduled); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// A replica starting while the occurrence is due doesn't skip it
+	if err := bus.Every(ctx, "hourly-report", "0 * * * *", &sendReport{Report: "redeclared"}); err != nil {
+		t.Fatalf("Every: %v", err)
+	}
+	if due := store.commands["hourly-report"].DueAt; !due.Equal(scheduled.DueAt) {
+		t.Fatalf("due at %v, want the pending occurrence at %v", due, scheduled.DueAt)
+	}
+	if err := bus.ProcessDue(ctx); err != nil {
+		t.Fatalf("ProcessDue: %v", err)
+	}
+	if len(next.dispatched) != 1 || next.dispatched[0] != "redeclared" {
+		t.Fatalf("dispatched %v, want [redeclared]", next.dispatched)
+	}
+
+	// Changing the expression reschedules the command
+	if err := bus.Every(ctx, "hourly-report", "0 0 1 1 *", &sendReport{Report: "yearly"}); err != nil {
+		t.Fatalf("Every: %v", err)
+	}
+	yearly, _ := ParseCron("0 0 1 1 *")
+	if due, want := store.commands["hourly-report"].DueAt, yearly.Next(time.Now().In(time.UTC)); !due.Equal(want) {
+		t.Errorf("due at %v, want the next occurrence of the new expression at %v", due, want)
+	}
+}
+
+func TestSchedulingBusEveryRejectsSchedulesNeverDue(t *testing.T) {
+	bus, _ := newTestSchedulingBus(t, NewMemoryScheduleStore())
+
+	err := bus.Every(context.Background(), "never", "0 0 30 2 *", &sendReport{})
+	var invalid InvalidCronExpression
+	if !errors.As(err, &invalid) {
+		t.Errorf("Every = %v, want InvalidCronExpression", err)
+	}
+}
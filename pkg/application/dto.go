@@ -8,6 +8,10 @@ type InvalidDto struct {
 	message string
 }
 
+func NewInvalidDto(message string) InvalidDto {
+	return InvalidDto{message: message}
+}
+
 func (i InvalidDto) Error() string {
 	return i.message
 }
@@ -0,0 +1,52 @@
+package application
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes why a single field of a command or query was rejected.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a command or query doesn't pass validation.
+type ValidationError struct {
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields"`
+}
+
+func NewValidationError(fields ...FieldError) *ValidationError {
+	return &ValidationError{Message: "validation failed", Fields: fields}
+}
+
+func (v *ValidationError) Error() string {
+	messages := make([]string, 0, len(v.Fields))
+	for _, f := range v.Fields {
+		messages = append(messages, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", v.Message, strings.Join(messages, "; "))
+}
+
+// BindingError is returned when a request can't be decoded into a command or query.
+type BindingError struct {
+	Message string `json:"message"`
+	err     error
+}
+
+func NewBindingError(message string, err error) *BindingError {
+	return &BindingError{Message: message, err: err}
+}
+
+func (b *BindingError) Error() string {
+	if b.err == nil {
+		return b.Message
+	}
+	return fmt.Sprintf("%s: %s", b.Message, b.err)
+}
+
+func (b *BindingError) Unwrap() error {
+	return b.err
+}
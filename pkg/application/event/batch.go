@@ -0,0 +1,310 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// NewBatchEventConsumer creates an EventConsumer handling the events in batches with handler. A batch
+// is handed to handler once it holds the batch size messages or the batch wait elapsed since its first
+// message was fetched.
+//
+// Brokers implementing domain.BatchBroker fetch the messages of a batch together, others one at a time.
+// With a partition key, batches are split by worker so that the events sharing a key are handled in
+// order.
+// Handlers report the events of a batch that failed with a *BatchError, the others being acknowledged,
+// so that only the failed ones are retried, routed to the dead letter handler or redelivered.
+func NewBatchEventConsumer(
+	broker domain.Broker,
+	event domain.Event,
+	handler domain.BatchEventHandler,
+	messageName string,
+	errorChannel chan ErrorMessage,
+	options ...func(*EventConsumer),
+) *EventConsumer {
+	options = append([]func(*EventConsumer){func(c *EventConsumer) {
+		c.batchHandler = handler
+	}}, options...)
+	return NewEventConsumer(broker, event, nil, messageName, errorChannel, options...)
+}
+
+// WithBatchSize sets the maximum number of messages of a batch, 100 by default.
+func WithBatchSize(n int) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithBatchWait sets how long a batch collects messages after its first one, 1 second by default.
+func WithBatchWait(wait time.Duration) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.batchWait = wait
+	}
+}
+
+// BatchError reports the events of a batch that failed, by index in the events handed to the
+// BatchEventHandler.
+type BatchError struct {
+	Errors map[int]error
+}
+
+// NewBatchError creates a new BatchError.
+func NewBatchError(errors map[int]error) *BatchError {
+	return &BatchError{Errors: errors}
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, i := range e.indexes() {
+		msgs = append(msgs, fmt.Sprintf("event %d: %s", i, e.Errors[i]))
+	}
+	return fmt.Sprintf("%d events of the batch failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, i := range e.indexes() {
+		errs = append(errs, e.Errors[i])
+	}
+	return errs
+}
+
+func (e *BatchError) indexes() []int {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// consumeBatches collects batches until fetchCtx is done, handling them with the workers.
+func (c *EventConsumer) consumeBatches(fetchCtx, handleCtx context.Context) {
+	// Without partition key the workers share a single queue
+	queues := make([]chan []delivery, 1)
+	if c.partitionKey != nil {
+		queues = make([]chan []delivery, c.workers)
+	}
+	for i := range queues {
+		queues[i] = make(chan []delivery)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		queue := queues[i%len(queues)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				c.handleBatch(fetchCtx, handleCtx, batch)
+			}
+		}()
+	}
+
+	defer func() {
+		// Drain the batches already collected
+		for _, queue := range queues {
+			close(queue)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		if c.breaker != nil && c.breaker.Wait(fetchCtx) != nil {
+			return
+		}
+
+		batch := c.collect(fetchCtx, handleCtx)
+		if len(batch) == 0 {
+			if c.breaker != nil {
+				c.breaker.release()
+			}
+			if fetchCtx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		// Wait for free workers, leaving the next messages in the broker meanwhile
+		for i, part := range c.split(batch, len(queues)) {
+			if len(part) > 0 {
+				queues[i] <- part
+			}
+		}
+	}
+}
+
+// split splits batch by worker queue, keeping the order of the events of each queue.
+func (c *EventConsumer) split(batch []delivery, queues int) [][]delivery {
+	if queues == 1 {
+		return [][]delivery{batch}
+	}
+
+	parts := make([][]delivery, queues)
+	for _, d := range batch {
+		i := c.queueIndex(d.event, queues)
+		parts[i] = append(parts[i], d)
+	}
+	return parts
+}
+
+// collect fetches and decodes the messages of the next batch, empty when no message arrived before
+// the broker timed out.
+func (c *EventConsumer) collect(fetchCtx, ctx context.Context) []delivery {
+	batch, err := c.collectMore(fetchCtx, ctx, nil)
+	if err != nil || len(batch) == 0 {
+		return batch
+	}
+
+	// The batch wait starts with the first message
+	waitCtx, cancel := context.WithTimeout(fetchCtx, c.batchWait)
+	defer cancel()
+	for err == nil && len(batch) < c.batchSize && waitCtx.Err() == nil {
+		batch, err = c.collectMore(waitCtx, ctx, batch)
+	}
+	return batch
+}
+
+// collectMore fetches and decodes messages up to the batch size, appending them to batch.
+func (c *EventConsumer) collectMore(fetchCtx, ctx context.Context, batch []delivery) ([]delivery, error) {
+	messages, err := c.fetchBatch(fetchCtx, c.batchSize-len(batch))
+	// Errors caused by the batch wait or the consumer stopping aren't reported
+	if err != nil && fetchCtx.Err() == nil {
+		log.Printf("Error fetching messages: %s\n", err)
+		c.sendError(err, nil)
+	}
+
+	for _, message := range messages {
+		if d, ok := c.decodeMessage(ctx, message); ok {
+			batch = append(batch, d)
+		}
+	}
+	return batch, err
+}
+
+// fetchBatch fetches up to max messages, one at a time when the broker doesn't fetch batches.
+func (c *EventConsumer) fetchBatch(ctx context.Context, max int) ([]*domain.Message, error) {
+	if bb, ok := c.broker.(domain.BatchBroker); ok {
+		return bb.FetchBatch(ctx, max)
+	}
+
+	message, err := c.fetch(ctx)
+	if err != nil || message == nil {
+		return nil, err
+	}
+	return []*domain.Message{message}, nil
+}
+
+// handleBatch handles a batch, acknowledging the messages handled and retrying the failed ones until
+// stopCtx is done.
+func (c *EventConsumer) handleBatch(stopCtx, ctx context.Context, batch []delivery) {
+	pending := batch
+	var failed []delivery
+	errs := make(map[*domain.Message]error)
+	attempts := make(map[*domain.Message]int)
+
+	_, interrupted, err := retry(stopCtx, policyOf(c.batchHandler, c.retryPolicy), func() error {
+		for _, d := range pending {
+			attempts[d.message]++
+		}
+		err := c.callBatch(ctx, pending)
+		if err == nil {
+			c.ackAll(ctx, pending)
+			pending = nil
+			return nil
+		}
+		log.Printf("Error processing batch: %s\n", err)
+
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			for _, d := range pending {
+				errs[d.message] = err
+			}
+			return err
+		}
+
+		// Only the failed events are retried, the permanent failures are not
+		var retryable []delivery
+		retryableErrs := make(map[int]error)
+		for i, d := range pending {
+			eventErr, ok := batchErr.Errors[i]
+			switch {
+			case !ok:
+				c.ack(ctx, d.message)
+			case IsPermanent(eventErr):
+				errs[d.message] = eventErr
+				failed = append(failed, d)
+			default:
+				errs[d.message] = eventErr
+				retryableErrs[len(retryable)] = eventErr
+				retryable = append(retryable, d)
+			}
+		}
+		pending = retryable
+		if len(pending) == 0 {
+			return nil
+		}
+		return NewBatchError(retryableErrs)
+	})
+	if interrupted {
+		// The consumer is stopping, the messages are redelivered instead of waiting to be retried
+		for _, d := range pending {
+			c.nack(ctx, d.message)
+		}
+		pending = nil
+	}
+	if err != nil {
+		failed = append(failed, pending...)
+	}
+
+	if c.breaker != nil {
+		if len(failed) == 0 {
+			c.breaker.RecordSuccess()
+		} else {
+			c.breaker.RecordFailure()
+		}
+	}
+
+	for _, d := range failed {
+		c.fail(ctx, d, errs[d.message], attempts[d.message])
+	}
+}
+
+// callBatch runs the batch handler once, turning a panic into an error.
+func (c *EventConsumer) callBatch(ctx context.Context, batch []delivery) (err error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	// Recover from panic for this batch processing
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic: %v", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	events := make([]domain.Event, len(batch))
+	for i, d := range batch {
+		events[i] = d.event
+	}
+	return c.batchHandler.HandleBatch(ctx, events)
+}
+
+func (c *EventConsumer) ackAll(ctx context.Context, batch []delivery) {
+	for _, d := range batch {
+		c.ack(ctx, d.message)
+	}
+}
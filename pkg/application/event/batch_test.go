@@ -0,0 +1,139 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+type batchHandlerFunc func(ctx context.Context, events []domain.Event) error
+
+func (f batchHandlerFunc) HandleBatch(ctx context.Context, events []domain.Event) error {
+	return f(ctx, events)
+}
+
+func TestBatchConsumerHandlesBatches(t *testing.T) {
+	broker := newTestBroker()
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+	handler := batchHandlerFunc(func(_ context.Context, events []domain.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, len(events))
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		broker.publish(t, newTestEvent("a", i), 1)
+	}
+	startConsumer(t, NewBatchEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithBatchSize(3), WithBatchWait(50*time.Millisecond)))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 5
+	}, "messages not acknowledged")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 2 {
+		t.Errorf("batch sizes %v, want [3 2]", sizes)
+	}
+}
+
+func TestBatchConsumerRetriesFailedEvents(t *testing.T) {
+	broker := newTestBroker()
+	var handled [][]int
+	handler := batchHandlerFunc(func(_ context.Context, events []domain.Event) error {
+		var ns []int
+		errs := make(map[int]error)
+		for i, event := range events {
+			n := event.(*testEvent).Data.N
+			ns = append(ns, n)
+			if n == 1 && len(handled) == 0 {
+				errs[i] = errors.New("failed")
+			}
+		}
+		handled = append(handled, ns)
+		if len(errs) > 0 {
+			return NewBatchError(errs)
+		}
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		broker.publish(t, newTestEvent("a", i), 1)
+	}
+	startConsumer(t, NewBatchEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithBatchSize(3), WithRetryPolicy(ImmediateRetry(2))))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 3
+	}, "messages not acknowledged")
+	if len(handled) != 2 || len(handled[1]) != 1 || handled[1][0] != 1 {
+		t.Errorf("handled %v, want the failed event retried alone", handled)
+	}
+}
+
+func TestBatchConsumerPartitionKeyKeepsOrder(t *testing.T) {
+	broker := newTestBroker()
+	var (
+		mu      sync.Mutex
+		handled = make(map[string][]int)
+	)
+	handler := batchHandlerFunc(func(_ context.Context, events []domain.Event) error {
+		// A slow first batch lets the following ones overtake it unless they wait for the same worker
+		time.Sleep(time.Duration(len(events)) * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range events {
+			e := event.(*testEvent)
+			handled[e.AggregateID()] = append(handled[e.AggregateID()], e.Data.N)
+		}
+		return nil
+	})
+
+	aggregates := []string{"a", "b", "c", "d"}
+	for i := 0; i < 40; i++ {
+		broker.publish(t, newTestEvent(aggregates[i%len(aggregates)], i), 1)
+	}
+	startConsumer(t, NewBatchEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithBatchSize(5), WithBatchWait(10*time.Millisecond), WithWorkers(4), WithPartitionKey(ByAggregateID)))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 40
+	}, "messages not acknowledged")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for aggregate, ns := range handled {
+		for i := 1; i < len(ns); i++ {
+			if ns[i] < ns[i-1] {
+				t.Errorf("events of %s handled out of order: %v", aggregate, ns)
+				break
+			}
+		}
+	}
+}
+
+func TestBatchConsumerRejectsMaxInFlight(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewBatchEventConsumer didn't panic with WithMaxInFlight")
+		}
+	}()
+
+	handler := batchHandlerFunc(func(context.Context, []domain.Event) error {
+		return nil
+	})
+	NewBatchEventConsumer(newTestBroker(), &testEvent{}, handler, "test.happened", nil, WithMaxInFlight(10))
+}
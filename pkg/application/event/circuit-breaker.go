@@ -0,0 +1,173 @@
+package application_event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets messages through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen pauses consumption until the open timeout elapses.
+	CircuitOpen
+	// CircuitHalfOpen lets a single message through, closing the circuit when it succeeds and opening
+	// it again otherwise.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker pauses consumption when messages keep failing, e.g. because a downstream service is
+// down, instead of burning their retries. It opens after threshold consecutive failures and lets a
+// message through again once openTimeout elapsed.
+//
+// A CircuitBreaker may be shared by several consumers depending on the same downstream.
+type CircuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	changed  chan struct{}
+}
+
+// NewCircuitBreaker creates a new closed CircuitBreaker.
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		changed:     make(chan struct{}),
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.openTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Wait blocks until a message may be consumed or ctx is done. Once half-open, a single caller is let
+// through until its result is recorded.
+func (b *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if b.state == CircuitOpen && time.Since(b.openedAt) >= b.openTimeout {
+			b.transition(CircuitHalfOpen)
+		}
+
+		var wait time.Duration
+		switch b.state {
+		case CircuitClosed:
+			b.mu.Unlock()
+			return nil
+		case CircuitHalfOpen:
+			if !b.probing {
+				b.probing = true
+				b.mu.Unlock()
+				return nil
+			}
+		case CircuitOpen:
+			wait = b.openTimeout - time.Since(b.openedAt)
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		var timeout <-chan time.Time
+		var t *time.Timer
+		if wait > 0 {
+			t = time.NewTimer(wait)
+			timeout = t.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeout:
+		}
+		if t != nil {
+			t.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// RecordSuccess records a handled message, closing a half-open circuit.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	// Messages fetched before the circuit opened don't close it
+	if b.state == CircuitHalfOpen {
+		b.transition(CircuitClosed)
+	}
+}
+
+// RecordFailure records a failed message, opening the circuit once the threshold is reached or when
+// half-open.
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.transition(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		b.transition(CircuitOpen)
+	}
+}
+
+// release lets another caller through a half-open circuit when the one let through had no message
+// to consume.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen && b.probing {
+		b.probing = false
+		close(b.changed)
+		b.changed = make(chan struct{})
+	}
+}
+
+// transition changes the state and wakes the waiting callers, b.mu must be held.
+func (b *CircuitBreaker) transition(state CircuitState) {
+	b.state = state
+	b.probing = false
+	if state == CircuitOpen {
+		b.openedAt = time.Now()
+	}
+	if state == CircuitClosed {
+		b.failures = 0
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,74 @@
+package application_event
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	breaker := NewCircuitBreaker(3, time.Minute)
+
+	breaker.RecordFailure()
+	breaker.RecordFailure()
+	breaker.RecordSuccess()
+	breaker.RecordFailure()
+	breaker.RecordFailure()
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state = %v, want closed as a success resets the failures", state)
+	}
+
+	breaker.RecordFailure()
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state = %v, want open", state)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := breaker.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait = %v, want %v while open", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 20*time.Millisecond)
+	breaker.RecordFailure()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := breaker.Wait(ctx); err != nil {
+		t.Fatalf("Wait = %v, want nil once the open timeout elapsed", err)
+	}
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Fatalf("state = %v, want half-open", state)
+	}
+
+	// A single caller is let through until its result is recorded
+	probing, cancelProbing := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelProbing()
+	if err := breaker.Wait(probing); err != context.DeadlineExceeded {
+		t.Fatalf("second Wait = %v, want %v while probing", err, context.DeadlineExceeded)
+	}
+
+	released := make(chan error, 1)
+	go func() {
+		released <- breaker.Wait(ctx)
+	}()
+	breaker.release()
+	if err := <-released; err != nil {
+		t.Fatalf("Wait = %v after release, want nil", err)
+	}
+
+	breaker.RecordFailure()
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state = %v, want open again after a failed probe", state)
+	}
+
+	if err := breaker.Wait(ctx); err != nil {
+		t.Fatalf("Wait = %v, want nil once the open timeout elapsed", err)
+	}
+	breaker.RecordSuccess()
+	if state := breaker.State(); state != CircuitClosed {
+		t.Fatalf("state = %v, want closed after a successful probe", state)
+	}
+}
@@ -0,0 +1,313 @@
+package application_event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// ContentTypeHeader is the message header holding the content type of the message data.
+const ContentTypeHeader = "content-type"
+
+// Codec converts events to broker messages and back, it is shared by publishers, brokers and consumers
+// so they agree on the wire format.
+type Codec interface {
+	Encode(event domain.Event) (*domain.Message, error)
+	Decode(msg *domain.Message) (Envelope, error)
+}
+
+// JSONCodec encodes events as the JSON envelope written by domain.BaseEvent:
+//
+//	{"event_id": "...", "event_name": "...", "aggregate_id": "...", "occurred_on": "...", "version": 1,
+//	 "correlation_id": "...", "causation_id": "...", "metadata": {}, "payload": {...}}
+//
+// Decoding also accepts a payload encoded as a JSON string and objects without payload, read as the
+// payload itself.
+type JSONCodec struct{}
+
+func NewJSONCodec() JSONCodec {
+	return JSONCodec{}
+}
+
+type jsonEnvelope struct {
+	ID            string                 `json:"event_id"`
+	Name          string                 `json:"event_name"`
+	AggregateID   string                 `json:"aggregate_id"`
+	OccurredOn    time.Time              `json:"occurred_on"`
+	Version       int                    `json:"version"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	CausationID   string                 `json:"causation_id,omitempty"`
+	Metadata      map[string]string      `json:"metadata,omitempty"`
+	Payload       map[string]interface{} `json:"payload"`
+}
+
+func (JSONCodec) Encode(event domain.Event) (*domain.Message, error) {
+	e := NewEnvelope(event)
+	data, err := json.Marshal(jsonEnvelope{
+		ID:            e.ID,
+		Name:          e.Name,
+		AggregateID:   e.AggregateID,
+		OccurredOn:    e.OccurredOn,
+		Version:       e.Version,
+		CorrelationID: e.CorrelationID,
+		CausationID:   e.CausationID,
+		Metadata:      e.Metadata,
+		Payload:       e.Payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize event: %w", err)
+	}
+
+	return &domain.Message{Data: data, Headers: map[string]string{ContentTypeHeader: "application/json"}}, nil
+}
+
+func (JSONCodec) Decode(msg *domain.Message) (Envelope, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(msg.Data, &raw); err != nil {
+		return Envelope{}, fmt.Errorf("failed to deserialize event: %w", err)
+	}
+
+	e := Envelope{
+		ID:            stringValue(raw[domain.EventIDKey]),
+		Name:          stringValue(raw[domain.EventNameKey]),
+		AggregateID:   stringValue(raw[domain.AggregateIDKey]),
+		CorrelationID: stringValue(raw[domain.CorrelationIDKey]),
+		CausationID:   stringValue(raw[domain.CausationIDKey]),
+	}
+
+	var err error
+	if e.Version, err = versionValue(raw[domain.VersionKey]); err != nil {
+		return Envelope{}, err
+	}
+	occurredOn := raw[domain.OccurredOnKey]
+	if occurredOn == nil {
+		// Written by the Redis stream broker before the codec was introduced
+		occurredOn = raw["occurred_at"]
+	}
+	if e.OccurredOn, err = timeValue(occurredOn); err != nil {
+		return Envelope{}, err
+	}
+
+	if metadata, ok := raw[domain.MetadataKey].(map[string]interface{}); ok {
+		e.Metadata = make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			e.Metadata[k] = stringValue(v)
+		}
+	}
+
+	switch payload := raw[domain.PayloadKey].(type) {
+	case map[string]interface{}:
+		e.Payload = payload
+	case string:
+		if err := json.Unmarshal([]byte(payload), &e.Payload); err != nil {
+			return Envelope{}, fmt.Errorf("failed to deserialize event payload: %w", err)
+		}
+	case nil:
+		e.Payload = raw
+	default:
+		return Envelope{}, fmt.Errorf("invalid event payload %v", payload)
+	}
+
+	return e, nil
+}
+
+// CloudEventsMode selects how CloudEventsCodec lays out events in messages.
+type CloudEventsMode int
+
+const (
+	// CloudEventsStructured writes the whole event as an application/cloudevents+json document.
+	CloudEventsStructured CloudEventsMode = iota
+	// CloudEventsBinary writes the attributes as "ce-" prefixed headers and the payload as data.
+	CloudEventsBinary
+)
+
+const (
+	cloudEventsSpecVersion  = "1.0"
+	cloudEventsContentType  = "application/cloudevents+json"
+	cloudEventsHeaderPrefix = "ce-"
+)
+
+// CloudEvents extension attributes holding the envelope fields with no CloudEvents counterpart.
+const (
+	cloudEventsVersion       = "eventversion"
+	cloudEventsCorrelationID = "correlationid"
+	cloudEventsCausationID   = "causationid"
+)
+
+// CloudEventsCodec encodes events as CloudEvents 1.0: the event name is the type, the aggregate ID the
+// subject, the version, correlation and causation IDs the eventversion, correlationid and causationid
+// extensions. Metadata entries are written as extensions, their keys lower cased and stripped of the
+// characters CloudEvents doesn't allow.
+//
+// Decoding reads binary messages, recognized by their ce-specversion header, whatever the mode.
+type CloudEventsCodec struct {
+	Source string
+	Mode   CloudEventsMode
+}
+
+func NewCloudEventsCodec(source string, mode CloudEventsMode) CloudEventsCodec {
+	return CloudEventsCodec{Source: source, Mode: mode}
+}
+
+func (c CloudEventsCodec) Encode(event domain.Event) (*domain.Message, error) {
+	e := NewEnvelope(event)
+
+	attributes := map[string]string{
+		"specversion": cloudEventsSpecVersion,
+		"id":          e.ID,
+		"source":      c.Source,
+		"type":        e.Name,
+	}
+	if e.AggregateID != "" {
+		attributes["subject"] = e.AggregateID
+	}
+	if !e.OccurredOn.IsZero() {
+		attributes["time"] = e.OccurredOn.UTC().Format(time.RFC3339Nano)
+	}
+	for k, v := range e.Metadata {
+		if name := extensionName(k); name != "" {
+			attributes[name] = v
+		}
+	}
+	if e.Version != 0 {
+		attributes[cloudEventsVersion] = fmt.Sprint(e.Version)
+	}
+	if e.CorrelationID != "" {
+		attributes[cloudEventsCorrelationID] = e.CorrelationID
+	}
+	if e.CausationID != "" {
+		attributes[cloudEventsCausationID] = e.CausationID
+	}
+
+	payload := e.Payload
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+
+	if c.Mode == CloudEventsBinary {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize event: %w", err)
+		}
+
+		headers := map[string]string{ContentTypeHeader: "application/json"}
+		for k, v := range attributes {
+			headers[cloudEventsHeaderPrefix+k] = v
+		}
+		return &domain.Message{Data: data, Headers: headers}, nil
+	}
+
+	document := make(map[string]interface{}, len(attributes)+2)
+	for k, v := range attributes {
+		document[k] = v
+	}
+	document["datacontenttype"] = "application/json"
+	document["data"] = payload
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize event: %w", err)
+	}
+	return &domain.Message{Data: data, Headers: map[string]string{ContentTypeHeader: cloudEventsContentType}}, nil
+}
+
+func (c CloudEventsCodec) Decode(msg *domain.Message) (Envelope, error) {
+	attributes := make(map[string]interface{})
+	var payload interface{}
+
+	if specVersion := header(msg.Headers, cloudEventsHeaderPrefix+"specversion"); specVersion != "" {
+		for k, v := range msg.Headers {
+			if name := strings.ToLower(k); strings.HasPrefix(name, cloudEventsHeaderPrefix) {
+				attributes[strings.TrimPrefix(name, cloudEventsHeaderPrefix)] = v
+			}
+		}
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &payload); err != nil {
+				return Envelope{}, fmt.Errorf("failed to deserialize event payload: %w", err)
+			}
+		}
+	} else {
+		if err := json.Unmarshal(msg.Data, &attributes); err != nil {
+			return Envelope{}, fmt.Errorf("failed to deserialize event: %w", err)
+		}
+		payload = attributes["data"]
+		if s, ok := payload.(string); ok {
+			if err := json.Unmarshal([]byte(s), &payload); err != nil {
+				return Envelope{}, fmt.Errorf("failed to deserialize event payload: %w", err)
+			}
+		}
+	}
+
+	if v := stringValue(attributes["specversion"]); v != cloudEventsSpecVersion {
+		return Envelope{}, fmt.Errorf("unsupported cloudevents specversion %q", v)
+	}
+
+	e := Envelope{
+		ID:            stringValue(attributes["id"]),
+		Name:          stringValue(attributes["type"]),
+		AggregateID:   stringValue(attributes["subject"]),
+		CorrelationID: stringValue(attributes[cloudEventsCorrelationID]),
+		CausationID:   stringValue(attributes[cloudEventsCausationID]),
+	}
+
+	var err error
+	if e.Version, err = versionValue(attributes[cloudEventsVersion]); err != nil {
+		return Envelope{}, err
+	}
+	if e.OccurredOn, err = timeValue(attributes["time"]); err != nil {
+		return Envelope{}, err
+	}
+
+	switch p := payload.(type) {
+	case map[string]interface{}:
+		e.Payload = p
+	case nil:
+	default:
+		return Envelope{}, fmt.Errorf("invalid event payload %v", p)
+	}
+
+	for k, v := range attributes {
+		if cloudEventsAttributes[k] {
+			continue
+		}
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata[k] = stringValue(v)
+	}
+
+	return e, nil
+}
+
+var cloudEventsAttributes = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true, "subject": true, "time": true,
+	"datacontenttype": true, "dataschema": true, "data": true, "data_base64": true,
+	cloudEventsVersion: true, cloudEventsCorrelationID: true, cloudEventsCausationID: true,
+}
+
+// extensionName returns key as a valid CloudEvents extension name, empty when nothing is left of it.
+func extensionName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	if cloudEventsAttributes[b.String()] {
+		return ""
+	}
+	return b.String()
+}
+
+// header returns the value of the header name, compared case insensitively.
+func header(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
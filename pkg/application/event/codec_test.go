@@ -0,0 +1,172 @@
+package application_event
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+func newCodecTestEvent() *testEvent {
+	event := newTestEvent("a1", 3)
+	event.SetCorrelationID("c1")
+	event.SetCausationID("e0")
+	event.SetMetadata("tenant", "t1")
+	return event
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		codec       Codec
+		contentType string
+	}{
+		{"json", NewJSONCodec(), "application/json"},
+		{"cloudevents structured", NewCloudEventsCodec("orders", CloudEventsStructured), "application/cloudevents+json"},
+		{"cloudevents binary", NewCloudEventsCodec("orders", CloudEventsBinary), "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := newCodecTestEvent()
+			msg, err := tt.codec.Encode(event)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if ct := msg.Headers[ContentTypeHeader]; ct != tt.contentType {
+				t.Errorf("content type = %q, want %q", ct, tt.contentType)
+			}
+
+			envelope, err := tt.codec.Decode(msg)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			want := NewEnvelope(event)
+			if !envelope.OccurredOn.Equal(want.OccurredOn) {
+				t.Errorf("OccurredOn = %v, want %v", envelope.OccurredOn, want.OccurredOn)
+			}
+			envelope.OccurredOn = want.OccurredOn
+			if !reflect.DeepEqual(envelope, want) {
+				t.Errorf("Decode = %+v, want %+v", envelope, want)
+			}
+
+			// The envelope decodes back into the event
+			decoded := newTestEvent("", 0)
+			if err := decoded.FromMap(envelope.Map()); err != nil {
+				t.Fatalf("FromMap: %v", err)
+			}
+			if decoded.EventID() != event.EventID() || decoded.AggregateID() != "a1" || decoded.Data.N != 3 ||
+				decoded.CorrelationID() != "c1" || decoded.CausationID() != "e0" || decoded.Metadata()["tenant"] != "t1" {
+				t.Errorf("decoded event = %+v, want %+v", decoded, event)
+			}
+		})
+	}
+}
+
+func TestCloudEventsCodecAttributes(t *testing.T) {
+	event := newCodecTestEvent()
+	event.SetMetadata("Trace-ID", "tr1")
+	event.SetMetadata("type", "overridden")
+
+	msg, err := NewCloudEventsCodec("orders", CloudEventsBinary).Encode(event)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	want := map[string]string{
+		"ce-specversion":   "1.0",
+		"ce-id":            event.EventID(),
+		"ce-source":        "orders",
+		"ce-type":          "test.happened",
+		"ce-subject":       "a1",
+		"ce-time":          event.OccurredOn().UTC().Format(time.RFC3339Nano),
+		"ce-eventversion":  "1",
+		"ce-correlationid": "c1",
+		"ce-causationid":   "e0",
+		"ce-tenant":        "t1",
+		// Metadata keys are stripped of the characters CloudEvents doesn't allow, and can't override
+		// the attributes
+		"ce-traceid":   "tr1",
+		"content-type": "application/json",
+	}
+	if !reflect.DeepEqual(msg.Headers, want) {
+		t.Errorf("headers = %v, want %v", msg.Headers, want)
+	}
+	if string(msg.Data) != `{"n":3}` {
+		t.Errorf("data = %s, want the payload", msg.Data)
+	}
+}
+
+func TestCloudEventsCodecDecodesBinaryMessagesInStructuredMode(t *testing.T) {
+	msg := &domain.Message{
+		Data: []byte(`{"n":3}`),
+		Headers: map[string]string{
+			"Ce-Specversion": "1.0",
+			"Ce-Id":          "e1",
+			"Ce-Type":        "test.happened",
+			"Ce-Source":      "orders",
+		},
+	}
+
+	envelope, err := NewCloudEventsCodec("orders", CloudEventsStructured).Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if envelope.ID != "e1" || envelope.Name != "test.happened" || envelope.Payload["n"] != float64(3) {
+		t.Errorf("Decode = %+v", envelope)
+	}
+}
+
+func TestCloudEventsCodecRejectsOtherSpecVersions(t *testing.T) {
+	msg := &domain.Message{Data: []byte(`{"specversion":"0.3","id":"e1","type":"test.happened","data":{}}`)}
+	if _, err := NewCloudEventsCodec("orders", CloudEventsStructured).Decode(msg); err == nil || !strings.Contains(err.Error(), "specversion") {
+		t.Errorf("Decode = %v, want an unsupported specversion error", err)
+	}
+}
+
+func TestJSONCodecDecodesPayloadVariants(t *testing.T) {
+	occurredOn := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		data string
+		want Envelope
+	}{
+		{
+			name: "legacy redis stream entry",
+			// Flat fields written by the Redis stream broker before the codec, the payload being a JSON
+			// string and the time under occurred_at
+			data: `{"aggregate_id":"a1","event_name":"test.happened","occurred_at":"2026-01-15T10:00:00Z","correlation_id":"c1","payload":"{\"n\":3}"}`,
+			want: Envelope{Name: "test.happened", AggregateID: "a1", OccurredOn: occurredOn, CorrelationID: "c1",
+				Payload: map[string]interface{}{"n": float64(3)}},
+		},
+		{
+			name: "string version",
+			data: `{"event_id":"e1","event_name":"test.happened","version":"2","payload":{"n":3}}`,
+			want: Envelope{ID: "e1", Name: "test.happened", Version: 2, Payload: map[string]interface{}{"n": float64(3)}},
+		},
+		{
+			name: "without payload",
+			data: `{"event_name":"test.happened","n":3}`,
+			want: Envelope{Name: "test.happened", Payload: map[string]interface{}{"event_name": "test.happened", "n": float64(3)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := NewJSONCodec().Decode(&domain.Message{Data: []byte(tt.data)})
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(envelope, tt.want) {
+				t.Errorf("Decode = %+v, want %+v", envelope, tt.want)
+			}
+		})
+	}
+
+	for _, data := range []string{`not json`, `{"version":"one"}`, `{"occurred_on":"yesterday"}`, `{"payload":3}`} {
+		if _, err := NewJSONCodec().Decode(&domain.Message{Data: []byte(data)}); err == nil {
+			t.Errorf("Decode(%s) = nil error", data)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package application_event
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/thebranchcrafter/go-kit/pkg/domain"
+	"hash/fnv"
 	"log"
+	"reflect"
+	"sync"
+	"time"
 )
 
 // EventConsumer represents a generic consumer.
@@ -14,7 +17,29 @@ type EventConsumer struct {
 	event        domain.Event
 	handler      domain.EventHandler
 	messageName  string
+	name         string
 	errorChannel chan ErrorMessage
+	registry     *EventRegistry
+	codec        Codec
+	factory      EventFactory
+	workers      int
+	maxInFlight  int
+	partitionKey func(domain.Event) string
+	timeout      time.Duration
+	retryPolicy  RetryPolicy
+	breaker      *CircuitBreaker
+	deadLetter   DeadLetterHandler
+	maxDeliver   int
+	batchHandler domain.BatchEventHandler
+	batchSize    int
+	batchWait    time.Duration
+
+	mu             sync.Mutex
+	started        bool
+	stopping       chan struct{}
+	stopOnce       sync.Once
+	done           chan struct{}
+	cancelHandling context.CancelFunc
 }
 
 // ErrorMessage represents an error and its associated message.
@@ -23,6 +48,12 @@ type ErrorMessage struct {
 	Msg   []byte
 }
 
+// delivery is a decoded message waiting to be handled by a worker.
+type delivery struct {
+	message *domain.Message
+	event   domain.Event
+}
+
 // NewEventConsumer creates a new EventConsumer with an error channel.
 func NewEventConsumer(
 	broker domain.Broker,
@@ -30,72 +61,479 @@ func NewEventConsumer(
 	handler domain.EventHandler,
 	messageName string,
 	errorChannel chan ErrorMessage,
+	options ...func(*EventConsumer),
 ) *EventConsumer {
-	return &EventConsumer{
+	c := &EventConsumer{
 		broker:       broker,
 		event:        event,
 		handler:      handler,
 		messageName:  messageName,
+		name:         messageName,
 		errorChannel: errorChannel,
+		codec:        NewJSONCodec(),
+		workers:      1,
+		retryPolicy:  NoRetry(),
+		maxDeliver:   5,
+		batchSize:    100,
+		batchWait:    time.Second,
+		stopping:     make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	if c.batchHandler != nil && c.maxInFlight > 0 {
+		panic("application_event: WithMaxInFlight doesn't apply to batch consumers")
+	}
+	if c.maxInFlight < c.workers {
+		c.maxInFlight = c.workers
+	}
+	// Messages are decoded while others are handled, they can't share the prototype
+	if (c.maxInFlight > 1 || c.batchHandler != nil) && c.factory == nil && c.registry == nil && c.event != nil {
+		c.factory = factoryFromPrototype(c.event)
+	}
+	return c
+}
+
+// WithEventRegistry decodes messages through the registry instead of the event prototype, upcasting
+// payloads written with older event versions. The event passed to NewEventConsumer may then be nil.
+func WithEventRegistry(r *EventRegistry) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.registry = r
+	}
+}
+
+// WithEventFactory decodes every message into a new event returned by factory instead of the event
+// prototype. Without it, concurrent consumers create zero events of the prototype type.
+func WithEventFactory(factory EventFactory) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.factory = factory
+	}
+}
+
+// WithCodec sets the codec messages are decoded with, JSONCodec by default.
+func WithCodec(codec Codec) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.codec = codec
+	}
+}
+
+// WithWorkers handles messages with n concurrent workers, 1 by default. Messages, or batches for batch
+// consumers, are then handled in any order unless a partition key is set.
+func WithWorkers(n int) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithMaxInFlight bounds the messages fetched and not yet handled, at least the number of workers.
+// Once reached, no message is fetched from the broker until a worker is done. NewBatchEventConsumer
+// panics when it is set, batch consumers holding up to a batch per worker instead.
+func WithMaxInFlight(n int) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.maxInFlight = n
+	}
+}
+
+// WithPartitionKey handles the events sharing the same key, e.g. their AggregateID, in order by the
+// same worker. Batch consumers split each batch by worker.
+func WithPartitionKey(key func(domain.Event) string) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.partitionKey = key
+	}
+}
+
+// WithHandlerTimeout bounds the time the handler has to handle a message, its context being canceled
+// once elapsed.
+func WithHandlerTimeout(timeout time.Duration) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetryPolicy sets how failed messages are retried, NoRetry by default. Handlers implementing
+// RetryPolicyProvider override it.
+func WithRetryPolicy(policy RetryPolicy) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithCircuitBreaker pauses fetching messages while the circuit breaker is open. Messages count as
+// failed once their retries are exhausted.
+func WithCircuitBreaker(breaker *CircuitBreaker) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.breaker = breaker
 	}
 }
 
-// Start starts the consumer and processes messages until a stop signal is received.
+// WithDeadLetter routes the messages whose retries are exhausted to handler. Without it, they are
+// negatively acknowledged up to the max deliveries.
+func WithDeadLetter(handler DeadLetterHandler) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.deadLetter = handler
+	}
+}
+
+// WithMaxDeliveries acknowledges the failed messages already delivered n times, reporting them on the
+// error channel, instead of negatively acknowledging them to be redelivered again. 5 by default, 0
+// redelivers them forever. It applies to brokers reporting the delivery count, and without a dead
+// letter handler, which takes every failed message.
+func WithMaxDeliveries(n int) func(*EventConsumer) {
+	return func(c *EventConsumer) {
+		c.maxDeliver = n
+	}
+}
+
+// ByAggregateID is a partition key keeping the events of an aggregate in order.
+func ByAggregateID(event domain.Event) string {
+	return event.AggregateID()
+}
+
+// Start starts the consumer and processes messages until ctx is done, stopChan is closed or Stop is
+// called. stopChan may be nil.
+//
+// Messages are fetched and decoded one at a time and handled by the workers, or in batches for
+// consumers created with NewBatchEventConsumer. Handled messages are acknowledged. Failed ones are
+// retried according to the retry policy, then routed to the dead letter handler or negatively
+// acknowledged so brokers supporting it redeliver them, up to the max deliveries. Messages that can't
+// be decoded are acknowledged as they would never succeed.
+//
+// Once stopped, no message is fetched anymore and Start returns after the messages in flight are
+// handled. Their handlers get a context that isn't canceled with ctx, only when Stop gives up waiting.
+// Messages waiting to be retried are negatively acknowledged right away.
 func (c *EventConsumer) Start(ctx context.Context, stopChan chan struct{}) {
-	log.Printf("Starting consumer for: %s\n", c.messageName)
+	fetchCtx, cancelFetch := context.WithCancel(ctx)
+	defer cancelFetch()
+	handleCtx, cancelHandling := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandling()
 
-	for {
+	c.mu.Lock()
+	if c.started {
+		c.mu.Unlock()
+		return
+	}
+	c.started = true
+	c.cancelHandling = cancelHandling
+	c.mu.Unlock()
+	defer close(c.done)
+
+	go func() {
 		select {
 		case <-stopChan:
-			log.Printf("Stopping consumer for: %s\n", c.messageName)
+		case <-c.stopping:
+		case <-fetchCtx.Done():
+		}
+		cancelFetch()
+	}()
+
+	log.Printf("Starting consumer for: %s\n", c.name)
+	if c.batchHandler != nil {
+		c.consumeBatches(fetchCtx, handleCtx)
+	} else {
+		c.consume(fetchCtx, handleCtx)
+	}
+	log.Printf("Stopping consumer for: %s\n", c.name)
+}
+
+// consume fetches messages one at a time until fetchCtx is done, handling them with the workers.
+func (c *EventConsumer) consume(fetchCtx, handleCtx context.Context) {
+	// Without partition key the workers share a single queue
+	queues := make([]chan delivery, 1)
+	if c.partitionKey != nil {
+		queues = make([]chan delivery, c.workers)
+	}
+	for i := range queues {
+		queues[i] = make(chan delivery, c.maxInFlight)
+	}
+
+	inFlight := make(chan struct{}, c.maxInFlight)
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		queue := queues[i%len(queues)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range queue {
+				c.handle(fetchCtx, handleCtx, d)
+				<-inFlight
+			}
+		}()
+	}
+
+	defer func() {
+		// Drain the messages already fetched
+		for _, queue := range queues {
+			close(queue)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		// Wait for a free slot before fetching, leaving the messages in the broker meanwhile
+		select {
+		case <-fetchCtx.Done():
 			return
-		default:
-			func() {
-				// Recover from panic for this message processing
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("Recovered from panic: %v", r)
-						c.sendError(fmt.Errorf("panic: %v", r), nil)
-					}
-				}()
-
-				// Fetch message
-				msg, err := c.broker.FetchMessage(ctx)
-				if err != nil {
-					log.Printf("Error fetching message: %s\n", err)
-					c.sendError(err, nil)
-					return
-				}
-
-				// Deserialize message
-				var payload map[string]interface{}
-				if err := json.Unmarshal(msg, &payload); err != nil {
-					log.Printf("Error unmarshalling message: %s\n", err)
-					c.sendError(err, msg)
-					return
-				}
-
-				// Map to domain event
-				if err := c.event.FromMap(payload); err != nil {
-					log.Printf("Error building domain event: %s\n", err)
-					c.sendError(err, msg)
-					return
-				}
-
-				// Handle the event
-				if err := c.handler.Handle(ctx, c.event); err != nil {
-					log.Printf("Error processing message: %s\n", err)
-					c.sendError(err, msg)
-					return
-				}
-			}()
+		case inFlight <- struct{}{}:
 		}
+
+		// Checked once the slot is free so that the messages in flight open the circuit first
+		if c.breaker != nil && c.breaker.Wait(fetchCtx) != nil {
+			<-inFlight
+			return
+		}
+
+		d, ok := c.receive(fetchCtx, handleCtx)
+		if !ok {
+			if c.breaker != nil {
+				c.breaker.release()
+			}
+			<-inFlight
+			continue
+		}
+
+		queues[c.queueIndex(d.event, len(queues))] <- d
 	}
 }
 
+// Stop stops fetching messages and waits for the messages in flight to be handled. When ctx is done
+// first, the context of the handlers still running is canceled and ctx.Err() is returned.
+func (c *EventConsumer) Stop(ctx context.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stopping)
+	})
+
+	c.mu.Lock()
+	started, cancelHandling := c.started, c.cancelHandling
+	c.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		cancelHandling()
+		return ctx.Err()
+	}
+}
+
+// receive fetches and decodes the next message, reporting the errors. The fetch is canceled with
+// fetchCtx, the message acknowledged with ctx.
+func (c *EventConsumer) receive(fetchCtx, ctx context.Context) (d delivery, ok bool) {
+	// Recover from panic for this message processing
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic: %v", r)
+			ok = false
+			c.sendError(fmt.Errorf("panic: %v", r), nil)
+		}
+	}()
+
+	// Fetch message
+	message, err := c.fetch(fetchCtx)
+	if err != nil {
+		// The consumer is stopping
+		if fetchCtx.Err() != nil {
+			return d, false
+		}
+		log.Printf("Error fetching message: %s\n", err)
+		c.sendError(err, nil)
+		return d, false
+	}
+	// No message available before the broker timed out
+	if message == nil {
+		return d, false
+	}
+
+	return c.decodeMessage(ctx, message)
+}
+
+// decodeMessage decodes the event of message, acknowledging and reporting the messages that can't be.
+func (c *EventConsumer) decodeMessage(ctx context.Context, message *domain.Message) (d delivery, ok bool) {
+	d.message = message
+
+	// Recover from panic for this message processing
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic: %v", r)
+			ok = false
+			c.ack(ctx, message)
+			c.sendError(fmt.Errorf("panic: %v", r), message.Data)
+		}
+	}()
+
+	// Deserialize message
+	envelope, err := c.codec.Decode(message)
+	if err != nil {
+		log.Printf("Error unmarshalling message: %s\n", err)
+		c.ack(ctx, message)
+		c.sendError(err, message.Data)
+		return d, false
+	}
+
+	// Map to domain event
+	d.event, err = c.decode(envelope.Map())
+	if err != nil {
+		log.Printf("Error building domain event: %s\n", err)
+		c.ack(ctx, message)
+		c.sendError(err, message.Data)
+		return d, false
+	}
+
+	return d, true
+}
+
+// handle handles a decoded message, acknowledging it on success and retrying it on failure until
+// stopCtx is done.
+func (c *EventConsumer) handle(stopCtx, ctx context.Context, d delivery) {
+	attempts, interrupted, err := retry(stopCtx, policyOf(c.handler, c.retryPolicy), func() error {
+		err := c.call(ctx, d)
+		if err != nil {
+			log.Printf("Error processing message: %s\n", err)
+		}
+		return err
+	})
+	if err == nil {
+		if c.breaker != nil {
+			c.breaker.RecordSuccess()
+		}
+		c.ack(ctx, d.message)
+		return
+	}
+
+	if c.breaker != nil {
+		c.breaker.RecordFailure()
+	}
+	if interrupted {
+		// The consumer is stopping, the message is redelivered instead of waiting to be retried
+		c.nack(ctx, d.message)
+		return
+	}
+	c.fail(ctx, d, err, attempts)
+}
+
+// fail reports a message whose retries are exhausted, routing it to the dead letter handler or
+// negatively acknowledging it, unless it reached the max deliveries.
+func (c *EventConsumer) fail(ctx context.Context, d delivery, err error, attempts int) {
+	c.sendError(err, d.message.Data)
+
+	if c.deadLetter != nil {
+		letter := DeadLetter{Message: d.message, Event: d.event, Err: err, Attempts: attempts}
+		dlErr := c.deadLetter(ctx, letter)
+		if dlErr == nil {
+			c.ack(ctx, d.message)
+			return
+		}
+		log.Printf("Error routing dead letter: %s\n", dlErr)
+		c.nack(ctx, d.message)
+		return
+	}
+
+	if c.maxDeliver > 0 && d.message.DeliveryCount >= c.maxDeliver {
+		log.Printf("Dropping message delivered %d times: %s\n", d.message.DeliveryCount, err)
+		c.ack(ctx, d.message)
+		return
+	}
+	c.nack(ctx, d.message)
+}
+
+// call runs the handler once with the message in its context.
+func (c *EventConsumer) call(ctx context.Context, d delivery) error {
+	ctx = ContextWithMessage(ctx, d.message)
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	return safeHandle(ctx, c.handler, d.event)
+}
+
+// safeHandle runs handler, turning a panic into an error.
+func safeHandle(ctx context.Context, handler domain.EventHandler, event domain.Event) (err error) {
+	// Recover from panic for this message processing
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic: %v", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler.Handle(ctx, event)
+}
+
+// queueIndex returns the queue of the worker handling event.
+func (c *EventConsumer) queueIndex(event domain.Event, queues int) int {
+	if queues == 1 {
+		return 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(c.partitionKey(event)))
+	return int(h.Sum32() % uint32(queues))
+}
+
+// fetch fetches the next message, with its headers when the broker exposes them.
+func (c *EventConsumer) fetch(ctx context.Context) (*domain.Message, error) {
+	if mb, ok := c.broker.(domain.MessageBroker); ok {
+		return mb.FetchMessageWithHeaders(ctx)
+	}
+
+	data, err := c.broker.FetchMessage(ctx)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return &domain.Message{Data: data}, nil
+}
+
+func (c *EventConsumer) ack(ctx context.Context, message *domain.Message) {
+	if err := message.Ack(ctx); err != nil {
+		log.Printf("Error acknowledging message: %s\n", err)
+	}
+}
+
+func (c *EventConsumer) nack(ctx context.Context, message *domain.Message) {
+	if err := message.Nack(ctx); err != nil {
+		log.Printf("Error requesting message redelivery: %s\n", err)
+	}
+}
+
+// decode builds the domain event from the message payload.
+func (c *EventConsumer) decode(payload map[string]interface{}) (domain.Event, error) {
+	if c.registry != nil {
+		return c.registry.Decode(c.messageName, payload)
+	}
+
+	event := c.event
+	if c.factory != nil {
+		event = c.factory()
+	}
+	if err := event.FromMap(payload); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
 // sendError sends the error and message to the error channel.
 func (c *EventConsumer) sendError(err error, msg []byte) {
 	if c.errorChannel != nil {
 		c.errorChannel <- ErrorMessage{Error: err, Msg: msg}
 	}
 }
+
+// factoryFromPrototype returns a factory of zero events of the prototype type.
+func factoryFromPrototype(prototype domain.Event) EventFactory {
+	t := reflect.TypeOf(prototype)
+	if t.Kind() != reflect.Ptr {
+		return func() domain.Event {
+			return reflect.New(t).Elem().Interface().(domain.Event)
+		}
+	}
+	return func() domain.Event {
+		return reflect.New(t.Elem()).Interface().(domain.Event)
+	}
+}
@@ -0,0 +1,574 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+type testPayload struct {
+	N int `json:"n"`
+}
+
+type testEvent struct {
+	domain.BaseEvent[testPayload]
+}
+
+func newTestEvent(aggregateID string, n int) *testEvent {
+	return &testEvent{domain.NewBaseEvent("test.happened", aggregateID, 1, testPayload{N: n})}
+}
+
+// testBroker delivers the published messages in order and records their acknowledgments.
+type testBroker struct {
+	messages chan *domain.Message
+
+	mu      sync.Mutex
+	fetched int
+	acked   []string
+	nacked  []string
+}
+
+func newTestBroker() *testBroker {
+	return &testBroker{messages: make(chan *domain.Message, 1000)}
+}
+
+// publish queues the event, delivered deliveryCount times already when greater than 1.
+func (b *testBroker) publish(t *testing.T, event *testEvent, deliveryCount int) {
+	t.Helper()
+
+	message, err := NewJSONCodec().Encode(event)
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	message.ID = event.EventID()
+	message.DeliveryCount = deliveryCount
+	message.Acknowledger = testAcknowledger{broker: b, id: message.ID}
+	b.messages <- message
+}
+
+func (b *testBroker) FetchMessage(ctx context.Context) ([]byte, error) {
+	message, err := b.FetchMessageWithHeaders(ctx)
+	if err != nil || message == nil {
+		return nil, err
+	}
+	return message.Data, nil
+}
+
+func (b *testBroker) FetchMessageWithHeaders(ctx context.Context) (*domain.Message, error) {
+	select {
+	case message := <-b.messages:
+		b.mu.Lock()
+		b.fetched++
+		b.mu.Unlock()
+		return message, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Millisecond):
+		return nil, nil
+	}
+}
+
+func (b *testBroker) Close() {}
+
+// counts returns the messages fetched, acknowledged and negatively acknowledged.
+func (b *testBroker) counts() (fetched, acked, nacked int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fetched, len(b.acked), len(b.nacked)
+}
+
+type testAcknowledger struct {
+	broker *testBroker
+	id     string
+}
+
+func (a testAcknowledger) Ack(context.Context) error {
+	a.broker.mu.Lock()
+	defer a.broker.mu.Unlock()
+	a.broker.acked = append(a.broker.acked, a.id)
+	return nil
+}
+
+func (a testAcknowledger) Nack(context.Context) error {
+	a.broker.mu.Lock()
+	defer a.broker.mu.Unlock()
+	a.broker.nacked = append(a.broker.nacked, a.id)
+	return nil
+}
+
+type handlerFunc func(ctx context.Context, event domain.Event) error
+
+func (f handlerFunc) Handle(ctx context.Context, event domain.Event) error {
+	return f(ctx, event)
+}
+
+// startConsumer starts the consumer, returning a function stopping it and waiting for Start to return.
+func startConsumer(t *testing.T, c *EventConsumer) func() {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Start(context.Background(), nil)
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := c.Stop(ctx); err != nil {
+				t.Errorf("Stop: %v", err)
+			}
+			<-done
+		})
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// eventually fails the test unless condition holds within a second.
+func eventually(t *testing.T, condition func() bool, format string, args ...interface{}) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConsumerHandlesAndAcknowledges(t *testing.T) {
+	broker := newTestBroker()
+	var (
+		mu      sync.Mutex
+		handled []int
+	)
+	handler := handlerFunc(func(_ context.Context, event domain.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, event.(*testEvent).Data.N)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		broker.publish(t, newTestEvent("a", i), 1)
+	}
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 3
+	}, "messages not acknowledged")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(handled) != "[0 1 2]" {
+		t.Errorf("handled %v, want [0 1 2]", handled)
+	}
+}
+
+func TestConsumerWorkersHandleConcurrently(t *testing.T) {
+	broker := newTestBroker()
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	handler := handlerFunc(func(_ context.Context, event domain.Event) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	for i := 0; i < 16; i++ {
+		broker.publish(t, newTestEvent(fmt.Sprint(i), i), 1)
+	}
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil, WithWorkers(4)))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 16
+	}, "messages not acknowledged")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak != 4 {
+		t.Errorf("%d messages handled at once, want 4", peak)
+	}
+}
+
+func TestConsumerPartitionKeyKeepsOrder(t *testing.T) {
+	broker := newTestBroker()
+	var (
+		mu    sync.Mutex
+		order = make(map[string][]int)
+	)
+	handler := handlerFunc(func(_ context.Context, event domain.Event) error {
+		// Events of the other aggregates are handled meanwhile
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		order[event.AggregateID()] = append(order[event.AggregateID()], event.(*testEvent).Data.N)
+		return nil
+	})
+
+	for i := 0; i < 40; i++ {
+		broker.publish(t, newTestEvent(fmt.Sprint("aggregate-", i%4), i), 1)
+	}
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithWorkers(4), WithMaxInFlight(8), WithPartitionKey(ByAggregateID)))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 40
+	}, "messages not acknowledged")
+
+	mu.Lock()
+	defer mu.Unlock()
+	for aggregate, ns := range order {
+		for i := 1; i < len(ns); i++ {
+			if ns[i] < ns[i-1] {
+				t.Errorf("events of %s handled out of order: %v", aggregate, ns)
+				break
+			}
+		}
+	}
+}
+
+func TestConsumerMaxInFlightBoundsFetching(t *testing.T) {
+	broker := newTestBroker()
+	release := make(chan struct{})
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		<-release
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		broker.publish(t, newTestEvent("a", i), 1)
+	}
+	stop := startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithWorkers(2), WithMaxInFlight(3)))
+
+	eventually(t, func() bool {
+		fetched, _, _ := broker.counts()
+		return fetched == 3
+	}, "in flight messages not fetched")
+	// No more message is fetched while the workers are busy
+	time.Sleep(50 * time.Millisecond)
+	if fetched, _, _ := broker.counts(); fetched != 3 {
+		t.Errorf("fetched %d messages, want 3 in flight", fetched)
+	}
+
+	close(release)
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 10
+	}, "messages not acknowledged")
+	stop()
+}
+
+func TestConsumerStopDrainsInFlightMessages(t *testing.T) {
+	broker := newTestBroker()
+	started := make(chan struct{}, 4)
+	handler := handlerFunc(func(ctx context.Context, _ domain.Event) error {
+		started <- struct{}{}
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	for i := 0; i < 10; i++ {
+		broker.publish(t, newTestEvent(fmt.Sprint(i), i), 1)
+	}
+	c := NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil, WithWorkers(2))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Start(context.Background(), nil)
+	}()
+	<-started
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-done:
+	default:
+		t.Error("Start still running once stopped")
+	}
+
+	fetched, acked, nacked := broker.counts()
+	if acked != fetched || nacked != 0 {
+		t.Errorf("fetched %d messages, acknowledged %d and negatively acknowledged %d, want all acknowledged", fetched, acked, nacked)
+	}
+	if len(broker.messages) != 10-fetched {
+		t.Errorf("%d messages left in the broker, want %d", len(broker.messages), 10-fetched)
+	}
+}
+
+func TestConsumerStopCancelsHandlersOnTimeout(t *testing.T) {
+	broker := newTestBroker()
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	handler := handlerFunc(func(ctx context.Context, _ domain.Event) error {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+
+	broker.publish(t, newTestEvent("a", 1), 1)
+	c := NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Start(context.Background(), nil)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Stop(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Stop error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context not canceled")
+	}
+	<-done
+}
+
+func TestConsumerStopsWithContextOrStopChan(t *testing.T) {
+	for name, stop := range map[string]func(cancel context.CancelFunc, stopChan chan struct{}){
+		"context":   func(cancel context.CancelFunc, _ chan struct{}) { cancel() },
+		"stop chan": func(_ context.CancelFunc, stopChan chan struct{}) { close(stopChan) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			broker := newTestBroker()
+			handlerCtx := make(chan context.Context, 1)
+			release := make(chan struct{})
+			handler := handlerFunc(func(ctx context.Context, _ domain.Event) error {
+				handlerCtx <- ctx
+				<-release
+				return nil
+			})
+			broker.publish(t, newTestEvent("a", 1), 1)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stopChan := make(chan struct{})
+			done := make(chan struct{})
+			c := NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil)
+			go func() {
+				defer close(done)
+				c.Start(ctx, stopChan)
+			}()
+			hctx := <-handlerCtx
+
+			stop(cancel, stopChan)
+			// The message in flight is handled with a context that isn't canceled with the consumer
+			time.Sleep(20 * time.Millisecond)
+			if hctx.Err() != nil {
+				t.Errorf("handler context canceled with the consumer: %v", hctx.Err())
+			}
+			select {
+			case <-done:
+				t.Fatal("consumer stopped before the message in flight was handled")
+			default:
+			}
+
+			close(release)
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("consumer not stopped")
+			}
+			if _, acked, _ := broker.counts(); acked != 1 {
+				t.Errorf("acknowledged %d messages, want 1", acked)
+			}
+		})
+	}
+}
+
+func TestConsumerStopBeforeStart(t *testing.T) {
+	c := NewEventConsumer(newTestBroker(), &testEvent{}, handlerFunc(func(context.Context, domain.Event) error {
+		return nil
+	}), "test.happened", nil)
+
+	if err := c.Stop(context.Background()); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+}
+
+func TestConsumerRetriesFailedMessages(t *testing.T) {
+	broker := newTestBroker()
+	var calls int32
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	broker.publish(t, newTestEvent("a", 1), 1)
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithRetryPolicy(ImmediateRetry(5))))
+
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 1
+	}, "message not acknowledged")
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("handled %d times, want 3", n)
+	}
+}
+
+func TestConsumerRoutesExhaustedMessagesToDeadLetter(t *testing.T) {
+	broker := newTestBroker()
+	errFailed := errors.New("failed")
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		return errFailed
+	})
+
+	letters := make(chan DeadLetter, 1)
+	deadLetter := func(_ context.Context, letter DeadLetter) error {
+		letters <- letter
+		return nil
+	}
+
+	broker.publish(t, newTestEvent("a", 1), 1)
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithRetryPolicy(ImmediateRetry(3)), WithDeadLetter(deadLetter)))
+
+	select {
+	case letter := <-letters:
+		if letter.Attempts != 3 || letter.Err != errFailed || letter.Event.(*testEvent).Data.N != 1 {
+			t.Errorf("dead letter = %d attempts, %v, %v", letter.Attempts, letter.Err, letter.Event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no dead letter")
+	}
+	eventually(t, func() bool {
+		_, acked, nacked := broker.counts()
+		return acked == 1 && nacked == 0
+	}, "dead letter not acknowledged")
+}
+
+func TestConsumerNacksFailedMessagesUpToMaxDeliveries(t *testing.T) {
+	broker := newTestBroker()
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		return errors.New("failed")
+	})
+
+	broker.publish(t, newTestEvent("a", 1), 2)
+	broker.publish(t, newTestEvent("a", 2), 3)
+	errs := make(chan ErrorMessage, 2)
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", errs,
+		WithMaxDeliveries(3)))
+
+	eventually(t, func() bool {
+		_, acked, nacked := broker.counts()
+		return acked == 1 && nacked == 1
+	}, "want the message delivered 3 times acknowledged and the other one negatively acknowledged")
+	if len(errs) != 2 {
+		t.Errorf("reported %d errors, want 2", len(errs))
+	}
+}
+
+func TestConsumerStopInterruptsRetries(t *testing.T) {
+	broker := newTestBroker()
+	var calls int32
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("failed")
+	})
+
+	broker.publish(t, newTestEvent("a", 1), 1)
+	c := NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithRetryPolicy(FixedRetry(3, time.Minute)))
+	stop := startConsumer(t, c)
+
+	eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) == 1
+	}, "message not handled")
+
+	start := time.Now()
+	stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop waited %v for the retry delay", elapsed)
+	}
+	if _, acked, nacked := broker.counts(); acked != 0 || nacked != 1 {
+		t.Errorf("acked %d, nacked %d, want the message negatively acknowledged", acked, nacked)
+	}
+}
+
+func TestConsumerDoesNotRetryPermanentErrors(t *testing.T) {
+	broker := newTestBroker()
+	var calls int32
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		atomic.AddInt32(&calls, 1)
+		return NewPermanentError(errors.New("invalid"))
+	})
+
+	broker.publish(t, newTestEvent("a", 1), 1)
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithRetryPolicy(ImmediateRetry(5))))
+
+	eventually(t, func() bool {
+		_, _, nacked := broker.counts()
+		return nacked == 1
+	}, "message not negatively acknowledged")
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("handled %d times, want 1", n)
+	}
+}
+
+func TestConsumerCircuitBreakerPausesFetching(t *testing.T) {
+	broker := newTestBroker()
+	handler := handlerFunc(func(context.Context, domain.Event) error {
+		return errors.New("failed")
+	})
+
+	for i := 0; i < 5; i++ {
+		broker.publish(t, newTestEvent("a", i), 1)
+	}
+	breaker := NewCircuitBreaker(2, time.Minute)
+	startConsumer(t, NewEventConsumer(broker, &testEvent{}, handler, "test.happened", nil,
+		WithCircuitBreaker(breaker)))
+
+	eventually(t, func() bool {
+		return breaker.State() == CircuitOpen
+	}, "circuit not opened")
+	time.Sleep(50 * time.Millisecond)
+	if fetched, _, _ := broker.counts(); fetched != 2 {
+		t.Errorf("fetched %d messages, want 2 until the circuit closes", fetched)
+	}
+}
@@ -0,0 +1,29 @@
+package application_event
+
+import (
+	"context"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// DeadLetter is a message whose handling failed once its retries were exhausted.
+type DeadLetter struct {
+	Message *domain.Message
+	Event   domain.Event
+	// Err is the error of the last attempt
+	Err      error
+	Attempts int
+}
+
+// DeadLetterHandler routes dead letters elsewhere, e.g. to a dead letter queue or a store for
+// later inspection. The message is acknowledged once routed and left to the broker redelivery
+// otherwise.
+type DeadLetterHandler func(ctx context.Context, letter DeadLetter) error
+
+// DeadLetterToBus publishes the events of dead letters to bus, typically a bus publishing to a
+// dedicated stream or subject.
+func DeadLetterToBus(bus EventBus) DeadLetterHandler {
+	return func(ctx context.Context, letter DeadLetter) error {
+		return bus.Publish(ctx, letter.Event)
+	}
+}
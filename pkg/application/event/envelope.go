@@ -0,0 +1,136 @@
+package application_event
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// Envelope is the transport independent representation of an event, produced and read by the codecs.
+type Envelope struct {
+	ID            string
+	Name          string
+	AggregateID   string
+	OccurredOn    time.Time
+	Version       int
+	CorrelationID string
+	CausationID   string
+	Metadata      map[string]string
+	Payload       map[string]interface{}
+}
+
+// NewEnvelope returns the envelope of event. Events exposing EventID, CausationID or Metadata, such as
+// domain.BaseEvent, have them copied, the others get a new event ID.
+func NewEnvelope(event domain.Event) Envelope {
+	envelope := Envelope{
+		Name:          event.EventName(),
+		AggregateID:   event.AggregateID(),
+		OccurredOn:    event.OccurredOn(),
+		Version:       event.Version(),
+		CorrelationID: event.CorrelationID(),
+		Payload:       event.Payload(),
+	}
+
+	if e, ok := event.(interface{ EventID() string }); ok {
+		envelope.ID = e.EventID()
+	}
+	if envelope.ID == "" {
+		envelope.ID = domain.NewEventID()
+	}
+	if e, ok := event.(interface{ CausationID() string }); ok {
+		envelope.CausationID = e.CausationID()
+	}
+	if e, ok := event.(interface{ Metadata() map[string]string }); ok {
+		envelope.Metadata = e.Metadata()
+	}
+
+	return envelope
+}
+
+// Map returns the map passed to domain.Event.FromMap: the payload fields, overridden by the envelope
+// fields under the domain keys (domain.EventNameKey, domain.VersionKey...), and the payload itself
+// under domain.PayloadKey.
+func (e Envelope) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(e.Payload)+9)
+	for k, v := range e.Payload {
+		m[k] = v
+	}
+
+	setIfNotEmpty := func(key, value string) {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	setIfNotEmpty(domain.EventIDKey, e.ID)
+	setIfNotEmpty(domain.EventNameKey, e.Name)
+	setIfNotEmpty(domain.AggregateIDKey, e.AggregateID)
+	setIfNotEmpty(domain.CorrelationIDKey, e.CorrelationID)
+	setIfNotEmpty(domain.CausationIDKey, e.CausationID)
+	if !e.OccurredOn.IsZero() {
+		m[domain.OccurredOnKey] = e.OccurredOn.Format(time.RFC3339Nano)
+	}
+	if e.Version != 0 {
+		m[domain.VersionKey] = e.Version
+	}
+	if len(e.Metadata) > 0 {
+		metadata := make(map[string]interface{}, len(e.Metadata))
+		for k, v := range e.Metadata {
+			metadata[k] = v
+		}
+		m[domain.MetadataKey] = metadata
+	}
+	payload := e.Payload
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	m[domain.PayloadKey] = payload
+
+	return m
+}
+
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+func timeValue(v interface{}) (time.Time, error) {
+	s := stringValue(v)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid event time %q: %w", s, err)
+	}
+	return t, nil
+}
+
+func versionValue(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int(n), nil
+	case int:
+		return n, nil
+	default:
+		s := stringValue(v)
+		if s == "" {
+			return 0, nil
+		}
+		version, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid event version %q: %w", s, err)
+		}
+		return version, nil
+	}
+}
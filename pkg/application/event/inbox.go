@@ -0,0 +1,192 @@
+package application_event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// ErrInboxInProgress is returned by InboxHandler for events claimed by another consumer, which may
+// still fail to handle them. It is retried like any error so that the event isn't acknowledged before
+// it is processed.
+var ErrInboxInProgress = errors.New("event is being handled by another consumer")
+
+// ErrInboxClaimLost is returned by InboxStore.MarkProcessed when the claim expired and another
+// consumer claimed the event meanwhile.
+var ErrInboxClaimLost = errors.New("inbox claim lost")
+
+// InboxStatus is the state of an event in the inbox, as found by InboxStore.Claim.
+type InboxStatus int
+
+const (
+	// InboxClaimed means the event has been claimed by the caller, which must handle it.
+	InboxClaimed InboxStatus = iota
+	// InboxInProgress means the event is claimed by another consumer.
+	InboxInProgress
+	// InboxProcessed means the event has already been processed.
+	InboxProcessed
+)
+
+func (s InboxStatus) String() string {
+	switch s {
+	case InboxClaimed:
+		return "claimed"
+	case InboxInProgress:
+		return "in progress"
+	case InboxProcessed:
+		return "processed"
+	}
+	return "unknown"
+}
+
+// InboxStore records the events handled by each handler so that redelivered events are skipped.
+//
+// Each operation must be atomic for a scope and ID, scopes isolating the handlers consuming the same
+// events. Claims are identified by a token so that a consumer whose lease expired doesn't release or
+// overwrite the claim of another one.
+type InboxStore interface {
+	// Claim reserves id for lease while it is handled, returning InboxClaimed with the token of the
+	// claim, or the status of the event when it is already claimed or processed.
+	Claim(ctx context.Context, scope, id string, lease time.Duration) (InboxStatus, string, error)
+	// MarkProcessed records id as processed for ttl, returning ErrInboxClaimLost when the claim
+	// identified by token was replaced by another one.
+	MarkProcessed(ctx context.Context, scope, id, token string, ttl time.Duration) error
+	// Release drops the claim identified by token so id can be handled again.
+	Release(ctx context.Context, scope, id, token string) error
+}
+
+// NewInboxToken generates a random claim token, for InboxStore implementations.
+func NewInboxToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// InboxHandler is a domain.EventHandler handling each event at most once per scope, skipping the
+// duplicates at-least-once brokers deliver.
+//
+// Events are identified by their EventID, such as domain.BaseEvent, or by the ID of the broker message
+// they were decoded from. Events without any ID are handled every time.
+type InboxHandler struct {
+	scope   string
+	handler domain.EventHandler
+	store   InboxStore
+	lease   time.Duration
+	ttl     time.Duration
+	key     func(ctx context.Context, event domain.Event) string
+}
+
+// NewInboxHandler wraps handler with the inbox, scope being unique to the handler.
+func NewInboxHandler(scope string, handler domain.EventHandler, store InboxStore, options ...func(*InboxHandler)) *InboxHandler {
+	h := &InboxHandler{
+		scope:   scope,
+		handler: handler,
+		store:   store,
+		lease:   5 * time.Minute,
+		ttl:     7 * 24 * time.Hour,
+		key:     EventOrMessageID,
+	}
+	for _, opt := range options {
+		opt(h)
+	}
+	return h
+}
+
+// WithInboxLease sets how long an event is reserved while handled, 5 minutes by default. A consumer
+// crashing while handling it leaves the event to redeliveries once the lease expired.
+func WithInboxLease(lease time.Duration) func(*InboxHandler) {
+	return func(h *InboxHandler) {
+		h.lease = lease
+	}
+}
+
+// WithInboxTTL sets how long processed events are remembered, 7 days by default. It should outlast
+// the redelivery window of the broker.
+func WithInboxTTL(ttl time.Duration) func(*InboxHandler) {
+	return func(h *InboxHandler) {
+		h.ttl = ttl
+	}
+}
+
+// WithInboxKey sets how events are identified, EventOrMessageID by default. Events with an empty key
+// are handled every time.
+func WithInboxKey(key func(ctx context.Context, event domain.Event) string) func(*InboxHandler) {
+	return func(h *InboxHandler) {
+		h.key = key
+	}
+}
+
+// EventOrMessageID identifies events by their EventID, or by the ID of their message when the event
+// doesn't have any.
+func EventOrMessageID(ctx context.Context, event domain.Event) string {
+	if e, ok := event.(interface{ EventID() string }); ok && e.EventID() != "" {
+		return e.EventID()
+	}
+	if m, ok := MessageFromContext(ctx); ok {
+		return m.ID
+	}
+	return ""
+}
+
+// Handle handles the event unless it has already been processed in this scope. Events claimed by
+// another consumer fail with ErrInboxInProgress.
+func (h *InboxHandler) Handle(ctx context.Context, event domain.Event) error {
+	id := h.key(ctx, event)
+	if id == "" {
+		return h.handler.Handle(ctx, event)
+	}
+
+	status, token, err := h.store.Claim(ctx, h.scope, id, h.lease)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case InboxProcessed:
+		log.Printf("Skipping already processed event %s for %s\n", id, h.scope)
+		return nil
+	case InboxInProgress:
+		return ErrInboxInProgress
+	}
+
+	if err := h.handler.Handle(ctx, event); err != nil {
+		// The handler context may be done, the claim is released regardless
+		if releaseErr := h.store.Release(context.WithoutCancel(ctx), h.scope, id, token); releaseErr != nil {
+			log.Printf("Error releasing inbox claim: %s\n", releaseErr)
+		}
+		return err
+	}
+
+	err = h.store.MarkProcessed(context.WithoutCancel(ctx), h.scope, id, token, h.ttl)
+	if errors.Is(err, ErrInboxClaimLost) {
+		// The event is handled, the consumer now holding the claim records it or releases it
+		log.Printf("Inbox claim on event %s for %s expired while handling it\n", id, h.scope)
+		return nil
+	}
+	return err
+}
+
+// RetryPolicy returns the retry policy of the wrapped handler, if any.
+func (h *InboxHandler) RetryPolicy() RetryPolicy {
+	if p, ok := h.handler.(RetryPolicyProvider); ok {
+		return p.RetryPolicy()
+	}
+	return nil
+}
+
+type messageContextKey struct{}
+
+// ContextWithMessage returns a copy of ctx carrying the broker message an event was decoded from.
+func ContextWithMessage(ctx context.Context, message *domain.Message) context.Context {
+	return context.WithValue(ctx, messageContextKey{}, message)
+}
+
+// MessageFromContext returns the broker message EventConsumer passes to handlers.
+func MessageFromContext(ctx context.Context) (*domain.Message, bool) {
+	m, ok := ctx.Value(messageContextKey{}).(*domain.Message)
+	return m, ok && m != nil
+}
@@ -0,0 +1,140 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+func TestMemoryInboxStoreClaims(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryInboxStore()
+	store.now = func() time.Time { return now }
+
+	status, token, err := store.Claim(ctx, "s", "1", time.Minute)
+	if err != nil || status != InboxClaimed || token == "" {
+		t.Fatalf("Claim = %v, %q, %v, want claimed with a token", status, token, err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != InboxInProgress {
+		t.Errorf("second Claim = %v, want in progress", status)
+	}
+	if status, _, _ := store.Claim(ctx, "other", "1", time.Minute); status != InboxClaimed {
+		t.Errorf("Claim in another scope = %v, want claimed", status)
+	}
+
+	// Releasing with another token leaves the claim
+	if err := store.Release(ctx, "s", "1", "other"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != InboxInProgress {
+		t.Errorf("Claim after a foreign release = %v, want in progress", status)
+	}
+
+	if err := store.MarkProcessed(ctx, "s", "1", token, time.Hour); err != nil {
+		t.Fatalf("MarkProcessed: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != InboxProcessed {
+		t.Errorf("Claim once processed = %v, want processed", status)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != InboxClaimed {
+		t.Errorf("Claim once the retention elapsed = %v, want claimed", status)
+	}
+}
+
+func TestMemoryInboxStoreExpiredClaim(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryInboxStore()
+	store.now = func() time.Time { return now }
+
+	_, expired, _ := store.Claim(ctx, "s", "1", time.Minute)
+	now = now.Add(2 * time.Minute)
+	status, token, _ := store.Claim(ctx, "s", "1", time.Minute)
+	if status != InboxClaimed {
+		t.Fatalf("Claim once the lease elapsed = %v, want claimed", status)
+	}
+
+	// The consumer whose lease expired neither releases nor overwrites the new claim
+	if err := store.Release(ctx, "s", "1", expired); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := store.MarkProcessed(ctx, "s", "1", expired, time.Hour); !errors.Is(err, ErrInboxClaimLost) {
+		t.Fatalf("MarkProcessed = %v, want %v", err, ErrInboxClaimLost)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != InboxInProgress {
+		t.Errorf("Claim = %v, want in progress", status)
+	}
+
+	if err := store.Release(ctx, "s", "1", token); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != InboxClaimed {
+		t.Errorf("Claim once released = %v, want claimed", status)
+	}
+}
+
+func TestInboxHandlerHandlesOnce(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	handler := NewInboxHandler("s", handlerFunc(func(context.Context, domain.Event) error {
+		calls++
+		return nil
+	}), NewMemoryInboxStore())
+
+	event := newTestEvent("a", 1)
+	for i := 0; i < 2; i++ {
+		if err := handler.Handle(ctx, event); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handled %d times, want 1", calls)
+	}
+}
+
+func TestInboxHandlerReleasesFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	calls := 0
+	handler := NewInboxHandler("s", handlerFunc(func(context.Context, domain.Event) error {
+		calls++
+		if calls == 1 {
+			return errFailed
+		}
+		return nil
+	}), NewMemoryInboxStore())
+
+	event := newTestEvent("a", 1)
+	if err := handler.Handle(ctx, event); err != errFailed {
+		t.Fatalf("Handle = %v, want %v", err, errFailed)
+	}
+	if err := handler.Handle(ctx, event); err != nil {
+		t.Fatalf("Handle = %v, want the released event handled again", err)
+	}
+	if calls != 2 {
+		t.Errorf("handled %d times, want 2", calls)
+	}
+}
+
+func TestInboxHandlerFailsEventsInProgress(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryInboxStore()
+	event := newTestEvent("a", 1)
+	if _, _, err := store.Claim(ctx, "s", event.EventID(), time.Minute); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	handler := NewInboxHandler("s", handlerFunc(func(context.Context, domain.Event) error {
+		t.Error("event claimed by another consumer handled")
+		return nil
+	}), store)
+	if err := handler.Handle(ctx, event); !errors.Is(err, ErrInboxInProgress) {
+		t.Errorf("Handle = %v, want %v so that the event isn't acknowledged", err, ErrInboxInProgress)
+	}
+}
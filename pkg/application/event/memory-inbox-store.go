@@ -0,0 +1,97 @@
+package application_event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const inboxSweepInterval = time.Minute
+
+// MemoryInboxStore keeps the inbox in memory, it is only suited to single instance deployments.
+type MemoryInboxStore struct {
+	mu        sync.Mutex
+	entries   map[inboxKey]inboxEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type inboxKey struct {
+	scope string
+	id    string
+}
+
+// inboxEntry is a claim, or a processed event once processed is set.
+type inboxEntry struct {
+	token     string
+	processed bool
+	expiresAt time.Time
+}
+
+// NewMemoryInboxStore creates a new empty MemoryInboxStore.
+func NewMemoryInboxStore() *MemoryInboxStore {
+	return &MemoryInboxStore{
+		entries:   make(map[inboxKey]inboxEntry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (m *MemoryInboxStore) Claim(_ context.Context, scope, id string, lease time.Duration) (InboxStatus, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+
+	key := inboxKey{scope: scope, id: id}
+	if entry, ok := m.entries[key]; ok && now.Before(entry.expiresAt) {
+		if entry.processed {
+			return InboxProcessed, "", nil
+		}
+		return InboxInProgress, "", nil
+	}
+
+	token := NewInboxToken()
+	m.entries[key] = inboxEntry{token: token, expiresAt: now.Add(lease)}
+	return InboxClaimed, token, nil
+}
+
+func (m *MemoryInboxStore) MarkProcessed(_ context.Context, scope, id, token string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+
+	key := inboxKey{scope: scope, id: id}
+	entry, ok := m.entries[key]
+	if ok && entry.token != token && !entry.processed && now.Before(entry.expiresAt) {
+		return ErrInboxClaimLost
+	}
+
+	m.entries[key] = inboxEntry{token: token, processed: true, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *MemoryInboxStore) Release(_ context.Context, scope, id, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := inboxKey{scope: scope, id: id}
+	if entry, ok := m.entries[key]; ok && entry.token == token && !entry.processed {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired entries, at most once per inboxSweepInterval.
+func (m *MemoryInboxStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < inboxSweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, entry := range m.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
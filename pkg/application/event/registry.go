@@ -0,0 +1,195 @@
+package application_event
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+const (
+	// DefaultNameKey is the message key holding the event name.
+	DefaultNameKey = domain.EventNameKey
+	// DefaultVersionKey is the message key holding the event version.
+	DefaultVersionKey = domain.VersionKey
+)
+
+// EventFactory returns a new, empty event to be filled with FromMap.
+type EventFactory func() domain.Event
+
+// Upcaster transforms the payload of an event from one version to the next one. It is given the map
+// held under domain.PayloadKey, or the whole message when there is none.
+type Upcaster func(payload map[string]interface{}) (map[string]interface{}, error)
+
+type eventKey struct {
+	name    string
+	version int
+}
+
+// EventRegistry maps event names and versions to factories, upcasting payloads written with older
+// versions before they are decoded.
+type EventRegistry struct {
+	lock       sync.RWMutex
+	factories  map[eventKey]EventFactory
+	upcasters  map[eventKey]Upcaster
+	nameKey    string
+	versionKey string
+}
+
+// NewEventRegistry creates an EventRegistry reading the event name and version from DefaultNameKey and
+// DefaultVersionKey.
+func NewEventRegistry(options ...func(*EventRegistry)) *EventRegistry {
+	r := &EventRegistry{
+		factories:  make(map[eventKey]EventFactory),
+		upcasters:  make(map[eventKey]Upcaster),
+		nameKey:    DefaultNameKey,
+		versionKey: DefaultVersionKey,
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	return r
+}
+
+// WithMetadataKeys sets the message keys the event name and version are read from.
+func WithMetadataKeys(nameKey, versionKey string) func(*EventRegistry) {
+	return func(r *EventRegistry) {
+		r.nameKey = nameKey
+		r.versionKey = versionKey
+	}
+}
+
+// Register registers the factory under the name and version of the events it creates.
+func (r *EventRegistry) Register(factory EventFactory) error {
+	event := factory()
+	key := eventKey{name: event.EventName(), version: event.Version()}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.factories[key]; ok {
+		return NewEventAlreadyRegistered(key.name, key.version)
+	}
+	r.factories[key] = factory
+
+	return nil
+}
+
+// RegisterUpcaster registers the function transforming the payload of the event name from fromVersion
+// to fromVersion+1.
+func (r *EventRegistry) RegisterUpcaster(name string, fromVersion int, upcaster Upcaster) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.upcasters[eventKey{name: name, version: fromVersion}] = upcaster
+}
+
+// Decode upcasts the payload up to the latest version reachable through the registered upcasters and
+// returns the event built from it. defaultName is used when the payload holds no event name, and events
+// without a version are considered version 1.
+func (r *EventRegistry) Decode(defaultName string, payload map[string]interface{}) (domain.Event, error) {
+	name, _ := payload[r.nameKey].(string)
+	if name == "" {
+		name = defaultName
+	}
+
+	version, err := versionValue(payload[r.versionKey])
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = 1
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	data, nested := payload[domain.PayloadKey].(map[string]interface{})
+	if !nested {
+		data = payload
+	}
+	upcasted := false
+	for {
+		upcaster, ok := r.upcasters[eventKey{name: name, version: version}]
+		if !ok {
+			break
+		}
+
+		data, err = upcaster(data)
+		if err != nil {
+			return nil, NewUpcastError(name, version, err)
+		}
+		version++
+		upcasted = true
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+	}
+	if upcasted {
+		if nested {
+			payload = maps.Clone(payload)
+			payload[domain.PayloadKey] = data
+		} else {
+			payload = data
+		}
+		payload[r.versionKey] = version
+	}
+
+	factory, ok := r.factories[eventKey{name: name, version: version}]
+	if !ok {
+		return nil, NewEventNotRegistered(name, version)
+	}
+
+	event := factory()
+	if err := event.FromMap(payload); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+type EventAlreadyRegistered struct {
+	Name    string
+	Version int
+}
+
+func NewEventAlreadyRegistered(name string, version int) EventAlreadyRegistered {
+	return EventAlreadyRegistered{Name: name, Version: version}
+}
+
+func (e EventAlreadyRegistered) Error() string {
+	return fmt.Sprintf("event %s version %d already registered", e.Name, e.Version)
+}
+
+type EventNotRegistered struct {
+	Name    string
+	Version int
+}
+
+func NewEventNotRegistered(name string, version int) EventNotRegistered {
+	return EventNotRegistered{Name: name, Version: version}
+}
+
+func (e EventNotRegistered) Error() string {
+	return fmt.Sprintf("event %s version %d not registered", e.Name, e.Version)
+}
+
+// UpcastError is returned when an upcaster fails to transform a payload.
+type UpcastError struct {
+	Name        string
+	FromVersion int
+	err         error
+}
+
+func NewUpcastError(name string, fromVersion int, err error) *UpcastError {
+	return &UpcastError{Name: name, FromVersion: fromVersion, err: err}
+}
+
+func (e *UpcastError) Error() string {
+	return fmt.Sprintf("failed to upcast event %s from version %d: %s", e.Name, e.FromVersion, e.err)
+}
+
+func (e *UpcastError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,130 @@
+package application_event
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+type weighedPayload struct {
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit"`
+}
+
+// weighedEvent is version 3 of test.happened, whose n became a quantity with a unit.
+type weighedEvent struct {
+	domain.BaseEvent[weighedPayload]
+}
+
+func newWeighedEvent() domain.Event {
+	return &weighedEvent{domain.NewBaseEvent("test.happened", "", 3, weighedPayload{})}
+}
+
+// newUpcastingRegistry registers version 3 of test.happened and the upcasters from version 1.
+func newUpcastingRegistry(t *testing.T) *EventRegistry {
+	t.Helper()
+
+	registry := NewEventRegistry()
+	if err := registry.Register(newWeighedEvent); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	registry.RegisterUpcaster("test.happened", 1, func(payload map[string]interface{}) (map[string]interface{}, error) {
+		payload["quantity"] = payload["n"]
+		delete(payload, "n")
+		return payload, nil
+	})
+	registry.RegisterUpcaster("test.happened", 2, func(payload map[string]interface{}) (map[string]interface{}, error) {
+		if _, ok := payload["quantity"].(float64); !ok {
+			return nil, errors.New("missing quantity")
+		}
+		payload["unit"] = "kg"
+		return payload, nil
+	})
+	return registry
+}
+
+func TestEventRegistryUpcasts(t *testing.T) {
+	registry := newUpcastingRegistry(t)
+
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+	}{
+		{"envelope of version 1", NewEnvelope(newTestEvent("a1", 3)).Map()},
+		{"envelope without version", func() map[string]interface{} {
+			m := NewEnvelope(newTestEvent("a1", 3)).Map()
+			delete(m, domain.VersionKey)
+			return m
+		}()},
+		{"flat message of version 1", map[string]interface{}{"event_name": "test.happened", "aggregate_id": "a1", "version": "1", "n": float64(3)}},
+		{"envelope of version 2", map[string]interface{}{"event_name": "test.happened", "aggregate_id": "a1", "version": float64(2),
+			"payload": map[string]interface{}{"quantity": float64(3)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := registry.Decode("", tt.payload)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			weighed, ok := event.(*weighedEvent)
+			if !ok {
+				t.Fatalf("Decode = %T, want *weighedEvent", event)
+			}
+			if weighed.Version() != 3 || weighed.AggregateID() != "a1" || weighed.Data != (weighedPayload{Quantity: 3, Unit: "kg"}) {
+				t.Errorf("Decode = version %d of %s %+v, want version 3 of a1 with 3 kg", weighed.Version(), weighed.AggregateID(), weighed.Data)
+			}
+		})
+	}
+}
+
+func TestEventRegistryDecodesRegisteredVersionsOnly(t *testing.T) {
+	registry := NewEventRegistry()
+	if err := registry.Register(func() domain.Event { return newTestEvent("", 0) }); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var alreadyRegistered EventAlreadyRegistered
+	if err := registry.Register(func() domain.Event { return newTestEvent("", 0) }); !errors.As(err, &alreadyRegistered) {
+		t.Errorf("second Register = %v, want EventAlreadyRegistered", err)
+	}
+
+	// The default name is used when the payload has none
+	event, err := registry.Decode("test.happened", map[string]interface{}{"n": float64(3)})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if event.(*testEvent).Data.N != 3 {
+		t.Errorf("Decode = %+v, want n 3", event)
+	}
+
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    EventNotRegistered
+	}{
+		{"unregistered name", map[string]interface{}{"event_name": "other.happened"}, EventNotRegistered{Name: "other.happened", Version: 1}},
+		{"unregistered version", map[string]interface{}{"event_name": "test.happened", "version": float64(2)}, EventNotRegistered{Name: "test.happened", Version: 2}},
+		{"missing name", map[string]interface{}{"n": float64(3)}, EventNotRegistered{Name: "", Version: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := registry.Decode("", tt.payload)
+			var notRegistered EventNotRegistered
+			if !errors.As(err, &notRegistered) || notRegistered != tt.want {
+				t.Errorf("Decode = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventRegistryUpcastError(t *testing.T) {
+	registry := newUpcastingRegistry(t)
+
+	_, err := registry.Decode("", map[string]interface{}{"event_name": "test.happened", "version": float64(2),
+		"payload": map[string]interface{}{"weight": float64(3)}})
+	var upcastErr *UpcastError
+	if !errors.As(err, &upcastErr) || upcastErr.Name != "test.happened" || upcastErr.FromVersion != 2 || errors.Unwrap(err).Error() != "missing quantity" {
+		t.Errorf("Decode = %v, want an UpcastError from version 2", err)
+	}
+}
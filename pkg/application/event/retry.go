@@ -0,0 +1,141 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides whether a failed message is handled again and after which delay.
+type RetryPolicy interface {
+	// NextDelay returns the delay before the next attempt, given the attempts made so far and the
+	// error of the last one, and false once the message shouldn't be retried.
+	NextDelay(attempt int, err error) (time.Duration, bool)
+}
+
+// RetryPolicyFunc is an adapter to use ordinary functions as a RetryPolicy.
+type RetryPolicyFunc func(attempt int, err error) (time.Duration, bool)
+
+// NextDelay calls f(attempt, err).
+func (f RetryPolicyFunc) NextDelay(attempt int, err error) (time.Duration, bool) {
+	return f(attempt, err)
+}
+
+// RetryPolicyProvider is implemented by handlers setting their own retry policy, which takes
+// precedence over the consumer one unless nil.
+type RetryPolicyProvider interface {
+	RetryPolicy() RetryPolicy
+}
+
+// NoRetry handles messages once.
+func NoRetry() RetryPolicy {
+	return RetryPolicyFunc(func(int, error) (time.Duration, bool) {
+		return 0, false
+	})
+}
+
+// ImmediateRetry handles messages up to maxAttempts times without waiting between attempts.
+func ImmediateRetry(maxAttempts int) RetryPolicy {
+	return FixedRetry(maxAttempts, 0)
+}
+
+// FixedRetry handles messages up to maxAttempts times waiting delay between attempts.
+func FixedRetry(maxAttempts int, delay time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int, _ error) (time.Duration, bool) {
+		return delay, attempt < maxAttempts
+	})
+}
+
+// ExponentialRetry handles messages up to maxAttempts times, doubling the delay between attempts from
+// initial up to max. Delays are jittered between half and the whole of their value so consumers
+// failing together don't retry together.
+func ExponentialRetry(maxAttempts int, initial, max time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int, _ error) (time.Duration, bool) {
+		if attempt >= maxAttempts {
+			return 0, false
+		}
+
+		delay := initial
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		if delay <= 1 {
+			return delay, true
+		}
+
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half))), true
+	})
+}
+
+// PermanentError wraps an error retrying wouldn't fix, such as an invalid payload, so that the
+// message isn't retried whatever the retry policy.
+type PermanentError struct {
+	Err error
+}
+
+// NewPermanentError creates a new PermanentError.
+func NewPermanentError(err error) PermanentError {
+	return PermanentError{Err: err}
+}
+
+func (e PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether err wraps a PermanentError.
+func IsPermanent(err error) bool {
+	var permanent PermanentError
+	return errors.As(err, &permanent)
+}
+
+// retry calls fn until it succeeds or policy gives up, right away for permanent errors, returning the
+// attempts made and the error of the last one. It is interrupted when ctx is done while waiting to retry.
+func retry(ctx context.Context, policy RetryPolicy, fn func() error) (attempts int, interrupted bool, err error) {
+	for {
+		attempts++
+		if err = fn(); err == nil || IsPermanent(err) {
+			return attempts, false, err
+		}
+
+		delay, ok := policy.NextDelay(attempts, err)
+		if !ok {
+			return attempts, false, err
+		}
+		if sleep(ctx, delay) != nil {
+			return attempts, true, err
+		}
+	}
+}
+
+// policyOf returns the retry policy of handler, or fallback when it doesn't set any.
+func policyOf(handler interface{}, fallback RetryPolicy) RetryPolicy {
+	if p, ok := handler.(RetryPolicyProvider); ok && p.RetryPolicy() != nil {
+		return p.RetryPolicy()
+	}
+	return fallback
+}
+
+// sleep waits for d, returning early with ctx.Err() when ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicies(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		delay   time.Duration
+		ok      bool
+	}{
+		{"no retry", NoRetry(), 1, 0, false},
+		{"immediate first", ImmediateRetry(3), 1, 0, true},
+		{"immediate last", ImmediateRetry(3), 3, 0, false},
+		{"fixed", FixedRetry(3, time.Second), 2, time.Second, true},
+		{"fixed exhausted", FixedRetry(3, time.Second), 3, time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := tt.policy.NextDelay(tt.attempt, errFailed)
+			if delay != tt.delay || ok != tt.ok {
+				t.Errorf("NextDelay(%d) = %v, %v, want %v, %v", tt.attempt, delay, ok, tt.delay, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExponentialRetryDelays(t *testing.T) {
+	policy := ExponentialRetry(6, 100*time.Millisecond, time.Second)
+	errFailed := errors.New("failed")
+
+	// The delays double from initial up to max, jittered between half and the whole of their value
+	want := []time.Duration{100, 200, 400, 800, 1000}
+	for i, max := range want {
+		max *= time.Millisecond
+		for n := 0; n < 20; n++ {
+			delay, ok := policy.NextDelay(i+1, errFailed)
+			if !ok || delay < max/2 || delay > max {
+				t.Fatalf("NextDelay(%d) = %v, %v, want between %v and %v", i+1, delay, ok, max/2, max)
+			}
+		}
+	}
+
+	if _, ok := policy.NextDelay(6, errFailed); ok {
+		t.Error("NextDelay(6) retries, want exhausted")
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	err := fmt.Errorf("handling: %w", NewPermanentError(errors.New("invalid payload")))
+	if !IsPermanent(err) {
+		t.Error("IsPermanent(wrapped permanent error) = false")
+	}
+	if err.Error() != "handling: invalid payload" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if IsPermanent(errors.New("failed")) {
+		t.Error("IsPermanent(error) = true")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	t.Run("until success", func(t *testing.T) {
+		calls := 0
+		attempts, interrupted, err := retry(context.Background(), ImmediateRetry(5), func() error {
+			calls++
+			if calls < 3 {
+				return errFailed
+			}
+			return nil
+		})
+		if attempts != 3 || interrupted || err != nil {
+			t.Errorf("retry = %d, %v, %v, want 3, false, nil", attempts, interrupted, err)
+		}
+	})
+
+	t.Run("until exhausted", func(t *testing.T) {
+		attempts, interrupted, err := retry(context.Background(), ImmediateRetry(3), func() error {
+			return errFailed
+		})
+		if attempts != 3 || interrupted || err != errFailed {
+			t.Errorf("retry = %d, %v, %v, want 3, false, %v", attempts, interrupted, err, errFailed)
+		}
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		attempts, _, err := retry(context.Background(), ImmediateRetry(3), func() error {
+			return NewPermanentError(errFailed)
+		})
+		if attempts != 1 || !IsPermanent(err) {
+			t.Errorf("retry = %d, %v, want a single attempt", attempts, err)
+		}
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		attempts, interrupted, err := retry(ctx, FixedRetry(3, time.Minute), func() error {
+			return errFailed
+		})
+		if attempts != 1 || !interrupted || err != errFailed {
+			t.Errorf("retry = %d, %v, %v, want 1, true, %v", attempts, interrupted, err, errFailed)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("retry waited %v after ctx was done", elapsed)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package application_event
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// EventRouter is a domain.EventHandler dispatching events by name to every handler registered for it,
+// so that a single consumer serves all the reactions to the events of a broker.
+//
+// Handlers run concurrently and in isolation: each one is retried with its own retry policy, and one
+// failing doesn't prevent the others from handling the event. The handlers that succeeded are recorded
+// in the inbox, scoped by handler name, so that a redelivered event only reaches the ones that failed.
+type EventRouter struct {
+	mu          sync.RWMutex
+	routes      map[string][]route
+	names       map[string]bool
+	retryPolicy RetryPolicy
+	inbox       InboxStore
+	inboxOpts   []func(*InboxHandler)
+}
+
+type route struct {
+	name    string
+	handler domain.EventHandler
+}
+
+// NewEventRouter creates an EventRouter keeping its inbox in memory.
+func NewEventRouter(options ...func(*EventRouter)) *EventRouter {
+	r := &EventRouter{
+		routes:      make(map[string][]route),
+		names:       make(map[string]bool),
+		retryPolicy: NoRetry(),
+		inbox:       NewMemoryInboxStore(),
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	return r
+}
+
+// WithRouterRetryPolicy sets how the handlers are retried, NoRetry by default. Handlers implementing
+// RetryPolicyProvider override it.
+func WithRouterRetryPolicy(policy RetryPolicy) func(*EventRouter) {
+	return func(r *EventRouter) {
+		r.retryPolicy = policy
+	}
+}
+
+// WithRouterInbox sets the store recording the handlers that succeeded, a MemoryInboxStore by default,
+// which doesn't survive restarts nor is shared by instances.
+func WithRouterInbox(store InboxStore, options ...func(*InboxHandler)) func(*EventRouter) {
+	return func(r *EventRouter) {
+		r.inbox = store
+		r.inboxOpts = options
+	}
+}
+
+// Register registers handler for the events named eventName. name identifies the handler, it must be
+// unique within the router and stable across deployments as it scopes the inbox.
+func (r *EventRouter) Register(eventName, name string, handler domain.EventHandler) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		return NewHandlerAlreadyRegistered(name)
+	}
+	r.names[name] = true
+
+	r.routes[eventName] = append(r.routes[eventName], route{
+		name:    name,
+		handler: NewInboxHandler(name, handler, r.inbox, r.inboxOpts...),
+	})
+	return nil
+}
+
+// EventNames returns the names of the events having handlers, sorted.
+func (r *EventRouter) EventNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.routes))
+	for name := range r.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handle dispatches the event to its handlers, returning a *RouterError listing the ones that failed.
+// Events without handlers are ignored.
+func (r *EventRouter) Handle(ctx context.Context, event domain.Event) error {
+	r.mu.RLock()
+	routes := r.routes[event.EventName()]
+	r.mu.RUnlock()
+
+	errs := make([]HandlerError, len(routes))
+	var wg sync.WaitGroup
+	for i, rt := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := retry(ctx, policyOf(rt.handler, r.retryPolicy), func() error {
+				err := safeHandle(ctx, rt.handler, event)
+				if err != nil {
+					log.Printf("Error processing event %s in %s: %s\n", event.EventName(), rt.name, err)
+				}
+				return err
+			})
+			if err != nil {
+				errs[i] = HandlerError{Handler: rt.name, Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := &RouterError{}
+	for _, err := range errs {
+		if err.Err != nil {
+			failed.Errors = append(failed.Errors, err)
+		}
+	}
+	if len(failed.Errors) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// RetryPolicy doesn't retry the whole event, the handlers are retried by the router.
+func (r *EventRouter) RetryPolicy() RetryPolicy {
+	return NoRetry()
+}
+
+// NewEventRouterConsumer creates an EventConsumer decoding the events of the broker with registry and
+// dispatching them with router. name identifies the consumer in the logs, the events being decoded by
+// the name they carry.
+func NewEventRouterConsumer(
+	broker domain.Broker,
+	registry *EventRegistry,
+	router *EventRouter,
+	name string,
+	errorChannel chan ErrorMessage,
+	options ...func(*EventConsumer),
+) *EventConsumer {
+	options = append([]func(*EventConsumer){WithEventRegistry(registry)}, options...)
+	c := NewEventConsumer(broker, nil, router, "", errorChannel, options...)
+	c.name = name
+	return c
+}
+
+type HandlerAlreadyRegistered struct {
+	Name string
+}
+
+func NewHandlerAlreadyRegistered(name string) HandlerAlreadyRegistered {
+	return HandlerAlreadyRegistered{Name: name}
+}
+
+func (e HandlerAlreadyRegistered) Error() string {
+	return fmt.Sprintf("handler %s already registered", e.Name)
+}
+
+// HandlerError is the error of a handler of an EventRouter.
+type HandlerError struct {
+	Handler string
+	Err     error
+}
+
+func (e HandlerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Handler, e.Err)
+}
+
+func (e HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// RouterError is returned by EventRouter when some of the handlers failed.
+type RouterError struct {
+	Errors []HandlerError
+}
+
+func (e *RouterError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "event handlers failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *RouterError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
@@ -0,0 +1,152 @@
+package application_event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// countingHandler counts its calls, failing the first failures of them.
+type countingHandler struct {
+	mu       sync.Mutex
+	calls    int
+	failures int
+	policy   RetryPolicy
+}
+
+func (h *countingHandler) Handle(context.Context, domain.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls++
+	if h.calls <= h.failures {
+		return errors.New("handler failed")
+	}
+	return nil
+}
+
+func (h *countingHandler) RetryPolicy() RetryPolicy {
+	return h.policy
+}
+
+func (h *countingHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls
+}
+
+func newTestRouter(t *testing.T, handlers map[string]domain.EventHandler, options ...func(*EventRouter)) *EventRouter {
+	t.Helper()
+
+	router := NewEventRouter(options...)
+	for name, handler := range handlers {
+		if err := router.Register("test.happened", name, handler); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	return router
+}
+
+func TestEventRouterFansOutToEveryHandler(t *testing.T) {
+	first, second := &countingHandler{}, &countingHandler{}
+	other := &countingHandler{}
+	router := newTestRouter(t, map[string]domain.EventHandler{"first": first, "second": second})
+	if err := router.Register("other.happened", "other", other); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if err := router.Handle(context.Background(), newTestEvent("a1", 1)); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if first.count() != 1 || second.count() != 1 || other.count() != 0 {
+		t.Errorf("calls = %d, %d, %d, want 1, 1, 0", first.count(), second.count(), other.count())
+	}
+
+	if names := router.EventNames(); len(names) != 2 || names[0] != "other.happened" || names[1] != "test.happened" {
+		t.Errorf("EventNames = %v", names)
+	}
+}
+
+func TestEventRouterRejectsDuplicateHandlerNames(t *testing.T) {
+	router := newTestRouter(t, map[string]domain.EventHandler{"first": &countingHandler{}})
+
+	var alreadyRegistered HandlerAlreadyRegistered
+	if err := router.Register("other.happened", "first", &countingHandler{}); !errors.As(err, &alreadyRegistered) {
+		t.Errorf("Register = %v, want HandlerAlreadyRegistered", err)
+	}
+}
+
+func TestEventRouterIsolatesHandlerErrors(t *testing.T) {
+	failing, succeeding := &countingHandler{failures: 1}, &countingHandler{}
+	router := newTestRouter(t, map[string]domain.EventHandler{"failing": failing, "succeeding": succeeding})
+
+	err := router.Handle(context.Background(), newTestEvent("a1", 1))
+	var routerErr *RouterError
+	if !errors.As(err, &routerErr) || len(routerErr.Errors) != 1 || routerErr.Errors[0].Handler != "failing" {
+		t.Fatalf("Handle = %v, want a RouterError for the failing handler only", err)
+	}
+	if succeeding.count() != 1 {
+		t.Errorf("succeeding handler called %d times, want 1", succeeding.count())
+	}
+}
+
+func TestEventRouterRetriesHandlersIndependently(t *testing.T) {
+	// The router policy applies to the handlers without their own one
+	flaky := &countingHandler{failures: 2}
+	ownPolicy := &countingHandler{failures: 2, policy: NoRetry()}
+	router := newTestRouter(t, map[string]domain.EventHandler{"flaky": flaky, "own-policy": ownPolicy},
+		WithRouterRetryPolicy(ImmediateRetry(3)))
+
+	err := router.Handle(context.Background(), newTestEvent("a1", 1))
+	var routerErr *RouterError
+	if !errors.As(err, &routerErr) || len(routerErr.Errors) != 1 || routerErr.Errors[0].Handler != "own-policy" {
+		t.Fatalf("Handle = %v, want a RouterError for the handler not retried", err)
+	}
+	if flaky.count() != 3 || ownPolicy.count() != 1 {
+		t.Errorf("calls = %d, %d, want 3 and 1", flaky.count(), ownPolicy.count())
+	}
+}
+
+func TestEventRouterSkipsSucceededHandlersOnRedelivery(t *testing.T) {
+	failing, succeeding := &countingHandler{failures: 1}, &countingHandler{}
+	router := newTestRouter(t, map[string]domain.EventHandler{"failing": failing, "succeeding": succeeding})
+
+	event := newTestEvent("a1", 1)
+	if err := router.Handle(context.Background(), event); err == nil {
+		t.Fatal("Handle = nil, want the failing handler error")
+	}
+	if err := router.Handle(context.Background(), event); err != nil {
+		t.Fatalf("Handle of the redelivered event: %v", err)
+	}
+	if failing.count() != 2 || succeeding.count() != 1 {
+		t.Errorf("calls = %d, %d, want the redelivery to only reach the failed handler", failing.count(), succeeding.count())
+	}
+}
+
+func TestEventRouterConsumer(t *testing.T) {
+	registry := NewEventRegistry()
+	if err := registry.Register(func() domain.Event { return newTestEvent("", 0) }); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	first, second := &countingHandler{}, &countingHandler{}
+	router := newTestRouter(t, map[string]domain.EventHandler{"first": first, "second": second})
+
+	broker := newTestBroker()
+	consumer := NewEventRouterConsumer(broker, registry, router, "projections", nil)
+	if consumer.name != "projections" || consumer.messageName != "" {
+		t.Errorf("consumer named %q decoding %q, want the name only used in the logs", consumer.name, consumer.messageName)
+	}
+	startConsumer(t, consumer)
+
+	broker.publish(t, newTestEvent("a1", 1), 1)
+	eventually(t, func() bool {
+		_, acked, _ := broker.counts()
+		return acked == 1
+	}, "event not acknowledged")
+	if first.count() != 1 || second.count() != 1 {
+		t.Errorf("calls = %d, %d, want 1 and 1", first.count(), second.count())
+	}
+}
@@ -0,0 +1,27 @@
+package application_query
+
+import (
+	"context"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+// AuthorizingBus checks the permissions and roles declared by a query against the principal in ctx
+// before it reaches the handler.
+type AuthorizingBus struct {
+	Bus
+	policies []application_auth.Policy
+}
+
+func NewAuthorizingBus(next Bus, policies ...application_auth.Policy) *AuthorizingBus {
+	return &AuthorizingBus{Bus: next, policies: policies}
+}
+
+func (bus *AuthorizingBus) Ask(ctx context.Context, q application.Query) (interface{}, error) {
+	if err := application_auth.Authorize(ctx, q, bus.policies...); err != nil {
+		return nil, err
+	}
+
+	return bus.Bus.Ask(ctx, q)
+}
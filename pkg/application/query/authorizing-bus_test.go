@@ -0,0 +1,90 @@
+package application_query
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+type getSalary struct {
+	UserID string
+}
+
+func (q *getSalary) Id() string {
+	return "get_salary"
+}
+
+func (q *getSalary) RequiredRoles() []string {
+	return []string{"hr", "admin"}
+}
+
+// countingBus answers every query with its number of calls and records the registered queries.
+type countingBus struct {
+	calls      int
+	registered []string
+}
+
+func (b *countingBus) RegisterQuery(q application.Query, _ QueryHandler) error {
+	b.registered = append(b.registered, q.Id())
+	return nil
+}
+
+func (b *countingBus) Ask(context.Context, application.Query) (interface{}, error) {
+	b.calls++
+	return b.calls, nil
+}
+
+func TestAuthorizingBus(t *testing.T) {
+	next := &countingBus{}
+	bus := NewAuthorizingBus(next)
+	anonymous := context.Background()
+
+	var unauthenticated *application_auth.Unauthenticated
+	if result, err := bus.Ask(anonymous, &getSalary{UserID: "u3"}); !errors.As(err, &unauthenticated) || result != nil {
+		t.Errorf("anonymous Ask = %v, %v, want Unauthenticated", result, err)
+	}
+	denied := application_auth.ContextWithPrincipal(anonymous, &application_auth.Principal{ID: "u1", Roles: []string{"sales"}})
+	var forbidden *application_auth.Forbidden
+	if result, err := bus.Ask(denied, &getSalary{UserID: "u3"}); !errors.As(err, &forbidden) || result != nil {
+		t.Errorf("Ask without role = %v, %v, want Forbidden", result, err)
+	}
+	if next.calls != 0 {
+		t.Fatalf("%d queries reached the bus, want none", next.calls)
+	}
+
+	allowed := application_auth.ContextWithPrincipal(anonymous, &application_auth.Principal{ID: "u2", Roles: []string{"admin"}})
+	if result, err := bus.Ask(allowed, &getSalary{UserID: "u3"}); err != nil || result != 1 {
+		t.Errorf("Ask with role = %v, %v, want the bus result", result, err)
+	}
+}
+
+func TestAuthorizingBusPolicies(t *testing.T) {
+	errDenied := errors.New("not the owner")
+	bus := NewAuthorizingBus(&countingBus{}, func(_ context.Context, principal *application_auth.Principal, dto application.Dto) error {
+		if q, ok := dto.(*getSalary); ok && !principal.HasRole("admin") && q.UserID != principal.ID {
+			return errDenied
+		}
+		return nil
+	})
+	ctx := application_auth.ContextWithPrincipal(context.Background(), &application_auth.Principal{ID: "u1", Roles: []string{"hr"}})
+
+	if _, err := bus.Ask(ctx, &getSalary{UserID: "u2"}); err != errDenied {
+		t.Errorf("Ask = %v, want the policy error", err)
+	}
+	if _, err := bus.Ask(ctx, &getSalary{UserID: "u1"}); err != nil {
+		t.Errorf("Ask = %v, want nil", err)
+	}
+}
+
+func TestAuthorizingBusRegistersOnTheNextBus(t *testing.T) {
+	next := &countingBus{}
+	if err := NewAuthorizingBus(next).RegisterQuery(&getSalary{}, nil); err != nil {
+		t.Fatalf("RegisterQuery: %v", err)
+	}
+	if len(next.registered) != 1 || next.registered[0] != "get_salary" {
+		t.Errorf("registered %v, want [get_salary]", next.registered)
+	}
+}
@@ -48,8 +48,8 @@ func (i QueryNotRegistered) Error() string {
 	return i.message
 }
 
-func NewQueryNotRegistered(message string, queryName string) QueryAlreadyRegistered {
-	return QueryAlreadyRegistered{message: message, queryName: queryName}
+func NewQueryNotRegistered(message string, queryName string) QueryNotRegistered {
+	return QueryNotRegistered{message: message, queryName: queryName}
 }
 
 func (bus *QueryBus) RegisterQuery(query application.Query, handler QueryHandler) error {
@@ -0,0 +1,98 @@
+package application_saga
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the sagas in memory, it is only suited to tests and single instance deployments.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[stateKey]State
+}
+
+type stateKey struct {
+	name string
+	id   string
+}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[stateKey]State)}
+}
+
+func (m *MemoryStore) Load(_ context.Context, name, id string) (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[stateKey{name: name, id: id}]
+	if !ok {
+		return nil, nil
+	}
+	return copyState(&state), nil
+}
+
+func (m *MemoryStore) Save(_ context.Context, state *State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := stateKey{name: state.Name, id: state.ID}
+	if current := m.states[key]; current.Version != state.Version {
+		return NewConcurrencyError(state.Name, state.ID)
+	}
+
+	state.Version++
+	state.UpdatedAt = time.Now()
+	m.states[key] = *copyState(state)
+	return nil
+}
+
+func (m *MemoryStore) Expired(_ context.Context, name string, now time.Time, limit int) ([]*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expired []*State
+	for key, state := range m.states {
+		if key.name == name && state.Status == StatusRunning && !state.Deadline.IsZero() && state.Deadline.Before(now) {
+			expired = append(expired, copyState(&state))
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].Deadline.Before(expired[j].Deadline)
+	})
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (m *MemoryStore) Pending(_ context.Context, name string, before time.Time, limit int) ([]*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []*State
+	for key, state := range m.states {
+		if key.name == name && len(state.Outbox) > 0 && state.UpdatedAt.Before(before) {
+			pending = append(pending, copyState(&state))
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].UpdatedAt.Before(pending[j].UpdatedAt)
+	})
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+// copyState returns a copy of state not sharing its data and outbox.
+func copyState(state *State) *State {
+	c := *state
+	c.Data = append([]byte(nil), state.Data...)
+	c.Outbox = append([]OutboxCommand(nil), state.Outbox...)
+	return &c
+}
@@ -0,0 +1,405 @@
+package application_saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+const (
+	// timeoutBatch is the maximum number of instances handled by ProcessTimeouts and ProcessOutbox.
+	timeoutBatch = 100
+	// defaultOutboxDelay is how long ProcessOutbox leaves the steps to dispatch their commands.
+	defaultOutboxDelay = time.Minute
+)
+
+// Correlator returns the ID of the saga instance an event belongs to, empty when it belongs to none.
+type Correlator func(event domain.Event) string
+
+// ByAggregateID correlates the events by their aggregate ID.
+func ByAggregateID(event domain.Event) string {
+	return event.AggregateID()
+}
+
+// Step advances a saga instance with an event.
+type Step[D any] func(ctx context.Context, instance *Instance[D], event domain.Event) error
+
+// TimeoutStep advances a saga instance that reached its deadline.
+type TimeoutStep[D any] func(ctx context.Context, instance *Instance[D]) error
+
+type step[D any] struct {
+	correlate Correlator
+	handle    Step[D]
+	starts    bool
+}
+
+// Saga is a long running workflow, or process manager, whose instances hold data of type D. Instances
+// are started and advanced by events, dispatch follow-up and compensating commands on the command bus
+// and are persisted in the SagaStore between steps.
+//
+// Saga is a domain.EventHandler, to be consumed by an EventConsumer or registered in an EventRouter
+// for each of its EventNames. Instances saved concurrently fail with a ConcurrencyError, to be retried
+// by the consumer retry policy.
+//
+// The commands of a step are saved in the outbox of the instance along with its state, then dispatched
+// and removed from the outbox, so that a step failing to save dispatches nothing. Commands failing to
+// be dispatched stay in the outbox until ProcessOutbox decodes and dispatches them, their types must be
+// registered with RegisterCommand to be decoded by another process. Commands are dispatched again when the outbox
+// fails to be saved once they are dispatched, e.g. when the process stops meanwhile.
+type Saga[D any] struct {
+	name    string
+	store   SagaStore
+	bus     application_command.Bus
+	steps   map[string]step[D]
+	timeout TimeoutStep[D]
+	delay   time.Duration
+
+	lock  sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewSaga creates a saga without steps, name identifying its instances in the store.
+func NewSaga[D any](name string, store SagaStore, bus application_command.Bus) *Saga[D] {
+	return &Saga[D]{
+		name:  name,
+		store: store,
+		bus:   bus,
+		steps: make(map[string]step[D]),
+		delay: defaultOutboxDelay,
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// StartOn registers the step starting an instance on the events named eventName. Events correlated to
+// an existing instance are ignored as duplicates.
+func (s *Saga[D]) StartOn(eventName string, correlate Correlator, handle Step[D]) error {
+	return s.register(eventName, step[D]{correlate: correlate, handle: handle, starts: true})
+}
+
+// On registers the step advancing a running instance on the events named eventName. Events without
+// running instance are ignored.
+func (s *Saga[D]) On(eventName string, correlate Correlator, handle Step[D]) error {
+	return s.register(eventName, step[D]{correlate: correlate, handle: handle})
+}
+
+// OnTimeout registers the step advancing the instances reaching their deadline. Without it, they end
+// with StatusTimedOut.
+func (s *Saga[D]) OnTimeout(handle TimeoutStep[D]) {
+	s.timeout = handle
+}
+
+// RegisterCommand registers the types of the commands dispatched by the steps, so that the commands
+// left in the outbox by another process can be decoded. Commands dispatched by this process are
+// registered when their step succeeds.
+func (s *Saga[D]) RegisterCommand(commands ...application.Command) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, c := range commands {
+		s.types[c.Id()] = reflect.TypeOf(c)
+	}
+}
+
+func (s *Saga[D]) register(eventName string, st step[D]) error {
+	if _, ok := s.steps[eventName]; ok {
+		return NewStepAlreadyRegistered(s.name, eventName)
+	}
+	s.steps[eventName] = st
+	return nil
+}
+
+// Name returns the name of the saga.
+func (s *Saga[D]) Name() string {
+	return s.name
+}
+
+// EventNames returns the names of the events the saga handles, sorted.
+func (s *Saga[D]) EventNames() []string {
+	names := make([]string, 0, len(s.steps))
+	for name := range s.steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handle starts or advances the instance the event is correlated to.
+func (s *Saga[D]) Handle(ctx context.Context, event domain.Event) error {
+	st, ok := s.steps[event.EventName()]
+	if !ok {
+		return nil
+	}
+	id := st.correlate(event)
+	if id == "" {
+		return nil
+	}
+
+	state, err := s.store.Load(ctx, s.name, id)
+	if err != nil {
+		return err
+	}
+	switch {
+	case state == nil && !st.starts:
+		log.Printf("Ignoring event %s without saga %s %s\n", event.EventName(), s.name, id)
+		return nil
+	case state == nil:
+		state = &State{Name: s.name, ID: id, Status: StatusRunning}
+	case st.starts || state.Status != StatusRunning:
+		return nil
+	}
+
+	instance, err := s.instance(state)
+	if err != nil {
+		return err
+	}
+	if err := st.handle(ctx, instance, event); err != nil {
+		return err
+	}
+	return s.commit(ctx, instance)
+}
+
+// ProcessTimeouts runs the timeout step of the instances whose deadline passed. A deadline left
+// unchanged by the step is cleared so that it only fires once. Instances saved meanwhile, e.g. by
+// another replica processing the timeouts, are skipped.
+func (s *Saga[D]) ProcessTimeouts(ctx context.Context) error {
+	expired, err := s.store.Expired(ctx, s.name, time.Now(), timeoutBatch)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, state := range expired {
+		err := s.handleTimeout(ctx, state)
+		var concurrencyErr ConcurrencyError
+		if err != nil && !errors.As(err, &concurrencyErr) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ProcessOutbox dispatches the commands left in the outbox of the instances saved over a minute ago.
+func (s *Saga[D]) ProcessOutbox(ctx context.Context) error {
+	pending, err := s.store.Pending(ctx, s.name, time.Now().Add(-s.delay), timeoutBatch)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, state := range pending {
+		err := s.dispatch(ctx, state, nil)
+		var concurrencyErr ConcurrencyError
+		if err != nil && !errors.As(err, &concurrencyErr) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunTimeouts processes the timeouts and the outbox every interval until ctx is done.
+func (s *Saga[D]) RunTimeouts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ProcessTimeouts(ctx); err != nil {
+				log.Printf("Error processing saga %s timeouts: %s\n", s.name, err)
+			}
+			if err := s.ProcessOutbox(ctx); err != nil {
+				log.Printf("Error processing saga %s outbox: %s\n", s.name, err)
+			}
+		}
+	}
+}
+
+func (s *Saga[D]) handleTimeout(ctx context.Context, state *State) error {
+	instance, err := s.instance(state)
+	if err != nil {
+		return err
+	}
+
+	deadline := state.Deadline
+	if s.timeout == nil {
+		instance.end(StatusTimedOut)
+	} else if err := s.timeout(ctx, instance); err != nil {
+		return err
+	}
+	if state.Deadline.Equal(deadline) {
+		state.Deadline = time.Time{}
+	}
+
+	return s.commit(ctx, instance)
+}
+
+// instance decodes the data of state.
+func (s *Saga[D]) instance(state *State) (*Instance[D], error) {
+	instance := &Instance[D]{ID: state.ID, state: state}
+	if len(state.Data) > 0 {
+		if err := json.Unmarshal(state.Data, &instance.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode saga %s %s: %w", s.name, state.ID, err)
+		}
+	}
+	return instance, nil
+}
+
+// commit saves the instance with the commands of the step in its outbox, then dispatches them. Once
+// saved, commands failing to be dispatched are left to ProcessOutbox.
+func (s *Saga[D]) commit(ctx context.Context, instance *Instance[D]) error {
+	data, err := json.Marshal(instance.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode saga %s %s: %w", s.name, instance.ID, err)
+	}
+	instance.state.Data = data
+
+	for _, c := range instance.commands {
+		payload, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to encode saga %s %s command %s: %w", s.name, instance.ID, c.Id(), err)
+		}
+		instance.state.Outbox = append(instance.state.Outbox, OutboxCommand{Name: c.Id(), Payload: payload})
+	}
+	if err := s.store.Save(ctx, instance.state); err != nil {
+		return err
+	}
+
+	s.RegisterCommand(instance.commands...)
+	if err := s.dispatch(ctx, instance.state, instance.commands); err != nil {
+		log.Printf("Error dispatching saga %s %s commands: %s\n", s.name, instance.ID, err)
+	}
+	return nil
+}
+
+// dispatch dispatches the commands of the outbox in order, then saves the outbox without the ones
+// dispatched. The last commands of the outbox, just saved by a step, are dispatched as given rather
+// than decoded.
+func (s *Saga[D]) dispatch(ctx context.Context, state *State, commands []application.Command) error {
+	if len(state.Outbox) == 0 {
+		return nil
+	}
+
+	saved := len(state.Outbox) - len(commands)
+	dispatched := 0
+	var err error
+	for i, oc := range state.Outbox {
+		var c application.Command
+		if i >= saved {
+			c = commands[i-saved]
+		} else if c, err = s.decodeCommand(oc); err != nil {
+			break
+		}
+		if err = s.bus.Dispatch(ctx, c); err != nil {
+			break
+		}
+		dispatched++
+	}
+	if dispatched == 0 {
+		return err
+	}
+
+	state.Outbox = state.Outbox[dispatched:]
+	if len(state.Outbox) == 0 {
+		state.Outbox = nil
+	}
+	return errors.Join(err, s.store.Save(ctx, state))
+}
+
+// decodeCommand decodes a command of the outbox into its registered type.
+func (s *Saga[D]) decodeCommand(oc OutboxCommand) (application.Command, error) {
+	s.lock.RLock()
+	t, ok := s.types[oc.Name]
+	s.lock.RUnlock()
+	if !ok {
+		return nil, application_command.NewCommandNotRegistered("Command not registered", oc.Name)
+	}
+
+	if t.Kind() != reflect.Ptr {
+		v := reflect.New(t)
+		if err := json.Unmarshal(oc.Payload, v.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to decode saga %s command %s: %w", s.name, oc.Name, err)
+		}
+		return v.Elem().Interface().(application.Command), nil
+	}
+
+	c := reflect.New(t.Elem()).Interface().(application.Command)
+	if err := json.Unmarshal(oc.Payload, c); err != nil {
+		return nil, fmt.Errorf("failed to decode saga %s command %s: %w", s.name, oc.Name, err)
+	}
+	return c, nil
+}
+
+// Instance is a saga instance being advanced by a step.
+type Instance[D any] struct {
+	// ID is the correlation ID of the instance
+	ID       string
+	Data     D
+	state    *State
+	commands []application.Command
+}
+
+// Status returns the status of the instance.
+func (i *Instance[D]) Status() Status {
+	return i.state.Status
+}
+
+// Dispatch dispatches commands once the step succeeded and the instance is saved, in order.
+func (i *Instance[D]) Dispatch(commands ...application.Command) {
+	i.commands = append(i.commands, commands...)
+}
+
+// Complete ends the instance successfully.
+func (i *Instance[D]) Complete() {
+	i.end(StatusCompleted)
+}
+
+// Compensate dispatches the commands undoing the steps already done, in order, and ends the instance.
+func (i *Instance[D]) Compensate(commands ...application.Command) {
+	i.Dispatch(commands...)
+	i.end(StatusCompensated)
+}
+
+// SetTimeout runs the timeout step once d elapsed, unless the instance ended meanwhile.
+func (i *Instance[D]) SetTimeout(d time.Duration) {
+	i.state.Deadline = time.Now().Add(d)
+}
+
+// ClearTimeout cancels the timeout.
+func (i *Instance[D]) ClearTimeout() {
+	i.state.Deadline = time.Time{}
+}
+
+// Deadline returns when the instance times out, zero when it doesn't.
+func (i *Instance[D]) Deadline() time.Time {
+	return i.state.Deadline
+}
+
+func (i *Instance[D]) end(status Status) {
+	i.state.Status = status
+	i.state.Deadline = time.Time{}
+}
+
+type StepAlreadyRegistered struct {
+	Saga      string
+	EventName string
+}
+
+func NewStepAlreadyRegistered(saga, eventName string) StepAlreadyRegistered {
+	return StepAlreadyRegistered{Saga: saga, EventName: eventName}
+}
+
+func (e StepAlreadyRegistered) Error() string {
+	return fmt.Sprintf("saga %s already has a step for event %s", e.Saga, e.EventName)
+}
@@ -0,0 +1,263 @@
+package application_saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+type orderPayload struct{}
+
+type orderEvent struct {
+	domain.BaseEvent[orderPayload]
+}
+
+func newOrderEvent(name, orderID string) *orderEvent {
+	return &orderEvent{domain.NewBaseEvent(name, orderID, 1, orderPayload{})}
+}
+
+type shipOrder struct {
+	OrderID string `json:"order_id"`
+	// carrier isn't encoded in the outbox
+	carrier string
+}
+
+func (c *shipOrder) Id() string {
+	return "ship_order"
+}
+
+type orderData struct {
+	Paid bool `json:"paid"`
+}
+
+// testBus records the dispatched commands, failing while err is set.
+type testBus struct {
+	application_command.Bus
+
+	mu         sync.Mutex
+	dispatched []string
+	commands   []application.Command
+	err        error
+}
+
+func (b *testBus) Dispatch(_ context.Context, c application.Command) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+	b.dispatched = append(b.dispatched, c.(*shipOrder).OrderID)
+	b.commands = append(b.commands, c)
+	return nil
+}
+
+func (b *testBus) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.dispatched)
+}
+
+// conflictingStore fails the next save with a ConcurrencyError once conflict is set.
+type conflictingStore struct {
+	*MemoryStore
+	conflict bool
+}
+
+func (s *conflictingStore) Save(ctx context.Context, state *State) error {
+	if s.conflict {
+		s.conflict = false
+		return NewConcurrencyError(state.Name, state.ID)
+	}
+	return s.MemoryStore.Save(ctx, state)
+}
+
+func newOrderSaga(t *testing.T, store SagaStore, bus application_command.Bus) *Saga[orderData] {
+	t.Helper()
+
+	saga := NewSaga[orderData]("order", store, bus)
+	err := saga.StartOn("order.paid", ByAggregateID, func(_ context.Context, instance *Instance[orderData], _ domain.Event) error {
+		instance.Data.Paid = true
+		instance.Dispatch(&shipOrder{OrderID: instance.ID})
+		instance.SetTimeout(time.Hour)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StartOn: %v", err)
+	}
+	saga.OnTimeout(func(_ context.Context, instance *Instance[orderData]) error {
+		instance.Compensate(&shipOrder{OrderID: "compensate-" + instance.ID})
+		return nil
+	})
+	return saga
+}
+
+func TestSagaSavesBeforeDispatching(t *testing.T) {
+	ctx := context.Background()
+	store := &conflictingStore{MemoryStore: NewMemoryStore(), conflict: true}
+	bus := &testBus{}
+	saga := newOrderSaga(t, store, bus)
+
+	event := newOrderEvent("order.paid", "o1")
+	var concurrencyErr ConcurrencyError
+	if err := saga.Handle(ctx, event); !errors.As(err, &concurrencyErr) {
+		t.Fatalf("Handle = %v, want a ConcurrencyError", err)
+	}
+	if n := bus.count(); n != 0 {
+		t.Fatalf("dispatched %d commands for a step failing to save", n)
+	}
+
+	if err := saga.Handle(ctx, event); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if n := bus.count(); n != 1 {
+		t.Fatalf("dispatched %d commands, want 1", n)
+	}
+
+	state, err := store.Load(ctx, "order", "o1")
+	if err != nil || state == nil {
+		t.Fatalf("Load = %v, %v", state, err)
+	}
+	if state.Status != StatusRunning || string(state.Data) != `{"paid":true}` || len(state.Outbox) != 0 {
+		t.Errorf("state = %s, %s, %d commands in outbox", state.Status, state.Data, len(state.Outbox))
+	}
+}
+
+func TestSagaKeepsUndispatchedCommandsInOutbox(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	bus := &testBus{err: errors.New("bus down")}
+	saga := newOrderSaga(t, store, bus)
+	saga.delay = 0
+
+	// The step is saved, its commands are left to the outbox instead of handling the event again
+	if err := saga.Handle(ctx, newOrderEvent("order.paid", "o1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	state, _ := store.Load(ctx, "order", "o1")
+	if len(state.Outbox) != 1 || state.Outbox[0].Name != "ship_order" {
+		t.Fatalf("outbox = %+v, want the ship_order command", state.Outbox)
+	}
+
+	if err := saga.ProcessOutbox(ctx); err == nil {
+		t.Fatal("ProcessOutbox = nil, want the bus error")
+	}
+
+	bus.mu.Lock()
+	bus.err = nil
+	bus.mu.Unlock()
+	if err := saga.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if n := bus.count(); n != 1 {
+		t.Fatalf("dispatched %d commands, want 1", n)
+	}
+	if state, _ := store.Load(ctx, "order", "o1"); len(state.Outbox) != 0 {
+		t.Errorf("outbox = %+v, want empty once dispatched", state.Outbox)
+	}
+}
+
+func TestSagaDecodesOutboxOfRegisteredCommands(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	failing := newOrderSaga(t, store, &testBus{err: errors.New("bus down")})
+	if err := failing.Handle(ctx, newOrderEvent("order.paid", "o1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	// Another process only decodes the commands it registered
+	bus := &testBus{}
+	saga := newOrderSaga(t, store, bus)
+	saga.delay = 0
+	var notRegistered application_command.CommandNotRegistered
+	if err := saga.ProcessOutbox(ctx); !errors.As(err, &notRegistered) {
+		t.Fatalf("ProcessOutbox = %v, want CommandNotRegistered", err)
+	}
+
+	saga.RegisterCommand(&shipOrder{})
+	if err := saga.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if len(bus.dispatched) != 1 || bus.dispatched[0] != "o1" {
+		t.Errorf("dispatched %v, want [o1]", bus.dispatched)
+	}
+}
+
+func TestSagaDispatchesTheCommandsOfTheStep(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	bus := &testBus{err: errors.New("bus down")}
+	saga := newOrderSaga(t, store, bus)
+	compensation := &shipOrder{OrderID: "compensate-o1", carrier: "express"}
+	saga.OnTimeout(func(_ context.Context, instance *Instance[orderData]) error {
+		instance.Compensate(compensation)
+		return nil
+	})
+
+	// The command of the first step is left in the outbox
+	if err := saga.Handle(ctx, newOrderEvent("order.paid", "o1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	bus.mu.Lock()
+	bus.err = nil
+	bus.mu.Unlock()
+
+	state, _ := store.Load(ctx, "order", "o1")
+	if err := saga.handleTimeout(ctx, state); err != nil {
+		t.Fatalf("handleTimeout: %v", err)
+	}
+
+	// The command left in the outbox is decoded, the one of the step dispatched as is
+	if len(bus.commands) != 2 || bus.dispatched[0] != "o1" || bus.commands[1].(*shipOrder).carrier != "express" {
+		t.Fatalf("dispatched %v, want o1 then the compensation of the step", bus.dispatched)
+	}
+	if state, _ := store.Load(ctx, "order", "o1"); len(state.Outbox) != 0 {
+		t.Errorf("outbox = %+v, want empty once dispatched", state.Outbox)
+	}
+}
+
+func TestSagaTimeoutsFireOnce(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	bus := &testBus{}
+	saga := newOrderSaga(t, store, bus)
+
+	if err := saga.Handle(ctx, newOrderEvent("order.paid", "o1")); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	state, _ := store.Load(ctx, "order", "o1")
+	state.Deadline = time.Now().Add(-time.Second)
+	if err := store.Save(ctx, state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Two replicas find the same expired instance, the one saving last dispatches nothing
+	first, _ := store.Expired(ctx, "order", time.Now(), 10)
+	second, _ := store.Expired(ctx, "order", time.Now(), 10)
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("expired %d and %d instances, want 1", len(first), len(second))
+	}
+	if err := saga.handleTimeout(ctx, first[0]); err != nil {
+		t.Fatalf("handleTimeout: %v", err)
+	}
+	var concurrencyErr ConcurrencyError
+	if err := saga.handleTimeout(ctx, second[0]); !errors.As(err, &concurrencyErr) {
+		t.Fatalf("second handleTimeout = %v, want a ConcurrencyError", err)
+	}
+
+	if len(bus.dispatched) != 2 || bus.dispatched[1] != "compensate-o1" {
+		t.Errorf("dispatched %v, want the compensation once", bus.dispatched)
+	}
+	if state, _ := store.Load(ctx, "order", "o1"); state.Status != StatusCompensated || !state.Deadline.IsZero() {
+		t.Errorf("state = %s deadline %v, want compensated without deadline", state.Status, state.Deadline)
+	}
+	if err := saga.ProcessTimeouts(ctx); err != nil || len(bus.dispatched) != 2 {
+		t.Errorf("ProcessTimeouts = %v, dispatched %v, want no more timeouts", err, bus.dispatched)
+	}
+}
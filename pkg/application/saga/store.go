@@ -0,0 +1,77 @@
+package application_saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Status is the status of a saga instance.
+type Status string
+
+const (
+	// StatusRunning sagas are advanced by their events and timeouts.
+	StatusRunning Status = "running"
+	// StatusCompleted sagas reached their end.
+	StatusCompleted Status = "completed"
+	// StatusCompensated sagas failed and dispatched their compensating commands.
+	StatusCompensated Status = "compensated"
+	// StatusTimedOut sagas reached their deadline without a timeout step.
+	StatusTimedOut Status = "timed_out"
+)
+
+// State is the persisted state of a saga instance.
+type State struct {
+	// Name is the name of the saga
+	Name string
+	// ID is the correlation ID shared by the events of the instance
+	ID     string
+	Status Status
+	// Data is the JSON encoded data of the instance
+	Data []byte
+	// Deadline is when the instance times out, zero when it doesn't
+	Deadline time.Time
+	// Outbox holds the commands saved with the state and not dispatched yet, in order
+	Outbox []OutboxCommand
+	// Version is incremented on every save, 0 until the instance is first saved
+	Version   int
+	UpdatedAt time.Time
+}
+
+// OutboxCommand is a command of a step waiting to be dispatched.
+type OutboxCommand struct {
+	// Name is the ID of the command, Payload holding its JSON encoding
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// SagaStore persists the state of saga instances.
+type SagaStore interface {
+	// Load returns the state of the instance id of saga name, nil when there is none.
+	Load(ctx context.Context, name, id string) (*State, error)
+	// Save creates or updates the state and increments its version, failing with a ConcurrencyError
+	// when the instance has been saved since state was loaded.
+	Save(ctx context.Context, state *State) error
+	// Expired returns up to limit running instances of saga name whose deadline is before now, the
+	// earliest deadline first. A limit that is not positive returns all of them.
+	Expired(ctx context.Context, name string, now time.Time, limit int) ([]*State, error)
+	// Pending returns up to limit instances of saga name whose outbox isn't empty, saved before, the
+	// least recently saved first. A limit that is not positive returns all of them.
+	Pending(ctx context.Context, name string, before time.Time, limit int) ([]*State, error)
+}
+
+// ConcurrencyError is returned when an instance is saved concurrently, the event is to be handled
+// again on the up to date state.
+type ConcurrencyError struct {
+	Name string
+	ID   string
+}
+
+func NewConcurrencyError(name, id string) ConcurrencyError {
+	return ConcurrencyError{Name: name, ID: id}
+}
+
+func (e ConcurrencyError) Error() string {
+	return fmt.Sprintf("saga %s %s has been modified concurrently", e.Name, e.ID)
+}
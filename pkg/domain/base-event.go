@@ -0,0 +1,283 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Envelope keys of the JSON representation of a BaseEvent.
+const (
+	EventIDKey       = "event_id"
+	EventNameKey     = "event_name"
+	AggregateIDKey   = "aggregate_id"
+	OccurredOnKey    = "occurred_on"
+	VersionKey       = "version"
+	CorrelationIDKey = "correlation_id"
+	CausationIDKey   = "causation_id"
+	MetadataKey      = "metadata"
+	PayloadKey       = "payload"
+)
+
+// BaseEvent implements Event for the typed payload P, serialized as JSON within a standard envelope:
+//
+//	{"event_id": "...", "event_name": "user.created", "aggregate_id": "...", "occurred_on": "...",
+//	 "version": 1, "correlation_id": "...", "causation_id": "...", "metadata": {}, "payload": {...}}
+//
+// Events are declared as BaseEvent of their payload struct, directly or embedded:
+//
+//	type UserCreated struct {
+//		domain.BaseEvent[UserCreatedPayload]
+//	}
+type BaseEvent[P any] struct {
+	id            string
+	name          string
+	aggregateID   string
+	occurredOn    time.Time
+	version       int
+	correlationID string
+	causationID   string
+	metadata      map[string]string
+	Data          P
+}
+
+var _ Event = (*BaseEvent[struct{}])(nil)
+
+// NewBaseEvent creates an event with a new ID, occurring now. The event ID is also its correlation ID
+// until it is set or the event is caused by another one.
+func NewBaseEvent[P any](name, aggregateID string, version int, data P) BaseEvent[P] {
+	id := NewEventID()
+	return BaseEvent[P]{
+		id:            id,
+		name:          name,
+		aggregateID:   aggregateID,
+		occurredOn:    time.Now().UTC(),
+		version:       version,
+		correlationID: id,
+		Data:          data,
+	}
+}
+
+func (e *BaseEvent[P]) EventID() string {
+	return e.id
+}
+
+func (e *BaseEvent[P]) EventName() string {
+	return e.name
+}
+
+func (e *BaseEvent[P]) AggregateID() string {
+	return e.aggregateID
+}
+
+func (e *BaseEvent[P]) OccurredOn() time.Time {
+	return e.occurredOn
+}
+
+func (e *BaseEvent[P]) Version() int {
+	return e.version
+}
+
+func (e *BaseEvent[P]) CorrelationID() string {
+	return e.correlationID
+}
+
+func (e *BaseEvent[P]) CausationID() string {
+	return e.causationID
+}
+
+// Metadata returns the metadata of the event, e.g. the user or tenant it was produced for.
+func (e *BaseEvent[P]) Metadata() map[string]string {
+	return e.metadata
+}
+
+func (e *BaseEvent[P]) SetCorrelationID(id string) {
+	e.correlationID = id
+}
+
+func (e *BaseEvent[P]) SetCausationID(id string) {
+	e.causationID = id
+}
+
+func (e *BaseEvent[P]) SetMetadata(key, value string) {
+	if e.metadata == nil {
+		e.metadata = make(map[string]string)
+	}
+	e.metadata[key] = value
+}
+
+// CausedBy marks the event as caused by parent, sharing its correlation ID.
+func (e *BaseEvent[P]) CausedBy(parent Event) {
+	e.correlationID = parent.CorrelationID()
+	if identified, ok := parent.(interface{ EventID() string }); ok {
+		e.causationID = identified.EventID()
+	}
+}
+
+// Payload returns the JSON representation of Data as a map.
+func (e *BaseEvent[P]) Payload() map[string]interface{} {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil
+	}
+	return payload
+}
+
+// FromMap fills the event from its envelope. The payload is read from the PayloadKey entry, a map or a
+// JSON string, or from the whole map when there is none. Envelope entries missing in data keep their
+// current value, so factories can preset the event name and version.
+func (e *BaseEvent[P]) FromMap(data map[string]interface{}) error {
+	if v, ok := data[EventIDKey].(string); ok && v != "" {
+		e.id = v
+	}
+	if v, ok := data[EventNameKey].(string); ok && v != "" {
+		e.name = v
+	}
+	if v, ok := data[AggregateIDKey].(string); ok && v != "" {
+		e.aggregateID = v
+	}
+	if v, ok := data[CorrelationIDKey].(string); ok && v != "" {
+		e.correlationID = v
+	} else if _, ok := data[EventIDKey].(string); ok {
+		// The correlation ID preset by NewBaseEvent belongs to another event
+		e.correlationID = e.id
+	}
+	if v, ok := data[CausationIDKey].(string); ok && v != "" {
+		e.causationID = v
+	}
+
+	if occurredOn, err := parseTime(data[OccurredOnKey]); err != nil {
+		return err
+	} else if !occurredOn.IsZero() {
+		e.occurredOn = occurredOn
+	}
+
+	if version, err := parseInt(data[VersionKey]); err != nil {
+		return err
+	} else if version != 0 {
+		e.version = version
+	}
+
+	if metadata, ok := data[MetadataKey].(map[string]interface{}); ok {
+		for k, v := range metadata {
+			e.SetMetadata(k, fmt.Sprint(v))
+		}
+	}
+
+	var payload []byte
+	var err error
+	switch p := data[PayloadKey].(type) {
+	case string:
+		payload = []byte(p)
+	case nil:
+		payload, err = json.Marshal(data)
+	default:
+		payload, err = json.Marshal(p)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", e.name, err)
+	}
+
+	if err := json.Unmarshal(payload, &e.Data); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.name, err)
+	}
+
+	return nil
+}
+
+type eventEnvelope[P any] struct {
+	ID            string            `json:"event_id"`
+	Name          string            `json:"event_name"`
+	AggregateID   string            `json:"aggregate_id"`
+	OccurredOn    time.Time         `json:"occurred_on"`
+	Version       int               `json:"version"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	CausationID   string            `json:"causation_id,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Payload       P                 `json:"payload"`
+}
+
+func (e BaseEvent[P]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eventEnvelope[P]{
+		ID:            e.id,
+		Name:          e.name,
+		AggregateID:   e.aggregateID,
+		OccurredOn:    e.occurredOn,
+		Version:       e.version,
+		CorrelationID: e.correlationID,
+		CausationID:   e.causationID,
+		Metadata:      e.metadata,
+		Payload:       e.Data,
+	})
+}
+
+func (e *BaseEvent[P]) UnmarshalJSON(data []byte) error {
+	var envelope eventEnvelope[P]
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+
+	e.id = envelope.ID
+	e.name = envelope.Name
+	e.aggregateID = envelope.AggregateID
+	e.occurredOn = envelope.OccurredOn
+	e.version = envelope.Version
+	e.correlationID = envelope.CorrelationID
+	e.causationID = envelope.CausationID
+	e.metadata = envelope.Metadata
+	e.Data = envelope.Payload
+
+	return nil
+}
+
+func parseTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return t, nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid event time %q: %w", t, err)
+		}
+		return parsed, nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid event time %v", v)
+	}
+}
+
+func parseInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	case string:
+		parsed, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("invalid event version %q: %w", n, err)
+		}
+		return parsed, nil
+	default:
+		return 0, fmt.Errorf("invalid event version %v", v)
+	}
+}
+
+// NewEventID returns a random (version 4) UUID.
+func NewEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
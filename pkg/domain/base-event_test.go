@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type userPayload struct {
+	Email string `json:"email"`
+}
+
+type userCreated struct {
+	BaseEvent[userPayload]
+}
+
+func newUserCreated() *userCreated {
+	event := &userCreated{NewBaseEvent("user.created", "u1", 2, userPayload{Email: "ada@example.com"})}
+	event.SetCausationID("e0")
+	event.SetMetadata("tenant", "t1")
+	return event
+}
+
+// assertSameEvent compares the envelope and payload of two events.
+func assertSameEvent(t *testing.T, got, want *userCreated) {
+	t.Helper()
+
+	if got.EventID() != want.EventID() || got.EventName() != want.EventName() || got.AggregateID() != want.AggregateID() ||
+		!got.OccurredOn().Equal(want.OccurredOn()) || got.Version() != want.Version() || got.CorrelationID() != want.CorrelationID() ||
+		got.CausationID() != want.CausationID() || !reflect.DeepEqual(got.Metadata(), want.Metadata()) || got.Data != want.Data {
+		t.Errorf("event = %+v, want %+v", got.BaseEvent, want.BaseEvent)
+	}
+}
+
+func TestNewBaseEvent(t *testing.T) {
+	event := newUserCreated()
+
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(event.EventID()) {
+		t.Errorf("EventID = %q, want a version 4 UUID", event.EventID())
+	}
+	if event.CorrelationID() != event.EventID() {
+		t.Errorf("CorrelationID = %q, want the event ID", event.CorrelationID())
+	}
+	if !reflect.DeepEqual(event.Payload(), map[string]interface{}{"email": "ada@example.com"}) {
+		t.Errorf("Payload = %v", event.Payload())
+	}
+
+	caused := &userCreated{NewBaseEvent("user.welcomed", "u1", 1, userPayload{})}
+	caused.CausedBy(event)
+	if caused.CorrelationID() != event.CorrelationID() || caused.CausationID() != event.EventID() {
+		t.Errorf("caused event correlation %q causation %q, want %q and %q", caused.CorrelationID(), caused.CausationID(), event.CorrelationID(), event.EventID())
+	}
+}
+
+func TestBaseEventJSONRoundTrip(t *testing.T) {
+	event := newUserCreated()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, key := range []string{EventIDKey, EventNameKey, AggregateIDKey, OccurredOnKey, VersionKey, CorrelationIDKey, CausationIDKey, MetadataKey, PayloadKey} {
+		if _, ok := envelope[key]; !ok {
+			t.Errorf("envelope %s misses %s", data, key)
+		}
+	}
+
+	var decoded userCreated
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	assertSameEvent(t, &decoded, event)
+
+	// FromMap reads the envelope as decoded by a broker
+	fromMap := &userCreated{}
+	if err := fromMap.FromMap(envelope); err != nil {
+		t.Fatalf("FromMap: %v", err)
+	}
+	assertSameEvent(t, fromMap, event)
+}
+
+func TestBaseEventFromMap(t *testing.T) {
+	occurredOn := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		// check reports what is wrong with the event, empty when it is as expected
+		check func(e *userCreated) string
+	}{
+		{"payload as a JSON string", map[string]interface{}{PayloadKey: `{"email":"ada@example.com"}`}, func(e *userCreated) string {
+			if e.Data.Email != "ada@example.com" {
+				return "payload not decoded"
+			}
+			return ""
+		}},
+		{"flat payload", map[string]interface{}{AggregateIDKey: "u1", "email": "ada@example.com"}, func(e *userCreated) string {
+			if e.Data.Email != "ada@example.com" || e.AggregateID() != "u1" {
+				return "payload not read from the whole map"
+			}
+			return ""
+		}},
+		{"string version and time", map[string]interface{}{VersionKey: "3", OccurredOnKey: "2026-01-15T10:00:00Z"}, func(e *userCreated) string {
+			if e.Version() != 3 || !e.OccurredOn().Equal(occurredOn) {
+				return "version or time not parsed"
+			}
+			return ""
+		}},
+		{"missing entries keep the preset values", map[string]interface{}{EventIDKey: "e1"}, func(e *userCreated) string {
+			if e.EventName() != "user.created" || e.Version() != 2 {
+				return "preset name or version overwritten"
+			}
+			if e.CorrelationID() != "e1" {
+				return "correlation ID preset by NewBaseEvent kept"
+			}
+			return ""
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &userCreated{NewBaseEvent("user.created", "", 2, userPayload{})}
+			if err := event.FromMap(tt.data); err != nil {
+				t.Fatalf("FromMap: %v", err)
+			}
+			if problem := tt.check(event); problem != "" {
+				t.Errorf("FromMap(%v): %s, got %+v", tt.data, problem, event.BaseEvent)
+			}
+		})
+	}
+
+	for _, data := range []map[string]interface{}{
+		{VersionKey: "two"},
+		{OccurredOnKey: "yesterday"},
+		{OccurredOnKey: 42},
+		{PayloadKey: `{"email":`},
+		{PayloadKey: []interface{}{"not", "an", "object"}},
+	} {
+		if err := (&userCreated{}).FromMap(data); err == nil {
+			t.Errorf("FromMap(%v) = nil error", data)
+		}
+	}
+}
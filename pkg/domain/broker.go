@@ -6,3 +6,52 @@ type Broker interface {
 	FetchMessage(ctx context.Context) ([]byte, error)
 	Close()
 }
+
+// Message is a message fetched from a broker along with its transport headers.
+type Message struct {
+	// ID identifies the message in the broker, when it has such an ID
+	ID      string
+	Data    []byte
+	Headers map[string]string
+	// DeliveryCount is the number of times the message has been delivered, 1 on its first delivery and
+	// 0 when the broker doesn't track it
+	DeliveryCount int
+	// Acknowledger is set by brokers redelivering the messages that aren't acknowledged
+	Acknowledger Acknowledger
+}
+
+// Acknowledger acknowledges a message once it has been processed, or asks for its redelivery.
+type Acknowledger interface {
+	Ack(ctx context.Context) error
+	Nack(ctx context.Context) error
+}
+
+// Ack acknowledges the message, it is a no-op for brokers without acknowledgment.
+func (m *Message) Ack(ctx context.Context) error {
+	if m.Acknowledger == nil {
+		return nil
+	}
+	return m.Acknowledger.Ack(ctx)
+}
+
+// Nack asks the broker to redeliver the message, it is a no-op for brokers without acknowledgment.
+func (m *Message) Nack(ctx context.Context) error {
+	if m.Acknowledger == nil {
+		return nil
+	}
+	return m.Acknowledger.Nack(ctx)
+}
+
+// MessageBroker is implemented by brokers exposing the transport headers of their messages, which
+// codecs such as CloudEvents in binary mode rely on.
+type MessageBroker interface {
+	Broker
+	FetchMessageWithHeaders(ctx context.Context) (*Message, error)
+}
+
+// BatchBroker is implemented by brokers fetching several messages at once.
+type BatchBroker interface {
+	MessageBroker
+	// FetchBatch fetches up to max messages, an empty batch when none arrived in time.
+	FetchBatch(ctx context.Context, max int) ([]*Message, error)
+}
@@ -8,3 +8,8 @@ import (
 type EventHandler interface {
 	Handle(ctx context.Context, event Event) error
 }
+
+// BatchEventHandler defines the contract for handling events in bulk.
+type BatchEventHandler interface {
+	HandleBatch(ctx context.Context, events []Event) error
+}
@@ -2,18 +2,23 @@ package app
 
 import (
 	"context"
-	"github.com/gin-gonic/gin"
 	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
 	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
 	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
+	"net"
 	"net/http"
 
+	"google.golang.org/grpc"
+
+	grpc_server "github.com/thebranchcrafter/go-kit/pkg/infrastructure/grpc"
+	http_openapi "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/openapi"
 	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
 	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
 )
 
 type CommonDependencies struct {
-	Router         *gin.Engine
+	Router         router.Router
 	CommandBus     application_command.Bus
 	QueryBus       application_query.Bus
 	EventBus       application_event.EventBus
@@ -23,8 +28,15 @@ type CommonDependencies struct {
 
 // Kernel holds the core infrastructure and components.
 type Kernel struct {
-	server  *http.Server
-	Modules map[string]Module
+	server        *http.Server
+	grpcServer    *grpc.Server
+	grpcEnabled   bool
+	grpcOptions   []grpc.ServerOption
+	Modules       map[string]Module
+	apiPrefix     string
+	apiMiddleware []router.Middleware
+	middleware    []router.Middleware
+	openAPI       *OpenAPIConfig
 	CommonDependencies
 }
 
@@ -36,19 +48,47 @@ func NewKernel(options ...func(*Kernel)) *Kernel {
 	for _, opt := range options {
 		opt(k)
 	}
+	if k.grpcEnabled {
+		k.grpcServer = grpc_server.NewServer(k.Logger, k.grpcOptions...)
+	}
 	return k
 }
 
 // WithRouter sets a custom router implementation.
-func WithRouter(r *gin.Engine) func(*Kernel) {
+func WithRouter(r router.Router) func(*Kernel) {
 	return func(k *Kernel) {
 		k.Router = r
 		if k.server != nil {
-			k.server.Handler = r.Handler()
+			k.server.Handler = k.handler()
 		}
 	}
 }
 
+// WithMiddleware wraps the whole server handler with the given middleware, including requests that
+// match no route (e.g. CORS preflight requests).
+func WithMiddleware(middleware ...router.Middleware) func(*Kernel) {
+	return func(k *Kernel) {
+		k.middleware = append(k.middleware, middleware...)
+	}
+}
+
+// WithAPIPrefix mounts every module route under prefix (e.g. "/api"), wrapped with the given middleware.
+func WithAPIPrefix(prefix string, middleware ...router.Middleware) func(*Kernel) {
+	return func(k *Kernel) {
+		k.apiPrefix = prefix
+		k.apiMiddleware = middleware
+	}
+}
+
+// WithGRPCServer enables the gRPC server, created with grpc_server.NewServer using the kernel logger
+// and the given options. Modules implementing GRPCModule register their services in it.
+func WithGRPCServer(opts ...grpc.ServerOption) func(*Kernel) {
+	return func(k *Kernel) {
+		k.grpcEnabled = true
+		k.grpcOptions = append(k.grpcOptions, opts...)
+	}
+}
+
 // WithCommandBus sets a custom CommandBus.
 func WithCommandBus(cb application_command.Bus) func(*Kernel) {
 	return func(k *Kernel) {
@@ -116,31 +156,154 @@ func (k *Kernel) GetModule(moduleName string) Module {
 }
 
 // RegisterRoutes allows each module to register its routes.
+//
+// Routes are mounted under the API prefix, the route or module version and the module route group:
+// "/api" + "/v1" + "/users" + "/{id}".
 func (k *Kernel) RegisterRoutes() {
+	api := k.Router
+	if k.apiPrefix != "" || len(k.apiMiddleware) > 0 {
+		api = k.Router.Group(k.apiPrefix, k.apiMiddleware...)
+	}
+
+	var openAPI *http_openapi.Generator
+	if k.openAPI != nil {
+		openAPI = http_openapi.NewGenerator(k.openAPI.Info)
+		for name, scheme := range k.openAPI.SecuritySchemes {
+			openAPI.AddSecurityScheme(name, scheme)
+		}
+	}
+
 	for _, module := range k.Modules {
+		moduleVersion := ""
+		if vm, ok := module.(VersionedModule); ok {
+			moduleVersion = vm.Version()
+		}
+
+		groups := make(map[string]router.Router)
 		for _, route := range module.Routes() {
-			// Apply middleware if any
-			handler := route.Handler
-			for _, mw := range route.Middlewares {
-				handler = mw(handler)
+			version := route.Version
+			if version == "" {
+				version = moduleVersion
+			}
+
+			group, ok := groups[version]
+			if !ok {
+				group = k.moduleGroup(api, module, version)
+				groups[version] = group
+			}
+
+			// Register the route in the router, applying its middleware
+			group.Handle(route.Method, route.Path, route.Handler, route.Middlewares...)
+
+			if openAPI != nil && !route.Doc.Hidden {
+				openAPI.AddOperation(k.operationSpec(module, version, route))
 			}
-			// Register the route in the router
-			k.Router.Handle(route.Method, route.Path, handler)
 		}
 	}
+
+	if openAPI != nil {
+		k.registerOpenAPI(openAPI.Document())
+	}
+}
+
+// moduleGroup returns the router the module routes for the given version are registered in.
+func (k *Kernel) moduleGroup(api router.Router, module Module, version string) router.Router {
+	group := api
+	if version != "" {
+		group = group.Group(version)
+	}
+
+	if gm, ok := module.(GroupedModule); ok {
+		prefix, middleware := gm.RouteGroup()
+		if prefix != "" || len(middleware) > 0 {
+			group = group.Group(prefix, middleware...)
+		}
+	}
+
+	return group
+}
+
+// routePath returns the full path a module route is mounted at.
+func (k *Kernel) routePath(module Module, version string, route Route) string {
+	path := k.apiPrefix
+	if version != "" {
+		path = router.JoinPath(path, version)
+	}
+	if gm, ok := module.(GroupedModule); ok {
+		prefix, _ := gm.RouteGroup()
+		path = router.JoinPath(path, prefix)
+	}
+
+	return router.JoinPath(path, route.Path)
 }
 
 // StartServer starts the HTTP server.
 func (k *Kernel) StartServer(port string) error {
 	k.server = &http.Server{
 		Addr:    port,
-		Handler: k.Router.Handler(),
+		Handler: k.handler(),
 	}
 
 	return k.server.ListenAndServe()
 }
 
-// ShutdownServer gracefully shuts down the HTTP server.
+// handler returns the router handler wrapped with the server middleware.
+func (k *Kernel) handler() http.Handler {
+	return router.Wrap(k.Router.Handler(), k.middleware...)
+}
+
+// GRPCServer returns the gRPC server enabled with WithGRPCServer, nil otherwise.
+func (k *Kernel) GRPCServer() *grpc.Server {
+	return k.grpcServer
+}
+
+// RegisterGRPCServices allows each GRPCModule to register its services in the gRPC server.
+func (k *Kernel) RegisterGRPCServices() {
+	if k.grpcServer == nil {
+		return
+	}
+
+	for _, module := range k.Modules {
+		if gm, ok := module.(GRPCModule); ok {
+			gm.RegisterGRPC(k.grpcServer)
+		}
+	}
+}
+
+// StartGRPCServer starts the gRPC server enabled with WithGRPCServer.
+func (k *Kernel) StartGRPCServer(addr string) error {
+	if k.grpcServer == nil {
+		return NewGRPCServerNotEnabledError()
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return k.grpcServer.Serve(lis)
+}
+
+// ShutdownServer gracefully shuts down the HTTP and gRPC servers. The gRPC server is stopped
+// immediately when ctx is done before its pending calls complete.
 func (k *Kernel) ShutdownServer(ctx context.Context) error {
+	if k.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			k.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				k.grpcServer.Stop()
+			}
+		}()
+	}
+
+	if k.server == nil {
+		return nil
+	}
 	return k.server.Shutdown(ctx)
 }
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serveGRPC serves the kernel gRPC server with the health service on an in-memory listener,
+// returning a health client and the channel receiving the Serve result.
+func serveGRPC(t *testing.T, k *Kernel) (grpc_health_v1.HealthClient, <-chan error) {
+	t.Helper()
+
+	grpc_health_v1.RegisterHealthServer(k.GRPCServer(), health.NewServer())
+	lis := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() {
+		served <- k.GRPCServer().Serve(lis)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	client := grpc_health_v1.NewHealthClient(conn)
+	if _, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return client, served
+}
+
+func waitServed(t *testing.T, served <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve = %v, want nil once stopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("gRPC server still serving after ShutdownServer")
+	}
+}
+
+func TestKernelShutdownServerStopsGRPCGracefully(t *testing.T) {
+	k := NewKernel(WithGRPCServer())
+	_, served := serveGRPC(t, k)
+
+	if err := k.ShutdownServer(context.Background()); err != nil {
+		t.Fatalf("ShutdownServer: %v", err)
+	}
+	waitServed(t, served)
+}
+
+func TestKernelShutdownServerForcesGRPCStopWhenCtxIsDone(t *testing.T) {
+	k := NewKernel(WithGRPCServer())
+	client, served := serveGRPC(t, k)
+
+	// A Watch stream stays open until the server stops, holding GracefulStop
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := k.ShutdownServer(ctx); err != nil {
+		t.Fatalf("ShutdownServer: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("ShutdownServer returned after %v, want once ctx was done", elapsed)
+	}
+	waitServed(t, served)
+
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Recv = nil, want the stream ended by the stop")
+	}
+}
+
+func TestKernelWithoutGRPCServer(t *testing.T) {
+	k := NewKernel()
+
+	if k.GRPCServer() != nil {
+		t.Error("GRPCServer != nil without WithGRPCServer")
+	}
+	var notEnabled *GRPCServerNotEnabledError
+	if err := k.StartGRPCServer("127.0.0.1:0"); !errors.As(err, &notEnabled) {
+		t.Errorf("StartGRPCServer = %v, want GRPCServerNotEnabledError", err)
+	}
+	if err := k.ShutdownServer(context.Background()); err != nil {
+		t.Errorf("ShutdownServer = %v, want nil without servers", err)
+	}
+}
@@ -2,21 +2,42 @@ package app
 
 import (
 	"fmt"
-	"github.com/gin-gonic/gin"
 	"github.com/thebranchcrafter/go-kit/pkg/application"
 	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
 	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
 	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+	"net/http"
+	"reflect"
+
+	"google.golang.org/grpc"
 )
 
 type Modules []Module
 
 // Route represents an HTTP route with its method, path, handler, and middleware.
+// Version, when set, overrides the module version the route is mounted under.
 type Route struct {
 	Method      string
 	Path        string
-	Handler     gin.HandlerFunc
+	Handler     http.HandlerFunc
 	Middlewares []router.Middleware
+	Version     string
+	Doc         RouteDoc
+}
+
+// RouteDoc documents a route in the generated OpenAPI document.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	// Tags default to the module name
+	Tags []string
+	// Request is the struct the request is bound into, Response the type written on success
+	Request       reflect.Type
+	Response      reflect.Type
+	SuccessStatus int
+	// Security lists the names of the security schemes accepted by the route
+	Security []string
+	Hidden   bool
 }
 
 // Module represents a module that can register routes.
@@ -27,9 +48,26 @@ type Module interface {
 	Queries() map[application.Query]application_query.QueryHandler
 }
 
+// GroupedModule is implemented by modules mounting their routes under a shared prefix and middleware.
+type GroupedModule interface {
+	RouteGroup() (prefix string, middleware []router.Middleware)
+}
+
+// VersionedModule is implemented by modules mounting their routes under an API version (e.g. "v1").
+type VersionedModule interface {
+	Version() string
+}
+
+// GRPCModule is implemented by modules exposing gRPC services next to their HTTP routes.
+type GRPCModule interface {
+	RegisterGRPC(s grpc.ServiceRegistrar)
+}
+
 type BaseModule struct {
-	commands map[application.Command]application_command.CommandHandler
-	queries  map[application.Query]application_query.QueryHandler
+	commands        map[application.Command]application_command.CommandHandler
+	queries         map[application.Query]application_query.QueryHandler
+	routePrefix     string
+	routeMiddleware []router.Middleware
 	CommonDependencies
 }
 
@@ -49,6 +87,17 @@ func (bm *BaseModule) AddQuery(c application.Query, queryHandler application_que
 	bm.queries[c] = queryHandler
 }
 
+// SetRouteGroup mounts the module routes under prefix, wrapped with the given middleware
+func (bm *BaseModule) SetRouteGroup(prefix string, middleware ...router.Middleware) {
+	bm.routePrefix = prefix
+	bm.routeMiddleware = middleware
+}
+
+// RouteGroup returns the prefix and middleware shared by the module routes
+func (bm *BaseModule) RouteGroup() (string, []router.Middleware) {
+	return bm.routePrefix, bm.routeMiddleware
+}
+
 // Commands returns all commands registered in the module
 func (bm *BaseModule) Commands() map[application.Command]application_command.CommandHandler {
 	return bm.commands
@@ -59,6 +108,15 @@ func (bm *BaseModule) Queries() map[application.Query]application_query.QueryHan
 	return bm.queries
 }
 
+// BuildRoutes builds the given route definitions with the module dependencies
+func (bm *BaseModule) BuildRoutes(definitions ...RouteDefinition) []Route {
+	routes := make([]Route, 0, len(definitions))
+	for _, rd := range definitions {
+		routes = append(routes, rd.Build(bm.CommonDependencies))
+	}
+	return routes
+}
+
 type AlreadyExistsError struct {
 	m Module
 }
@@ -70,3 +128,14 @@ func NewModuleAlreadyExistsError(m Module) *AlreadyExistsError {
 func (m AlreadyExistsError) Error() string {
 	return fmt.Sprintf("module %s already exists", m.m.Name())
 }
+
+// GRPCServerNotEnabledError is returned when starting the gRPC server without WithGRPCServer.
+type GRPCServerNotEnabledError struct{}
+
+func NewGRPCServerNotEnabledError() *GRPCServerNotEnabledError {
+	return &GRPCServerNotEnabledError{}
+}
+
+func (GRPCServerNotEnabledError) Error() string {
+	return "grpc server not enabled, use WithGRPCServer"
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+
+	http_openapi "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/openapi"
+)
+
+// OpenAPIConfig configures the OpenAPI document generated from the module routes.
+type OpenAPIConfig struct {
+	Info http_openapi.Info
+	// Path the document is served at, "/openapi.json" by default.
+	Path string
+	// SwaggerUIPath serves a Swagger UI page for the document when set (e.g. "/docs").
+	SwaggerUIPath string
+	// SwaggerUIOptions tune the Swagger UI page, e.g. http_openapi.WithSwaggerUIAssets to serve its
+	// assets from the application.
+	SwaggerUIOptions []func(*http_openapi.SwaggerUIOptions)
+	SecuritySchemes  map[string]http_openapi.SecurityScheme
+}
+
+// WithOpenAPI generates an OpenAPI 3.1 document from the module routes when they are registered.
+func WithOpenAPI(config OpenAPIConfig) func(*Kernel) {
+	return func(k *Kernel) {
+		if config.Path == "" {
+			config.Path = "/openapi.json"
+		}
+		k.openAPI = &config
+	}
+}
+
+// operationSpec describes a module route for the OpenAPI generator.
+func (k *Kernel) operationSpec(module Module, version string, route Route) http_openapi.OperationSpec {
+	tags := route.Doc.Tags
+	if len(tags) == 0 {
+		tags = []string{module.Name()}
+	}
+
+	return http_openapi.OperationSpec{
+		Method:        route.Method,
+		Path:          k.routePath(module, version, route),
+		Summary:       route.Doc.Summary,
+		Description:   route.Doc.Description,
+		Tags:          tags,
+		Request:       route.Doc.Request,
+		Response:      route.Doc.Response,
+		SuccessStatus: route.Doc.SuccessStatus,
+		Security:      route.Doc.Security,
+	}
+}
+
+// registerOpenAPI serves the document and, if enabled, the Swagger UI page.
+func (k *Kernel) registerOpenAPI(doc *http_openapi.Document) {
+	k.Router.Handle(http.MethodGet, k.openAPI.Path, http_openapi.Handler(doc))
+
+	if k.openAPI.SwaggerUIPath != "" {
+		k.Router.Handle(http.MethodGet, k.openAPI.SwaggerUIPath, http_openapi.SwaggerUIHandler(doc.Info.Title, k.openAPI.Path, k.openAPI.SwaggerUIOptions...))
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"net/http"
+	"reflect"
+
+	http_request "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/request"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// RouteDefinition declares a route whose handler is built once the module dependencies are known.
+type RouteDefinition struct {
+	Method      string
+	Path        string
+	Middlewares []router.Middleware
+	Version     string
+	Doc         RouteDoc
+	handler     func(d CommonDependencies) http.HandlerFunc
+}
+
+// With adds middlewares to the route definition.
+func (rd RouteDefinition) With(middlewares ...router.Middleware) RouteDefinition {
+	rd.Middlewares = append(append([]router.Middleware{}, rd.Middlewares...), middlewares...)
+	return rd
+}
+
+// InVersion mounts the route under the given API version instead of the module one.
+func (rd RouteDefinition) InVersion(version string) RouteDefinition {
+	rd.Version = version
+	return rd
+}
+
+// Describe sets the summary and description of the route in the OpenAPI document.
+func (rd RouteDefinition) Describe(summary, description string) RouteDefinition {
+	rd.Doc.Summary = summary
+	rd.Doc.Description = description
+	return rd
+}
+
+// Returns documents the type of the response, given as a value of that type.
+func (rd RouteDefinition) Returns(response interface{}) RouteDefinition {
+	rd.Doc.Response = reflect.TypeOf(response)
+	return rd
+}
+
+// Tagged sets the tags of the route in the OpenAPI document.
+func (rd RouteDefinition) Tagged(tags ...string) RouteDefinition {
+	rd.Doc.Tags = tags
+	return rd
+}
+
+// SecuredBy documents the security schemes accepted by the route.
+func (rd RouteDefinition) SecuredBy(schemes ...string) RouteDefinition {
+	rd.Doc.Security = schemes
+	return rd
+}
+
+// Build turns the definition into a Route using the given dependencies.
+func (rd RouteDefinition) Build(d CommonDependencies) Route {
+	return Route{
+		Method:      rd.Method,
+		Path:        rd.Path,
+		Handler:     rd.handler(d),
+		Middlewares: rd.Middlewares,
+		Version:     rd.Version,
+		Doc:         rd.Doc,
+	}
+}
+
+// CommandRoute maps a route to the command C: the request is bound into C, dispatched through the
+// CommandBus and answered with successStatus.
+func CommandRoute[C any, PC http_request.CommandPointer[C]](method, path string, successStatus int) RouteDefinition {
+	return RouteDefinition{
+		Method: method,
+		Path:   path,
+		Doc:    RouteDoc{Request: reflect.TypeOf((*C)(nil)).Elem(), SuccessStatus: successStatus},
+		handler: func(d CommonDependencies) http.HandlerFunc {
+			return http_request.DispatchCommand[C, PC](d.CommandBus, d.ResponseWriter, successStatus)
+		},
+	}
+}
+
+// QueryRoute maps a route to the query Q: the request is bound into Q, asked to the QueryBus and the
+// result is written with successStatus.
+func QueryRoute[Q any, PQ http_request.QueryPointer[Q]](method, path string, successStatus int) RouteDefinition {
+	return RouteDefinition{
+		Method: method,
+		Path:   path,
+		Doc:    RouteDoc{Request: reflect.TypeOf((*Q)(nil)).Elem(), SuccessStatus: successStatus},
+		handler: func(d CommonDependencies) http.HandlerFunc {
+			return http_request.AskQuery[Q, PQ](d.QueryBus, d.ResponseWriter, successStatus)
+		},
+	}
+}
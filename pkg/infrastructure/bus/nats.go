@@ -0,0 +1,187 @@
+package infrastructure_bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+)
+
+const defaultMaxConcurrency = 64
+
+// ErrNoHandler is returned when no process subscribes to the subject of a command or query.
+var ErrNoHandler = errors.New("no remote handler")
+
+// NATSConfig configures the NATS command and query buses.
+type NATSConfig struct {
+	// SubjectPrefix is prepended to the command or query Id
+	SubjectPrefix string
+	// QueueGroup load balances the requests of a subject between the instances handling it,
+	// defaults to SubjectPrefix
+	QueueGroup string
+	// Timeout bounds requests sent without a context deadline and the handling of incoming ones,
+	// defaults to 5 seconds
+	Timeout time.Duration
+	// MaxConcurrency bounds the messages of a subscription handled at once, further messages waiting
+	// in the subscription pending buffer, defaults to 64
+	MaxConcurrency int
+}
+
+// WithDefaults returns the config with its empty fields set, using prefix as default SubjectPrefix.
+func (c NATSConfig) WithDefaults(prefix string) NATSConfig {
+	if c.SubjectPrefix == "" {
+		c.SubjectPrefix = prefix
+	}
+	if c.QueueGroup == "" {
+		c.QueueGroup = c.SubjectPrefix
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.MaxConcurrency <= 0 {
+		c.MaxConcurrency = defaultMaxConcurrency
+	}
+	return c
+}
+
+// Subject returns the subject a command or query with the given Id is sent to, "commands.user.create".
+func (c NATSConfig) Subject(id string) string {
+	return c.SubjectPrefix + "." + subjectReplacer.Replace(id)
+}
+
+var subjectReplacer = strings.NewReplacer(" ", "_", "\t", "_", "*", "_", ">", "_")
+
+type reply struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RemoteError    `json:"error,omitempty"`
+}
+
+// Request sends payload to the subject of id and waits for the reply of the remote handler, returning its
+// JSON encoded result or its decoded error. The result is nil when the handler returned none.
+func Request(ctx context.Context, conn *nats.Conn, cfg NATSConfig, id string, payload interface{}) (json.RawMessage, error) {
+	msg, err := newMsg(ctx, cfg.Subject(id), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	resp, err := conn.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return nil, fmt.Errorf("%w for %s", ErrNoHandler, msg.Subject)
+		}
+		return nil, fmt.Errorf("failed to request %s: %w", msg.Subject, err)
+	}
+
+	var r reply
+	if err := json.Unmarshal(resp.Data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode reply of %s: %w", msg.Subject, err)
+	}
+	if r.Error != nil {
+		return nil, DecodeError(r.Error)
+	}
+	if len(r.Result) == 0 {
+		return nil, nil
+	}
+
+	return r.Result, nil
+}
+
+// Publish sends payload to the subject of id without waiting for it to be handled.
+func Publish(ctx context.Context, conn *nats.Conn, cfg NATSConfig, id string, payload interface{}) error {
+	msg, err := newMsg(ctx, cfg.Subject(id), payload)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", msg.Subject, err)
+	}
+
+	return nil
+}
+
+// HandlerFunc handles a message received on a subject, msg.Reply being empty for published messages.
+type HandlerFunc func(ctx context.Context, msg *nats.Msg) (interface{}, error)
+
+// Subscribe handles the messages sent to the subject of id with handler, up to cfg.MaxConcurrency at
+// once each in its own goroutine, replying with the handler result or error.
+func Subscribe(conn *nats.Conn, cfg NATSConfig, id string, handler HandlerFunc) (*nats.Subscription, error) {
+	limit := cfg.MaxConcurrency
+	if limit <= 0 {
+		limit = defaultMaxConcurrency
+	}
+	sem := make(chan struct{}, limit)
+
+	sub, err := conn.QueueSubscribe(cfg.Subject(id), cfg.QueueGroup, func(msg *nats.Msg) {
+		// Blocking the subscription callback leaves the next messages pending
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			serve(conn, cfg, msg, handler)
+		}()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", cfg.Subject(id), err)
+	}
+
+	return sub, nil
+}
+
+func serve(conn *nats.Conn, cfg NATSConfig, msg *nats.Msg, handler HandlerFunc) {
+	ctx := context.Background()
+	if id := msg.Header.Get(requestid.Header); id != "" {
+		ctx = requestid.NewContext(ctx, id)
+	}
+
+	// Published messages are handled asynchronously, their context must outlive this call
+	if msg.Reply == "" {
+		_, _ = handler(ctx, msg)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	result, err := handler(ctx, msg)
+
+	var r reply
+	if err != nil {
+		r.Error = EncodeError(err)
+	} else if result != nil {
+		if r.Result, err = json.Marshal(result); err != nil {
+			r.Error = EncodeError(fmt.Errorf("failed to encode result: %w", err))
+		}
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	_ = conn.Publish(msg.Reply, data)
+}
+
+func newMsg(ctx context.Context, subject string, payload interface{}) (*nats.Msg, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize %s: %w", subject, err)
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if id := requestid.FromContext(ctx); id != "" {
+		msg.Header.Set(requestid.Header, id)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,182 @@
+package infrastructure_bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+)
+
+func connectNATS(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+
+	conn, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to nats: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+func TestRequestReply(t *testing.T) {
+	conn := connectNATS(t)
+	cfg := NATSConfig{}.WithDefaults("test")
+
+	_, err := Subscribe(conn, cfg, "echo", func(ctx context.Context, msg *nats.Msg) (interface{}, error) {
+		var payload map[string]string
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			return nil, err
+		}
+		payload["request_id"] = requestid.FromContext(ctx)
+		return payload, nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	result, err := Request(ctx, conn, cfg, "echo", map[string]string{"name": "gopher"})
+	if err != nil {
+		t.Fatalf("Request: %v", err)
+	}
+	if string(result) != `{"name":"gopher","request_id":"req-1"}` {
+		t.Errorf("result = %s", result)
+	}
+}
+
+func TestRequestWithoutResult(t *testing.T) {
+	conn := connectNATS(t)
+	cfg := NATSConfig{}.WithDefaults("test")
+
+	_, err := Subscribe(conn, cfg, "noop", func(context.Context, *nats.Msg) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	result, err := Request(context.Background(), conn, cfg, "noop", struct{}{})
+	if err != nil || result != nil {
+		t.Errorf("Request = %q, %v, want nil, nil", result, err)
+	}
+}
+
+func TestRequestWithoutHandler(t *testing.T) {
+	conn := connectNATS(t)
+
+	_, err := Request(context.Background(), conn, NATSConfig{}.WithDefaults("test"), "missing", struct{}{})
+	if !errors.Is(err, ErrNoHandler) {
+		t.Errorf("Request = %v, want ErrNoHandler", err)
+	}
+}
+
+type customError struct {
+	Code string `json:"code"`
+}
+
+func (e *customError) Error() string {
+	return "custom " + e.Code
+}
+
+func TestRemoteErrorKinds(t *testing.T) {
+	RegisterJSONErrorKind[*customError]("custom")
+
+	conn := connectNATS(t)
+	cfg := NATSConfig{}.WithDefaults("test")
+
+	tests := []struct {
+		name string
+		err  error
+		// want checks the error returned to the caller
+		want func(err error) bool
+	}{
+		{"validation", application.NewValidationError(application.FieldError{Field: "name", Tag: "required", Message: "name is required"}), func(err error) bool {
+			var target *application.ValidationError
+			return errors.As(err, &target) && reflect.DeepEqual(target.Fields, []application.FieldError{{Field: "name", Tag: "required", Message: "name is required"}})
+		}},
+		{"binding", application.NewBindingError("invalid JSON body", errors.New("unexpected EOF")), func(err error) bool {
+			var target *application.BindingError
+			return errors.As(err, &target) && target.Message == "invalid JSON body"
+		}},
+		{"unauthenticated", application_auth.NewUnauthenticated("missing token"), func(err error) bool {
+			var target *application_auth.Unauthenticated
+			return errors.As(err, &target) && target.Message == "missing token"
+		}},
+		{"forbidden", fmt.Errorf("authorizing: %w", application_auth.NewForbidden("forbidden", "users:write")), func(err error) bool {
+			var target *application_auth.Forbidden
+			return errors.As(err, &target) && reflect.DeepEqual(target.Missing, []string{"users:write"})
+		}},
+		{"invalid dto", application.NewInvalidDto("invalid payload"), func(err error) bool {
+			var target application.InvalidDto
+			return errors.As(err, &target) && target.Error() == "invalid payload"
+		}},
+		{"command not registered", application_command.NewCommandNotRegistered("Command not registered", "create_user"), func(err error) bool {
+			var target application_command.CommandNotRegistered
+			return errors.As(err, &target)
+		}},
+		{"query not registered", application_query.NewQueryNotRegistered("Query not registered", "get_user"), func(err error) bool {
+			var target application_query.QueryNotRegistered
+			return errors.As(err, &target)
+		}},
+		{"deadline exceeded", fmt.Errorf("querying: %w", context.DeadlineExceeded), func(err error) bool {
+			return errors.Is(err, context.DeadlineExceeded)
+		}},
+		{"canceled", context.Canceled, func(err error) bool {
+			return errors.Is(err, context.Canceled)
+		}},
+		{"registered", &customError{Code: "E42"}, func(err error) bool {
+			var target *customError
+			return errors.As(err, &target) && target.Code == "E42"
+		}},
+		{"unknown", errors.New("boom"), func(err error) bool {
+			var target *RemoteError
+			return errors.As(err, &target) && target.Kind == UnknownErrorKind && target.Message == "boom"
+		}},
+	}
+
+	// The handler fails with the error of the test named in the payload
+	_, err := Subscribe(conn, cfg, "fail", func(_ context.Context, msg *nats.Msg) (interface{}, error) {
+		var name string
+		_ = json.Unmarshal(msg.Data, &name)
+		for _, tt := range tests {
+			if tt.name == name {
+				return nil, tt.err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Request(context.Background(), conn, cfg, "fail", tt.name)
+			if !tt.want(err) {
+				t.Errorf("Request = %T %v, want %T %v", err, err, tt.err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package infrastructure_bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
+)
+
+// UnknownErrorKind is the kind of the errors matching no registered ErrorKind.
+const UnknownErrorKind = "unknown"
+
+// RemoteError is the wire representation of an error returned by a remote handler. It is also the
+// error returned to the caller when its kind isn't registered in the calling process.
+type RemoteError struct {
+	Kind    string          `json:"kind"`
+	Message string          `json:"message"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// ErrorKind describes how an error type crosses process boundaries, so errors.As keeps working on the
+// caller side.
+type ErrorKind struct {
+	Name string
+	// Match returns the error of this kind found in err's chain, if any
+	Match func(err error) (error, bool)
+	// Decode rebuilds the error from its message and details
+	Decode func(message string, details json.RawMessage) error
+}
+
+var errorKinds = struct {
+	sync.RWMutex
+	kinds []ErrorKind
+}{}
+
+func init() {
+	RegisterJSONErrorKind[*application.ValidationError]("validation")
+	RegisterJSONErrorKind[*application.BindingError]("binding")
+	RegisterJSONErrorKind[*application_auth.Unauthenticated]("unauthenticated")
+	RegisterJSONErrorKind[*application_auth.Forbidden]("forbidden")
+	RegisterErrorKind(ErrorKind{
+		Name:  "invalid_dto",
+		Match: matchAs[application.InvalidDto],
+		Decode: func(message string, _ json.RawMessage) error {
+			return application.NewInvalidDto(message)
+		},
+	})
+	RegisterErrorKind(ErrorKind{
+		Name:  "command_not_registered",
+		Match: matchAs[application_command.CommandNotRegistered],
+		Decode: func(message string, _ json.RawMessage) error {
+			return application_command.NewCommandNotRegistered(message, "")
+		},
+	})
+	RegisterErrorKind(ErrorKind{
+		Name:  "query_not_registered",
+		Match: matchAs[application_query.QueryNotRegistered],
+		Decode: func(message string, _ json.RawMessage) error {
+			return application_query.NewQueryNotRegistered(message, "")
+		},
+	})
+	RegisterErrorKind(sentinelKind("deadline_exceeded", context.DeadlineExceeded))
+	RegisterErrorKind(sentinelKind("canceled", context.Canceled))
+}
+
+// RegisterErrorKind registers an error kind, kinds are matched in registration order.
+func RegisterErrorKind(kind ErrorKind) {
+	errorKinds.Lock()
+	defer errorKinds.Unlock()
+
+	for i, k := range errorKinds.kinds {
+		if k.Name == kind.Name {
+			errorKinds.kinds[i] = kind
+			return
+		}
+	}
+	errorKinds.kinds = append(errorKinds.kinds, kind)
+}
+
+// RegisterJSONErrorKind registers the error type E, serialized as JSON. E must be a pointer to a struct
+// whose state is held in exported fields.
+func RegisterJSONErrorKind[E error](name string) {
+	RegisterErrorKind(ErrorKind{
+		Name:  name,
+		Match: matchAs[E],
+		Decode: func(message string, details json.RawMessage) error {
+			target := reflect.New(reflect.TypeOf((*E)(nil)).Elem().Elem())
+			if len(details) > 0 {
+				if err := json.Unmarshal(details, target.Interface()); err != nil {
+					return &RemoteError{Kind: name, Message: message, Details: details}
+				}
+			}
+			return target.Interface().(error)
+		},
+	})
+}
+
+// EncodeError converts err into its wire representation.
+func EncodeError(err error) *RemoteError {
+	if err == nil {
+		return nil
+	}
+
+	var remote *RemoteError
+	if errors.As(err, &remote) {
+		return remote
+	}
+
+	errorKinds.RLock()
+	defer errorKinds.RUnlock()
+
+	for _, kind := range errorKinds.kinds {
+		matched, ok := kind.Match(err)
+		if !ok {
+			continue
+		}
+
+		details, _ := json.Marshal(matched)
+		if string(details) == "{}" || string(details) == "null" {
+			details = nil
+		}
+		return &RemoteError{Kind: kind.Name, Message: matched.Error(), Details: details}
+	}
+
+	return &RemoteError{Kind: UnknownErrorKind, Message: err.Error()}
+}
+
+// DecodeError rebuilds the error described by remote, keeping it as a RemoteError when its kind isn't registered.
+func DecodeError(remote *RemoteError) error {
+	if remote == nil {
+		return nil
+	}
+
+	errorKinds.RLock()
+	defer errorKinds.RUnlock()
+
+	for _, kind := range errorKinds.kinds {
+		if kind.Name == remote.Kind {
+			return kind.Decode(remote.Message, remote.Details)
+		}
+	}
+
+	return remote
+}
+
+func matchAs[E error](err error) (error, bool) {
+	var target E
+	if errors.As(err, &target) {
+		return target, true
+	}
+	return nil, false
+}
+
+func sentinelKind(name string, sentinel error) ErrorKind {
+	return ErrorKind{
+		Name: name,
+		Match: func(err error) (error, bool) {
+			if errors.Is(err, sentinel) {
+				return sentinel, true
+			}
+			return nil, false
+		},
+		Decode: func(string, json.RawMessage) error {
+			return sentinel
+		},
+	}
+}
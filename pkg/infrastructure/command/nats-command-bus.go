@@ -0,0 +1,130 @@
+package infrastructure_command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	infrastructure_bus "github.com/thebranchcrafter/go-kit/pkg/infrastructure/bus"
+)
+
+var _ application_command.Bus = (*NATSCommandBus)(nil)
+
+// NATSCommandBus dispatches commands to other processes through NATS request-reply. Commands registered
+// in it are handled by the local bus and served on the "<prefix>.<command Id>" subject, the others are
+// sent to that subject and answered by the process registering them.
+type NATSCommandBus struct {
+	conn  *nats.Conn
+	local application_command.Bus
+	cfg   infrastructure_bus.NATSConfig
+	lock  sync.RWMutex
+	types map[string]reflect.Type
+	subs  []*nats.Subscription
+}
+
+// NewNATSCommandBus creates a NATSCommandBus handling registered commands with local. The subject
+// prefix defaults to "commands".
+func NewNATSCommandBus(conn *nats.Conn, local application_command.Bus, cfg infrastructure_bus.NATSConfig) *NATSCommandBus {
+	return &NATSCommandBus{
+		conn:  conn,
+		local: local,
+		cfg:   cfg.WithDefaults("commands"),
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// RegisterCommand registers the handler in the local bus and serves the command to the other processes.
+// Commands are decoded from JSON into a new value of the type of c, which must be a pointer to a struct.
+func (bus *NATSCommandBus) RegisterCommand(c application.Command, handler application_command.CommandHandler) error {
+	t := reflect.TypeOf(c)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return application.NewInvalidDto("command must be a pointer to a struct")
+	}
+
+	if err := bus.local.RegisterCommand(c, handler); err != nil {
+		return err
+	}
+
+	sub, err := infrastructure_bus.Subscribe(bus.conn, bus.cfg, c.Id(), bus.handler(t))
+	if err != nil {
+		return err
+	}
+
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.types[c.Id()] = t
+	bus.subs = append(bus.subs, sub)
+
+	return nil
+}
+
+// Dispatch handles the command locally when registered in this bus, otherwise it is sent to the
+// remote handler and the call waits for its result.
+func (bus *NATSCommandBus) Dispatch(ctx context.Context, c application.Command) error {
+	if bus.isLocal(c) {
+		return bus.local.Dispatch(ctx, c)
+	}
+
+	_, err := infrastructure_bus.Request(ctx, bus.conn, bus.cfg, c.Id(), c)
+	if errors.Is(err, infrastructure_bus.ErrNoHandler) {
+		return application_command.NewCommandNotRegistered("Command not registered", c.Id())
+	}
+
+	return err
+}
+
+// DispatchAsync handles the command locally when registered in this bus, otherwise it is published
+// without waiting for the remote handler.
+func (bus *NATSCommandBus) DispatchAsync(ctx context.Context, c application.Command) error {
+	if bus.isLocal(c) {
+		return bus.local.DispatchAsync(ctx, c)
+	}
+
+	return infrastructure_bus.Publish(ctx, bus.conn, bus.cfg, c.Id(), c)
+}
+
+func (bus *NATSCommandBus) ProcessFailed(ctx context.Context) {
+	bus.local.ProcessFailed(ctx)
+}
+
+// Close stops serving the registered commands.
+func (bus *NATSCommandBus) Close() error {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	var errs []error
+	for _, sub := range bus.subs {
+		errs = append(errs, sub.Unsubscribe())
+	}
+	bus.subs = nil
+
+	return errors.Join(errs...)
+}
+
+func (bus *NATSCommandBus) isLocal(c application.Command) bool {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+
+	_, ok := bus.types[c.Id()]
+	return ok
+}
+
+// handler decodes the incoming commands into new values of t and dispatches them to the local bus.
+func (bus *NATSCommandBus) handler(t reflect.Type) infrastructure_bus.HandlerFunc {
+	return func(ctx context.Context, msg *nats.Msg) (interface{}, error) {
+		c := reflect.New(t.Elem()).Interface().(application.Command)
+		if err := json.Unmarshal(msg.Data, c); err != nil {
+			return nil, application.NewInvalidDto("invalid command payload: " + err.Error())
+		}
+
+		if msg.Reply == "" {
+			return nil, bus.local.DispatchAsync(ctx, c)
+		}
+		return nil, bus.local.Dispatch(ctx, c)
+	}
+}
@@ -0,0 +1,110 @@
+package infrastructure_command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	infrastructure_bus "github.com/thebranchcrafter/go-kit/pkg/infrastructure/bus"
+)
+
+type createUser struct {
+	Name string `json:"name"`
+}
+
+func (c *createUser) Id() string {
+	return "create_user"
+}
+
+type commandHandlerFunc func(ctx context.Context, c application.Command) error
+
+func (f commandHandlerFunc) Handle(ctx context.Context, c application.Command) error {
+	return f(ctx, c)
+}
+
+func runNATS(t *testing.T) string {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+
+	return s.ClientURL()
+}
+
+func newNATSCommandBus(t *testing.T, url string) *NATSCommandBus {
+	t.Helper()
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("failed to connect to nats: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	bus := NewNATSCommandBus(conn, application_command.InitCommandBus(nil), infrastructure_bus.NATSConfig{})
+	t.Cleanup(func() { _ = bus.Close() })
+	return bus
+}
+
+func TestNATSCommandBusDispatchesToRemoteHandler(t *testing.T) {
+	url := runNATS(t)
+	serving := newNATSCommandBus(t, url)
+	client := newNATSCommandBus(t, url)
+
+	handled := make(chan string, 1)
+	err := serving.RegisterCommand(&createUser{}, commandHandlerFunc(func(_ context.Context, c application.Command) error {
+		name := c.(*createUser).Name
+		if name == "" {
+			return application.NewValidationError(application.FieldError{Field: "name", Tag: "required", Message: "name is required"})
+		}
+		handled <- name
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("RegisterCommand: %v", err)
+	}
+
+	if err := client.Dispatch(context.Background(), &createUser{Name: "gopher"}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if name := <-handled; name != "gopher" {
+		t.Errorf("handled %q, want gopher", name)
+	}
+
+	var validationErr *application.ValidationError
+	if err := client.Dispatch(context.Background(), &createUser{}); !errors.As(err, &validationErr) {
+		t.Errorf("Dispatch = %v, want the remote ValidationError", err)
+	}
+
+	if err := client.DispatchAsync(context.Background(), &createUser{Name: "async"}); err != nil {
+		t.Fatalf("DispatchAsync: %v", err)
+	}
+	select {
+	case name := <-handled:
+		if name != "async" {
+			t.Errorf("handled %q, want async", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("published command not handled")
+	}
+}
+
+func TestNATSCommandBusWithoutHandler(t *testing.T) {
+	client := newNATSCommandBus(t, runNATS(t))
+
+	var notRegistered application_command.CommandNotRegistered
+	if err := client.Dispatch(context.Background(), &createUser{Name: "gopher"}); !errors.As(err, &notRegistered) {
+		t.Errorf("Dispatch = %v, want CommandNotRegistered", err)
+	}
+}
@@ -0,0 +1,40 @@
+package infrastructure_command
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisDeduplicationStore implements application_command.DeduplicationStore with Redis keys, so commands
+// are deduplicated across instances.
+type RedisDeduplicationStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisDeduplicationStore(client redis.UniversalClient, prefix string) *RedisDeduplicationStore {
+	if prefix == "" {
+		prefix = "command-dedup"
+	}
+	return &RedisDeduplicationStore{client: client, prefix: prefix}
+}
+
+func (r *RedisDeduplicationStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	reserved, err := r.client.SetNX(ctx, r.prefix+":"+key, time.Now().Unix(), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve command key: %w", err)
+	}
+
+	return reserved, nil
+}
+
+func (r *RedisDeduplicationStore) Release(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.prefix+":"+key).Err(); err != nil {
+		return fmt.Errorf("failed to release command key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package infrastructure_command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisDeduplicationStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	store := NewRedisDeduplicationStore(client, "")
+
+	if reserved, err := store.Reserve(ctx, "charge_order:o1", time.Minute); err != nil || !reserved {
+		t.Fatalf("Reserve = %v, %v, want the key reserved", reserved, err)
+	}
+	if reserved, err := store.Reserve(ctx, "charge_order:o1", time.Minute); err != nil || reserved {
+		t.Fatalf("second Reserve = %v, %v, want false", reserved, err)
+	}
+
+	if err := store.Release(ctx, "charge_order:o1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if reserved, _ := store.Reserve(ctx, "charge_order:o1", time.Minute); !reserved {
+		t.Fatal("Reserve after Release = false, want the key reserved")
+	}
+
+	server.FastForward(time.Minute)
+	if reserved, _ := store.Reserve(ctx, "charge_order:o1", time.Minute); !reserved {
+		t.Error("Reserve after the ttl = false, want the key reserved again")
+	}
+}
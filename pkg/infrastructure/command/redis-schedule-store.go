@@ -0,0 +1,208 @@
+package infrastructure_command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+)
+
+const (
+	// maxAddAttempts bounds the retries of AddRecurring when the command changes while it is read
+	maxAddAttempts = 10
+	// defaultDuePage is the number of due commands read at once by Due without limit
+	defaultDuePage = 100
+)
+
+// claimScript moves a scheduled command to its next due time, or removes it without next one, if its
+// due time is unchanged, so that a single replica claims each occurrence.
+var claimScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if score == false or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+if ARGV[3] == '' then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+	redis.call('HDEL', KEYS[2], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+	redis.call('HSET', KEYS[2], ARGV[1], ARGV[4])
+end
+return 1
+`)
+
+// RedisScheduleStore implements application_command.ScheduleStore with a Redis sorted set of the
+// command IDs scored by due time, and a hash of the commands, shared by every replica.
+type RedisScheduleStore struct {
+	client   redis.UniversalClient
+	due      string
+	commands string
+}
+
+func NewRedisScheduleStore(client redis.UniversalClient, prefix string) *RedisScheduleStore {
+	if prefix == "" {
+		prefix = "command-schedule"
+	}
+	return &RedisScheduleStore{client: client, due: prefix + ":due", commands: prefix + ":commands"}
+}
+
+func (r *RedisScheduleStore) Add(ctx context.Context, c application_command.ScheduledCommand) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode scheduled command: %w", err)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.commands, c.ID, data)
+		pipe.ZAdd(ctx, r.due, redis.Z{Score: float64(c.DueAt.UnixMilli()), Member: c.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule command: %w", err)
+	}
+	return nil
+}
+
+// AddRecurring reads the command it replaces in a WATCH transaction, retried when the command is claimed
+// or replaced meanwhile.
+func (r *RedisScheduleStore) AddRecurring(ctx context.Context, c application_command.ScheduledCommand) error {
+	for attempt := 0; attempt < maxAddAttempts; attempt++ {
+		err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+			stored, err := r.get(ctx, tx, c.ID)
+			if err != nil {
+				return err
+			}
+			if stored != nil && stored.Cron == c.Cron {
+				c.DueAt = stored.DueAt
+			}
+
+			data, err := json.Marshal(c)
+			if err != nil {
+				return fmt.Errorf("failed to encode scheduled command: %w", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(ctx, r.commands, c.ID, data)
+				pipe.ZAdd(ctx, r.due, redis.Z{Score: float64(c.DueAt.UnixMilli()), Member: c.ID})
+				return nil
+			})
+			return err
+		}, r.commands)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to schedule command: %w", err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("failed to schedule command: %w", redis.TxFailedErr)
+}
+
+// Due pages through the due commands until limit commands with one of the names are found, reading the
+// commands of other names on every call.
+func (r *RedisScheduleStore) Due(ctx context.Context, now time.Time, names []string, limit int) ([]application_command.ScheduledCommand, error) {
+	page := int64(limit)
+	if page <= 0 {
+		page = defaultDuePage
+	}
+
+	var due []application_command.ScheduledCommand
+	for offset := int64(0); limit <= 0 || len(due) < limit; offset += page {
+		ids, err := r.client.ZRangeByScore(ctx, r.due, &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    strconv.FormatInt(now.UnixMilli(), 10),
+			Offset: offset,
+			Count:  page,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read due commands: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		values, err := r.client.HMGet(ctx, r.commands, ids...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read due commands: %w", err)
+		}
+
+		for _, v := range values {
+			// Claimed or removed meanwhile
+			data, ok := v.(string)
+			if !ok {
+				continue
+			}
+
+			var c application_command.ScheduledCommand
+			if err := json.Unmarshal([]byte(data), &c); err != nil {
+				return nil, fmt.Errorf("failed to decode scheduled command: %w", err)
+			}
+			if !slices.Contains(names, c.Name) {
+				continue
+			}
+			due = append(due, c)
+			if len(due) == limit {
+				break
+			}
+		}
+
+		if int64(len(ids)) < page {
+			break
+		}
+	}
+	return due, nil
+}
+
+func (r *RedisScheduleStore) Claim(ctx context.Context, c application_command.ScheduledCommand, next time.Time) (bool, error) {
+	args := []interface{}{c.ID, c.DueAt.UnixMilli(), "", ""}
+	if !next.IsZero() {
+		c.DueAt = next
+		data, err := json.Marshal(c)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode scheduled command: %w", err)
+		}
+		args[2], args[3] = next.UnixMilli(), data
+	}
+
+	claimed, err := claimScript.Run(ctx, r.client, []string{r.due, r.commands}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled command: %w", err)
+	}
+	return claimed == 1, nil
+}
+
+// get returns the stored command with id, nil if there is none.
+func (r *RedisScheduleStore) get(ctx context.Context, client redis.Cmdable, id string) (*application_command.ScheduledCommand, error) {
+	data, err := client.HGet(ctx, r.commands, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduled command: %w", err)
+	}
+
+	var c application_command.ScheduledCommand
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduled command: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *RedisScheduleStore) Remove(ctx context.Context, id string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, r.due, id)
+		pipe.HDel(ctx, r.commands, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove scheduled command: %w", err)
+	}
+	return nil
+}
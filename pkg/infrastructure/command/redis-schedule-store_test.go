@@ -0,0 +1,143 @@
+package infrastructure_command
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+)
+
+func newTestRedisScheduleStore(t *testing.T) *RedisScheduleStore {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return NewRedisScheduleStore(client, "")
+}
+
+func TestRedisScheduleStoreClaimsOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisScheduleStore(t)
+	now := time.Now()
+
+	for _, c := range []application_command.ScheduledCommand{
+		{ID: "once", Name: "send_report", Payload: []byte(`{}`), DueAt: now.Add(-time.Minute)},
+		{ID: "later", Name: "send_report", Payload: []byte(`{}`), DueAt: now.Add(time.Hour)},
+	} {
+		if err := store.Add(ctx, c); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	due, err := store.Due(ctx, now, []string{"send_report"}, 10)
+	if err != nil || len(due) != 1 || due[0].ID != "once" {
+		t.Fatalf("Due = %+v, %v, want the due command", due, err)
+	}
+
+	if claimed, err := store.Claim(ctx, due[0], time.Time{}); err != nil || !claimed {
+		t.Fatalf("Claim = %v, %v, want true", claimed, err)
+	}
+	if claimed, err := store.Claim(ctx, due[0], time.Time{}); err != nil || claimed {
+		t.Errorf("second Claim = %v, %v, want false", claimed, err)
+	}
+	if due, _ := store.Due(ctx, now.Add(2*time.Hour), []string{"send_report"}, 10); len(due) != 1 || due[0].ID != "later" {
+		t.Errorf("Due = %+v, want the claimed command removed", due)
+	}
+}
+
+func TestRedisScheduleStoreMovesRecurringCommands(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisScheduleStore(t)
+	now := time.Now().Truncate(time.Millisecond)
+
+	c := application_command.ScheduledCommand{ID: "hourly", Name: "send_report", Payload: []byte(`{}`), DueAt: now.Add(-time.Minute), Cron: "0 * * * *"}
+	if err := store.Add(ctx, c); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	next := now.Add(time.Hour)
+	if claimed, err := store.Claim(ctx, c, next); err != nil || !claimed {
+		t.Fatalf("Claim = %v, %v, want true", claimed, err)
+	}
+	if claimed, _ := store.Claim(ctx, c, next); claimed {
+		t.Error("second Claim of the same occurrence = true")
+	}
+
+	if due, _ := store.Due(ctx, now, []string{"send_report"}, 10); len(due) != 0 {
+		t.Errorf("Due = %+v, want nothing until the next occurrence", due)
+	}
+	due, err := store.Due(ctx, next, []string{"send_report"}, 10)
+	if err != nil || len(due) != 1 || !due[0].DueAt.Equal(next) || due[0].Cron != c.Cron {
+		t.Fatalf("Due = %+v, %v, want the command at its next occurrence", due, err)
+	}
+
+	if err := store.Remove(ctx, "hourly"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if due, _ := store.Due(ctx, next, []string{"send_report"}, 10); len(due) != 0 {
+		t.Errorf("Due = %+v, want nothing once removed", due)
+	}
+}
+
+func TestRedisScheduleStoreDuePagesPastOtherNames(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisScheduleStore(t)
+	now := time.Now()
+
+	for i := 0; i < 25; i++ {
+		orphan := application_command.ScheduledCommand{ID: fmt.Sprintf("orphan-%d", i), Name: "removed_command", Payload: []byte(`{}`), DueAt: now.Add(-time.Hour)}
+		if err := store.Add(ctx, orphan); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	for _, id := range []string{"first", "second"} {
+		c := application_command.ScheduledCommand{ID: id, Name: "send_report", Payload: []byte(`{}`), DueAt: now.Add(-time.Minute)}
+		if err := store.Add(ctx, c); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	due, err := store.Due(ctx, now, []string{"send_report"}, 10)
+	if err != nil || len(due) != 2 {
+		t.Fatalf("Due = %+v, %v, want the 2 send_report commands", due, err)
+	}
+	if due, _ := store.Due(ctx, now, []string{"send_report", "removed_command"}, 10); len(due) != 10 {
+		t.Errorf("Due returned %d commands, want the limit of 10", len(due))
+	}
+}
+
+func TestRedisScheduleStoreAddRecurringKeepsDueTime(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisScheduleStore(t)
+	pending := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+
+	c := application_command.ScheduledCommand{ID: "hourly", Name: "send_report", Payload: []byte(`{}`), DueAt: pending, Cron: "0 * * * *"}
+	if err := store.AddRecurring(ctx, c); err != nil {
+		t.Fatalf("AddRecurring: %v", err)
+	}
+
+	c.DueAt = pending.Add(time.Hour)
+	c.Payload = []byte(`{"report":"new"}`)
+	if err := store.AddRecurring(ctx, c); err != nil {
+		t.Fatalf("AddRecurring: %v", err)
+	}
+	due, err := store.Due(ctx, time.Now(), []string{"send_report"}, 10)
+	if err != nil || len(due) != 1 || !due[0].DueAt.Equal(pending) || string(due[0].Payload) != `{"report":"new"}` {
+		t.Fatalf("Due = %+v, %v, want the new payload at the pending due time", due, err)
+	}
+
+	c.Cron = "30 * * * *"
+	if err := store.AddRecurring(ctx, c); err != nil {
+		t.Fatalf("AddRecurring: %v", err)
+	}
+	if due, _ := store.Due(ctx, time.Now(), []string{"send_report"}, 10); len(due) != 0 {
+		t.Errorf("Due = %+v, want the command rescheduled with its new expression", due)
+	}
+}
@@ -0,0 +1,37 @@
+package infrastructure
+
+import (
+	"context"
+)
+
+type fetchResult[T any] struct {
+	value T
+	err   error
+}
+
+// fetchWithContext runs a blocking fetch that doesn't honor ctx cancellation, returning ctx.Err() as
+// soon as ctx is done. What is fetched once the caller is gone is handed to abandon, if any.
+func fetchWithContext[T any](ctx context.Context, fetch func() (T, error), abandon func(T)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	result := make(chan fetchResult[T], 1)
+	go func() {
+		value, err := fetch()
+		result <- fetchResult[T]{value: value, err: err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.value, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-result; r.err == nil && abandon != nil {
+				abandon(r.value)
+			}
+		}()
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,206 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// JetStreamBrokerConfig configures the stream and the durable pull consumer of a JetStreamBroker.
+type JetStreamBrokerConfig struct {
+	// Stream is created or updated when the broker starts, its Duplicates window bounds the message ID
+	// deduplication
+	Stream jetstream.StreamConfig
+	// Consumer is created or updated when the broker starts. Durable is required, AckPolicy is forced to
+	// explicit and MaxDeliver defaults to 5
+	Consumer jetstream.ConsumerConfig
+	// FetchWait is how long FetchMessage waits for a message, defaults to 5 seconds
+	FetchWait time.Duration
+}
+
+// JetStreamBroker implements domain.MessageBroker with a durable JetStream pull consumer, so messages
+// published while the service is down are delivered once it is back.
+type JetStreamBroker struct {
+	conn      *nats.Conn
+	consumer  jetstream.Consumer
+	fetchWait time.Duration
+	mu        sync.Mutex
+	closed    bool
+}
+
+// NewJetStreamBroker connects to NATS and provisions the stream and the durable consumer.
+func NewJetStreamBroker(ctx context.Context, url string, cfg JetStreamBrokerConfig) (*JetStreamBroker, error) {
+	if cfg.Consumer.Durable == "" {
+		return nil, errors.New("jetstream consumer must be durable")
+	}
+	cfg.Consumer.AckPolicy = jetstream.AckExplicitPolicy
+	if cfg.Consumer.MaxDeliver == 0 {
+		cfg.Consumer.MaxDeliver = 5
+	}
+	if cfg.FetchWait <= 0 {
+		cfg.FetchWait = 5 * time.Second
+	}
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, cfg.Stream); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to provision jetstream stream %s: %w", cfg.Stream.Name, err)
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream.Name, cfg.Consumer)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to provision jetstream consumer %s: %w", cfg.Consumer.Durable, err)
+	}
+
+	return &JetStreamBroker{conn: nc, consumer: consumer, fetchWait: cfg.FetchWait}, nil
+}
+
+// FetchMessage fetches a message and acknowledges it right away, use FetchMessageWithHeaders to
+// acknowledge it once processed.
+func (j *JetStreamBroker) FetchMessage(ctx context.Context) ([]byte, error) {
+	msg, err := j.FetchMessageWithHeaders(ctx)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+
+	if err := msg.Ack(ctx); err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// FetchMessageWithHeaders fetches a message, nil when none arrived within the fetch wait. The message
+// is redelivered, up to the consumer MaxDeliver, unless it is acknowledged before the consumer AckWait.
+//
+// It returns ctx.Err() as soon as ctx is done, a message received afterwards is negatively acknowledged.
+func (j *JetStreamBroker) FetchMessageWithHeaders(ctx context.Context) (*domain.Message, error) {
+	wait, err := j.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchWithContext(ctx, func() (*domain.Message, error) {
+		return j.next(wait)
+	}, func(msg *domain.Message) {
+		if msg != nil {
+			_ = msg.Nack(context.Background())
+		}
+	})
+}
+
+// FetchBatch fetches up to max messages, waiting up to the fetch wait for the batch to fill. Like
+// FetchMessageWithHeaders, the messages received once ctx is done are negatively acknowledged.
+func (j *JetStreamBroker) FetchBatch(ctx context.Context, max int) ([]*domain.Message, error) {
+	wait, err := j.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchWithContext(ctx, func() ([]*domain.Message, error) {
+		batch, err := j.consumer.Fetch(max, jetstream.FetchMaxWait(wait))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch jetstream messages: %w", err)
+		}
+
+		var messages []*domain.Message
+		for msg := range batch.Messages() {
+			messages = append(messages, toJetStreamMessage(msg))
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) && !errors.Is(err, jetstream.ErrNoMessages) {
+			return messages, fmt.Errorf("failed to fetch jetstream messages: %w", err)
+		}
+		return messages, nil
+	}, func(messages []*domain.Message) {
+		for _, msg := range messages {
+			_ = msg.Nack(context.Background())
+		}
+	})
+}
+
+// wait returns the fetch wait, shortened to the ctx deadline.
+func (j *JetStreamBroker) wait(ctx context.Context) (time.Duration, error) {
+	wait := j.fetchWait
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		wait = time.Until(deadline)
+	}
+	if wait <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+	return wait, nil
+}
+
+func (j *JetStreamBroker) next(wait time.Duration) (*domain.Message, error) {
+	msg, err := j.consumer.Next(jetstream.FetchMaxWait(wait))
+	if errors.Is(err, nats.ErrTimeout) || errors.Is(err, jetstream.ErrNoMessages) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch jetstream message: %w", err)
+	}
+
+	return toJetStreamMessage(msg), nil
+}
+
+// toJetStreamMessage converts a JetStream message, identified by its Nats-Msg-Id or stream sequence.
+func toJetStreamMessage(msg jetstream.Msg) *domain.Message {
+	headers := make(map[string]string, len(msg.Headers()))
+	for k := range msg.Headers() {
+		headers[k] = msg.Headers().Get(k)
+	}
+
+	message := &domain.Message{
+		ID:           msg.Headers().Get(jetstream.MsgIDHeader),
+		Data:         msg.Data(),
+		Headers:      headers,
+		Acknowledger: jetStreamAcknowledger{msg},
+	}
+	if metadata, err := msg.Metadata(); err == nil {
+		message.DeliveryCount = int(metadata.NumDelivered)
+		if message.ID == "" {
+			message.ID = strconv.FormatUint(metadata.Sequence.Stream, 10)
+		}
+	}
+
+	return message
+}
+
+// Close gracefully shuts down the NATS connection
+func (j *JetStreamBroker) Close() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.closed {
+		j.conn.Close()
+		j.closed = true
+		log.Println("JetStream connection closed")
+	}
+}
+
+type jetStreamAcknowledger struct {
+	msg jetstream.Msg
+}
+
+func (a jetStreamAcknowledger) Ack(ctx context.Context) error {
+	return a.msg.DoubleAck(ctx)
+}
+
+func (a jetStreamAcknowledger) Nack(_ context.Context) error {
+	return a.msg.Nak()
+}
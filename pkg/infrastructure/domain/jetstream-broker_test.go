@@ -0,0 +1,197 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// runJetStream starts an in process NATS server with JetStream enabled.
+func runJetStream(t *testing.T) *server.Server {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+
+	return s
+}
+
+func newTestJetStreamBroker(t *testing.T, s *server.Server, maxDeliver int) *JetStreamBroker {
+	t.Helper()
+
+	broker, err := NewJetStreamBroker(context.Background(), s.ClientURL(), JetStreamBrokerConfig{
+		Stream:    jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}},
+		Consumer:  jetstream.ConsumerConfig{Durable: "worker", MaxDeliver: maxDeliver},
+		FetchWait: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewJetStreamBroker: %v", err)
+	}
+	t.Cleanup(broker.Close)
+
+	return broker
+}
+
+func publish(t *testing.T, s *server.Server, id, data string) {
+	t.Helper()
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("failed to create jetstream context: %v", err)
+	}
+	if _, err := js.Publish(context.Background(), "events.test", []byte(data), jetstream.WithMsgID(id)); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+}
+
+func TestJetStreamBrokerFetchAndAck(t *testing.T) {
+	s := runJetStream(t)
+	broker := newTestJetStreamBroker(t, s, 0)
+	ctx := context.Background()
+
+	publish(t, s, "event-1", "hello")
+	// Deduplicated by the stream
+	publish(t, s, "event-1", "hello")
+
+	msg, err := broker.FetchMessageWithHeaders(ctx)
+	if err != nil || msg == nil {
+		t.Fatalf("FetchMessageWithHeaders = %v, %v, want a message", msg, err)
+	}
+	if msg.ID != "event-1" || string(msg.Data) != "hello" || msg.DeliveryCount != 1 {
+		t.Errorf("message = %+v, want event-1 delivered once", msg)
+	}
+	if msg.Headers[jetstream.MsgIDHeader] != "event-1" {
+		t.Errorf("headers = %v, want the message ID header", msg.Headers)
+	}
+	if err := msg.Ack(ctx); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+
+	if msg, err := broker.FetchMessageWithHeaders(ctx); err != nil || msg != nil {
+		t.Errorf("FetchMessageWithHeaders = %v, %v, want no message once acknowledged", msg, err)
+	}
+}
+
+func TestJetStreamBrokerFetchMessageAcknowledges(t *testing.T) {
+	s := runJetStream(t)
+	broker := newTestJetStreamBroker(t, s, 0)
+	ctx := context.Background()
+
+	publish(t, s, "event-1", "hello")
+
+	data, err := broker.FetchMessage(ctx)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("FetchMessage = %q, %v, want hello", data, err)
+	}
+	if data, err := broker.FetchMessage(ctx); err != nil || data != nil {
+		t.Errorf("FetchMessage = %q, %v, want no message", data, err)
+	}
+}
+
+func TestJetStreamBrokerNackRedelivers(t *testing.T) {
+	s := runJetStream(t)
+	broker := newTestJetStreamBroker(t, s, 0)
+	ctx := context.Background()
+
+	publish(t, s, "event-1", "hello")
+
+	msg, err := broker.FetchMessageWithHeaders(ctx)
+	if err != nil || msg == nil {
+		t.Fatalf("FetchMessageWithHeaders = %v, %v, want a message", msg, err)
+	}
+	if err := msg.Nack(ctx); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+
+	msg, err = broker.FetchMessageWithHeaders(ctx)
+	if err != nil || msg == nil {
+		t.Fatalf("FetchMessageWithHeaders = %v, %v, want the message redelivered", msg, err)
+	}
+	if msg.ID != "event-1" || msg.DeliveryCount != 2 {
+		t.Errorf("message = %+v, want event-1 delivered twice", msg)
+	}
+}
+
+func TestJetStreamBrokerStopsAtMaxDeliver(t *testing.T) {
+	s := runJetStream(t)
+	broker := newTestJetStreamBroker(t, s, 2)
+	ctx := context.Background()
+
+	publish(t, s, "event-1", "hello")
+
+	for delivery := 1; delivery <= 2; delivery++ {
+		msg, err := broker.FetchMessageWithHeaders(ctx)
+		if err != nil || msg == nil {
+			t.Fatalf("delivery %d: FetchMessageWithHeaders = %v, %v, want a message", delivery, msg, err)
+		}
+		if msg.DeliveryCount != delivery {
+			t.Errorf("DeliveryCount = %d, want %d", msg.DeliveryCount, delivery)
+		}
+		if err := msg.Nack(ctx); err != nil {
+			t.Fatalf("Nack: %v", err)
+		}
+	}
+
+	if msg, err := broker.FetchMessageWithHeaders(ctx); err != nil || msg != nil {
+		t.Errorf("FetchMessageWithHeaders = %+v, %v, want no delivery past MaxDeliver", msg, err)
+	}
+}
+
+func TestJetStreamBrokerFetchBatch(t *testing.T) {
+	s := runJetStream(t)
+	broker := newTestJetStreamBroker(t, s, 0)
+	ctx := context.Background()
+
+	for _, id := range []string{"event-1", "event-2", "event-3"} {
+		publish(t, s, id, id)
+	}
+
+	batch, err := broker.FetchBatch(ctx, 10)
+	if err != nil {
+		t.Fatalf("FetchBatch: %v", err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("FetchBatch returned %d messages, want 3", len(batch))
+	}
+	for i, msg := range batch {
+		if want := []string{"event-1", "event-2", "event-3"}[i]; msg.ID != want {
+			t.Errorf("message %d = %s, want %s", i, msg.ID, want)
+		}
+		if err := msg.Ack(ctx); err != nil {
+			t.Fatalf("Ack: %v", err)
+		}
+	}
+
+	if batch, err := broker.FetchBatch(ctx, 10); err != nil || len(batch) != 0 {
+		t.Errorf("FetchBatch = %d messages, %v, want an empty batch", len(batch), err)
+	}
+}
+
+func TestJetStreamBrokerFetchReturnsOnContextDone(t *testing.T) {
+	s := runJetStream(t)
+	broker := newTestJetStreamBroker(t, s, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := broker.FetchMessageWithHeaders(ctx); err != context.DeadlineExceeded {
+		t.Errorf("FetchMessageWithHeaders error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
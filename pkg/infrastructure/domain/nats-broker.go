@@ -6,9 +6,10 @@ import (
 	"sync"
 
 	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
 )
 
-// NatsBroker implements the domain.Broker interface
+// NatsBroker implements the domain.MessageBroker interface
 type NatsBroker struct {
 	conn   *nats.Conn
 	sub    *nats.Subscription
@@ -40,11 +41,38 @@ func NewNatsBroker(url, subject string) (*NatsBroker, error) {
 }
 
 // FetchMessage fetches a message from the NATS subject
-func (n *NatsBroker) FetchMessage(_ context.Context) ([]byte, error) {
-	msg := <-n.msgCh // Blocks until a message is received
+func (n *NatsBroker) FetchMessage(ctx context.Context) ([]byte, error) {
+	msg, err := n.next(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return msg.Data, nil
 }
 
+// FetchMessageWithHeaders fetches a message from the NATS subject along with its headers
+func (n *NatsBroker) FetchMessageWithHeaders(ctx context.Context) (*domain.Message, error) {
+	msg, err := n.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(msg.Header))
+	for k := range msg.Header {
+		headers[k] = msg.Header.Get(k)
+	}
+	return &domain.Message{Data: msg.Data, Headers: headers}, nil
+}
+
+// next blocks until a message is received or ctx is done
+func (n *NatsBroker) next(ctx context.Context) (*nats.Msg, error) {
+	select {
+	case msg := <-n.msgCh:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // Close gracefully shuts down the NATS connection
 func (n *NatsBroker) Close() {
 	n.mu.Lock()
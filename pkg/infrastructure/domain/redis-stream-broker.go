@@ -5,23 +5,50 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
 	"github.com/thebranchcrafter/go-kit/pkg/domain"
 )
 
 // RedisStreamBroker implements domain.Broker and domain.EventBus using Redis Streams.
+//
+// Messages fetched with FetchMessageWithHeaders stay in the consumer group pending entries list until
+// they are acknowledged. On startup the consumer first reads its own pending entries, negatively
+// acknowledged entries are delivered again on the next fetch, and entries left idle by any consumer of
+// the group are periodically claimed with XAUTOCLAIM.
 type RedisStreamBroker struct {
 	client     *redis.Client
 	streamName string
 	groupName  string
 	consumerID string
-	closed     bool
+	closed     atomic.Bool
+	codec      application_event.Codec
+
+	// mu guards the recovery state below, it is never held during Redis calls
+	mu            sync.Mutex
+	pendingCursor string
+	nacked        []string
+	claimMinIdle  time.Duration
+	claimInterval time.Duration
+	claimCursor   string
+	lastClaim     time.Time
+	claimed       []redis.XMessage
+
+	maxLen    int64
+	retention time.Duration
+	block     time.Duration
 }
 
+// redisDataField is the stream entry field holding the encoded event, the other fields hold its headers.
+const redisDataField = "data"
+
 // NewRedisStreamBroker initializes a Redis Stream broker.
-func NewRedisStreamBroker(redisAddr, streamName, groupName, consumerID string) (*RedisStreamBroker, error) {
+func NewRedisStreamBroker(redisAddr, streamName, groupName, consumerID string, options ...func(*RedisStreamBroker)) (*RedisStreamBroker, error) {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: redisAddr,
 	})
@@ -32,38 +59,96 @@ func NewRedisStreamBroker(redisAddr, streamName, groupName, consumerID string) (
 		return nil, fmt.Errorf("failed to create Redis stream group: %w", err)
 	}
 
-	return &RedisStreamBroker{
+	r := &RedisStreamBroker{
 		client:     rdb,
 		streamName: streamName,
 		groupName:  groupName,
 		consumerID: consumerID,
-		closed:     false,
-	}, nil
+		codec:      application_event.NewJSONCodec(),
+
+		pendingCursor: "0",
+		claimMinIdle:  time.Minute,
+		claimInterval: 30 * time.Second,
+		claimCursor:   "0-0",
+		block:         5 * time.Second,
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	return r, nil
+}
+
+// WithRedisCodec sets the codec events are encoded with, application_event.JSONCodec by default.
+func WithRedisCodec(codec application_event.Codec) func(*RedisStreamBroker) {
+	return func(r *RedisStreamBroker) {
+		r.codec = codec
+	}
+}
+
+// WithRedisClaim sets how long a pending entry must stay idle before it is claimed by this consumer,
+// and how often idle entries are looked for. Defaults to 1 minute and 30 seconds, a zero minIdle
+// disables claiming.
+func WithRedisClaim(minIdle, interval time.Duration) func(*RedisStreamBroker) {
+	return func(r *RedisStreamBroker) {
+		r.claimMinIdle = minIdle
+		r.claimInterval = interval
+	}
+}
+
+// WithRedisBlock sets how long a fetch waits for a new entry before returning no message, 5 seconds
+// by default. Non-positive values keep the default.
+func WithRedisBlock(block time.Duration) func(*RedisStreamBroker) {
+	return func(r *RedisStreamBroker) {
+		if block > 0 {
+			r.block = block
+		}
+	}
+}
+
+// WithRedisMaxLen trims the stream to about maxLen entries when publishing (XADD MAXLEN ~).
+func WithRedisMaxLen(maxLen int64) func(*RedisStreamBroker) {
+	return func(r *RedisStreamBroker) {
+		r.maxLen = maxLen
+	}
+}
+
+// WithRedisRetention trims the entries older than retention when publishing (XADD MINID ~).
+func WithRedisRetention(retention time.Duration) func(*RedisStreamBroker) {
+	return func(r *RedisStreamBroker) {
+		r.retention = retention
+	}
 }
 
 // Publish sends a domain event to the Redis stream.
 func (r *RedisStreamBroker) Publish(ctx context.Context, event domain.Event) error {
-	if r.closed {
+	if r.closed.Load() {
 		return fmt.Errorf("broker is closed")
 	}
 
-	// Serialize event to JSON
-	payload, err := json.Marshal(event.Payload())
+	// Serialize event with the codec
+	msg, err := r.codec.Encode(event)
 	if err != nil {
-		return fmt.Errorf("failed to serialize event: %w", err)
+		return err
 	}
 
-	// Publish event to Redis Stream
-	_, err = r.client.XAdd(ctx, &redis.XAddArgs{
+	values := map[string]interface{}{redisDataField: string(msg.Data)}
+	for k, v := range msg.Headers {
+		values[k] = v
+	}
+
+	// Publish event to Redis Stream, trimming it according to the retention policy
+	args := &redis.XAddArgs{
 		Stream: r.streamName,
-		Values: map[string]interface{}{
-			"aggregate_id":   event.AggregateID(),
-			"event_name":     event.EventName(),
-			"occurred_at":    event.OccurredOn().Format(time.RFC3339),
-			"correlation_id": event.CorrelationID(),
-			"payload":        string(payload), // Store payload as JSON string
-		},
-	}).Result()
+		Values: values,
+	}
+	if r.maxLen > 0 {
+		args.MaxLen = r.maxLen
+		args.Approx = true
+	} else if r.retention > 0 {
+		args.MinID = strconv.FormatInt(time.Now().Add(-r.retention).UnixMilli(), 10)
+		args.Approx = true
+	}
+	_, err = r.client.XAdd(ctx, args).Result()
 
 	if err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
@@ -73,18 +158,82 @@ func (r *RedisStreamBroker) Publish(ctx context.Context, event domain.Event) err
 	return nil
 }
 
-// FetchMessage retrieves a message from the Redis Stream.
+// FetchMessage retrieves a message from the Redis Stream and acknowledges it right away, use
+// FetchMessageWithHeaders to acknowledge it once processed.
 func (r *RedisStreamBroker) FetchMessage(ctx context.Context) ([]byte, error) {
-	if r.closed {
+	msg, err := r.FetchMessageWithHeaders(ctx)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+
+	if err := msg.Ack(ctx); err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// FetchMessageWithHeaders retrieves a message from the Redis Stream along with its headers. It returns,
+// in order, the consumer own pending entries left by a previous run, the negatively acknowledged
+// entries, the idle entries claimed from other consumers and new entries. Messages neither acknowledged
+// nor negatively acknowledged stay pending and are claimed again once idle for the claim min idle time.
+func (r *RedisStreamBroker) FetchMessageWithHeaders(ctx context.Context) (*domain.Message, error) {
+	if r.closed.Load() {
+		return nil, fmt.Errorf("broker is closed")
+	}
+
+	messages, err := r.FetchBatch(ctx, 1)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// FetchBatch retrieves up to max messages from the Redis Stream, reading up to max new entries with
+// COUNT. Like FetchMessageWithHeaders, recovered pending, negatively acknowledged and claimed entries
+// come first, they are returned without waiting for new ones.
+func (r *RedisStreamBroker) FetchBatch(ctx context.Context, max int) ([]*domain.Message, error) {
+	if r.closed.Load() {
 		return nil, fmt.Errorf("broker is closed")
 	}
 
-	streams, err := r.client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+	var messages []*domain.Message
+	for len(messages) < max {
+		msg, err := r.recoverPending(ctx)
+		if err != nil {
+			return messages, err
+		}
+		if msg == nil {
+			break
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) > 0 {
+		return messages, nil
+	}
+
+	block := r.block
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < block {
+		block = time.Until(deadline)
+	}
+	if block <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+
+	// Messages read once ctx is done stay in the consumer pending entries, they are read again on
+	// startup or claimed once idle
+	return fetchWithContext(ctx, func() ([]*domain.Message, error) {
+		return r.readNew(ctx, block, max)
+	}, nil)
+}
+
+// readNew reads up to count entries never delivered to the group, waiting up to block for one.
+func (r *RedisStreamBroker) readNew(ctx context.Context, block time.Duration, count int) ([]*domain.Message, error) {
+	streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Group:    r.groupName,
 		Consumer: r.consumerID,
 		Streams:  []string{r.streamName, ">"},
-		Count:    1,
-		Block:    5 * time.Second, // Block waiting for new messages
+		Count:    int64(count),
+		Block:    block, // Block waiting for new messages
 	}).Result()
 
 	if err == redis.Nil {
@@ -93,30 +242,249 @@ func (r *RedisStreamBroker) FetchMessage(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read from Redis stream: %w", err)
 	}
 
-	if len(streams) > 0 && len(streams[0].Messages) > 0 {
-		msg := streams[0].Messages[0]
+	if len(streams) == 0 {
+		return nil, nil
+	}
 
-		// Acknowledge message
-		_ = r.client.XAck(context.Background(), r.streamName, r.groupName, msg.ID).Err()
+	messages := make([]*domain.Message, 0, len(streams[0].Messages))
+	for _, msg := range streams[0].Messages {
+		message, err := r.toMessage(msg, 1)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// recoverPending returns the next entry of the consumer own pending entries, of the negatively
+// acknowledged ones or of the claimed ones.
+func (r *RedisStreamBroker) recoverPending(ctx context.Context) (*domain.Message, error) {
+	msg, err := r.readPending(ctx)
+	if err != nil || msg != nil {
+		return msg, err
+	}
+
+	msg, err = r.nextNacked(ctx)
+	if err != nil || msg != nil {
+		return msg, err
+	}
+
+	return r.nextClaimed(ctx)
+}
 
-		// Convert message to JSON
-		data, err := json.Marshal(msg.Values)
+// readPending returns the next entry of the consumer own pending entries list, read once on startup.
+func (r *RedisStreamBroker) readPending(ctx context.Context) (*domain.Message, error) {
+	for {
+		r.mu.Lock()
+		cursor := r.pendingCursor
+		r.mu.Unlock()
+		if cursor == "" {
+			return nil, nil
+		}
+
+		streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.groupName,
+			Consumer: r.consumerID,
+			Streams:  []string{r.streamName, cursor},
+			Count:    1,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to read pending entries: %w", err)
+		}
+
+		var msg *redis.XMessage
+		next := ""
+		if len(streams) > 0 && len(streams[0].Messages) > 0 {
+			msg = &streams[0].Messages[0]
+			next = msg.ID
+		}
+
+		r.mu.Lock()
+		if r.pendingCursor != cursor {
+			// Another fetch read this entry meanwhile
+			r.mu.Unlock()
+			continue
+		}
+		r.pendingCursor = next
+		r.mu.Unlock()
+
+		if msg == nil {
+			return nil, nil
+		}
+		// Entries deleted from the stream while pending come back without values
+		if msg.Values == nil {
+			_ = r.client.XAck(ctx, r.streamName, r.groupName, msg.ID).Err()
+			continue
+		}
+		return r.toMessage(*msg, r.deliveryCount(ctx, msg.ID))
+	}
+}
+
+// nextNacked returns the next negatively acknowledged entry, claimed again by this consumer so that
+// its delivery count is incremented.
+func (r *RedisStreamBroker) nextNacked(ctx context.Context) (*domain.Message, error) {
+	for {
+		r.mu.Lock()
+		if len(r.nacked) == 0 {
+			r.mu.Unlock()
+			return nil, nil
+		}
+		id := r.nacked[0]
+		r.nacked = r.nacked[1:]
+		r.mu.Unlock()
+
+		messages, err := r.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   r.streamName,
+			Group:    r.groupName,
+			Consumer: r.consumerID,
+			Messages: []string{id},
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to claim negatively acknowledged entry %s: %w", id, err)
+		}
+
+		// Acknowledged meanwhile
+		if len(messages) == 0 {
+			continue
+		}
+		// Entries deleted from the stream while pending come back without values
+		if messages[0].Values == nil {
+			_ = r.client.XAck(ctx, r.streamName, r.groupName, id).Err()
+			continue
+		}
+		return r.toMessage(messages[0], r.deliveryCount(ctx, id))
+	}
+}
+
+// nextClaimed returns the next entry claimed from idle pending entries, claiming a new batch every
+// claim interval.
+func (r *RedisStreamBroker) nextClaimed(ctx context.Context) (*domain.Message, error) {
+	for {
+		r.mu.Lock()
+		if len(r.claimed) > 0 {
+			msg := r.claimed[0]
+			r.claimed = r.claimed[1:]
+			r.mu.Unlock()
+
+			// Entries deleted from the stream while pending come back without values
+			if msg.Values == nil {
+				_ = r.client.XAck(ctx, r.streamName, r.groupName, msg.ID).Err()
+				continue
+			}
+			return r.toMessage(msg, r.deliveryCount(ctx, msg.ID))
+		}
+
+		if r.claimMinIdle <= 0 || time.Since(r.lastClaim) < r.claimInterval {
+			r.mu.Unlock()
+			return nil, nil
+		}
+		// Set before claiming so that a single fetch claims at a time
+		r.lastClaim = time.Now()
+		cursor := r.claimCursor
+		r.mu.Unlock()
+
+		messages, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   r.streamName,
+			Group:    r.groupName,
+			Consumer: r.consumerID,
+			MinIdle:  r.claimMinIdle,
+			Start:    cursor,
+			Count:    10,
+		}).Result()
 		if err != nil {
-			return nil, fmt.Errorf("failed to serialize message: %w", err)
+			return nil, fmt.Errorf("failed to claim idle entries: %w", err)
 		}
 
-		return data, nil
+		r.mu.Lock()
+		r.claimCursor = next
+		r.claimed = append(r.claimed, messages...)
+		r.mu.Unlock()
+
+		if len(messages) == 0 {
+			return nil, nil
+		}
 	}
+}
+
+// deliveryCount returns how many times the pending entry id has been delivered, 0 when unknown.
+func (r *RedisStreamBroker) deliveryCount(ctx context.Context, id string) int {
+	pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: r.streamName,
+		Group:  r.groupName,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 0
+	}
+	return int(pending[0].RetryCount)
+}
 
-	return nil, nil
+func (r *RedisStreamBroker) toMessage(msg redis.XMessage, deliveryCount int) (*domain.Message, error) {
+	message, err := toMessage(msg.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	message.ID = msg.ID
+	message.DeliveryCount = deliveryCount
+	message.Acknowledger = redisAcknowledger{broker: r, id: msg.ID}
+	return message, nil
+}
+
+type redisAcknowledger struct {
+	broker *RedisStreamBroker
+	id     string
+}
+
+func (a redisAcknowledger) Ack(ctx context.Context) error {
+	if err := a.broker.client.XAck(ctx, a.broker.streamName, a.broker.groupName, a.id).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge message %s: %w", a.id, err)
+	}
+	return nil
+}
+
+// Nack leaves the entry pending and queues it to be delivered again by the next fetch of this broker.
+// Should the process stop first, it is read again on startup or claimed once idle.
+func (a redisAcknowledger) Nack(_ context.Context) error {
+	a.broker.mu.Lock()
+	defer a.broker.mu.Unlock()
+
+	a.broker.nacked = append(a.broker.nacked, a.id)
+	return nil
+}
+
+// toMessage converts the fields of a stream entry into a message. Entries written before the codec was
+// introduced have no data field, they are read as the JSON object of their fields.
+func toMessage(values map[string]interface{}) (*domain.Message, error) {
+	data, ok := values[redisDataField].(string)
+	if !ok {
+		legacy, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize message: %w", err)
+		}
+		return &domain.Message{Data: legacy}, nil
+	}
+
+	headers := make(map[string]string, len(values)-1)
+	for k, v := range values {
+		if k == redisDataField {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			headers[k] = s
+		}
+	}
+	return &domain.Message{Data: []byte(data), Headers: headers}, nil
 }
 
 // Close shuts down the Redis broker.
 func (r *RedisStreamBroker) Close() {
-	if r.closed {
+	if !r.closed.CompareAndSwap(false, true) {
 		return
 	}
-	r.closed = true
 	_ = r.client.Close()
 	log.Println("Redis stream broker closed.")
 }
@@ -0,0 +1,216 @@
+package infrastructure
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+type userCreatedPayload struct {
+	Email string `json:"email"`
+}
+
+type userCreated struct {
+	domain.BaseEvent[userCreatedPayload]
+}
+
+func newUserCreated(id string) *userCreated {
+	return &userCreated{domain.NewBaseEvent("user.created", id, 1, userCreatedPayload{Email: id + "@example.com"})}
+}
+
+func newTestRedisStreamBroker(t *testing.T, addr, consumerID string, options ...func(*RedisStreamBroker)) *RedisStreamBroker {
+	t.Helper()
+
+	options = append([]func(*RedisStreamBroker){WithRedisBlock(50 * time.Millisecond)}, options...)
+	broker, err := NewRedisStreamBroker(addr, "events", "workers", consumerID, options...)
+	if err != nil {
+		t.Fatalf("NewRedisStreamBroker: %v", err)
+	}
+	t.Cleanup(broker.Close)
+
+	return broker
+}
+
+func fetch(t *testing.T, broker *RedisStreamBroker) *domain.Message {
+	t.Helper()
+
+	msg, err := broker.FetchMessageWithHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("FetchMessageWithHeaders: %v", err)
+	}
+	return msg
+}
+
+func TestRedisStreamBrokerPublishFetchAndAck(t *testing.T) {
+	server := miniredis.RunT(t)
+	broker := newTestRedisStreamBroker(t, server.Addr(), "c1")
+	ctx := context.Background()
+
+	if err := broker.Publish(ctx, newUserCreated("u1")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	msg := fetch(t, broker)
+	if msg == nil {
+		t.Fatal("no message fetched")
+	}
+	if msg.DeliveryCount != 1 || msg.Headers["content-type"] != "application/json" {
+		t.Errorf("message = %+v, want a JSON message delivered once", msg)
+	}
+	if !strings.Contains(string(msg.Data), `"event_name":"user.created"`) {
+		t.Errorf("data = %s, want the user.created event", msg.Data)
+	}
+	if err := msg.Ack(ctx); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+
+	if msg := fetch(t, broker); msg != nil {
+		t.Errorf("fetched %+v, want no message once acknowledged", msg)
+	}
+}
+
+func TestRedisStreamBrokerNackRedeliversOnNextFetch(t *testing.T) {
+	server := miniredis.RunT(t)
+	broker := newTestRedisStreamBroker(t, server.Addr(), "c1")
+	ctx := context.Background()
+
+	_ = broker.Publish(ctx, newUserCreated("u1"))
+	_ = broker.Publish(ctx, newUserCreated("u2"))
+
+	first := fetch(t, broker)
+	if err := first.Nack(ctx); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+
+	again := fetch(t, broker)
+	if again == nil || again.ID != first.ID {
+		t.Fatalf("fetched %+v, want %s again before new entries", again, first.ID)
+	}
+	if again.DeliveryCount != 2 {
+		t.Errorf("DeliveryCount = %d, want 2", again.DeliveryCount)
+	}
+	_ = again.Ack(ctx)
+
+	// A message acknowledged after being negatively acknowledged is not delivered again
+	second := fetch(t, broker)
+	_ = second.Nack(ctx)
+	_ = second.Ack(ctx)
+	if msg := fetch(t, broker); msg != nil {
+		t.Errorf("fetched %+v, want no message", msg)
+	}
+}
+
+func TestRedisStreamBrokerRecoversPendingEntriesOnRestart(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	crashed := newTestRedisStreamBroker(t, server.Addr(), "c1", WithRedisClaim(0, 0))
+	_ = crashed.Publish(ctx, newUserCreated("u1"))
+	lost := fetch(t, crashed)
+
+	restarted := newTestRedisStreamBroker(t, server.Addr(), "c1", WithRedisClaim(0, 0))
+	msg := fetch(t, restarted)
+	if msg == nil || msg.ID != lost.ID {
+		t.Fatalf("fetched %+v, want pending entry %s", msg, lost.ID)
+	}
+	if msg.DeliveryCount != 2 {
+		t.Errorf("DeliveryCount = %d, want 2", msg.DeliveryCount)
+	}
+}
+
+func TestRedisStreamBrokerClaimsIdleEntries(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	crashed := newTestRedisStreamBroker(t, server.Addr(), "c1", WithRedisClaim(0, 0))
+	_ = crashed.Publish(ctx, newUserCreated("u1"))
+	lost := fetch(t, crashed)
+
+	other := newTestRedisStreamBroker(t, server.Addr(), "c2", WithRedisClaim(20*time.Millisecond, 0))
+	time.Sleep(40 * time.Millisecond)
+
+	msg := fetch(t, other)
+	if msg == nil || msg.ID != lost.ID {
+		t.Fatalf("fetched %+v, want claimed entry %s", msg, lost.ID)
+	}
+	if err := msg.Ack(ctx); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+}
+
+func TestRedisStreamBrokerConcurrentFetches(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	crashed := newTestRedisStreamBroker(t, server.Addr(), "c1", WithRedisClaim(0, 0))
+	for i := 0; i < 20; i++ {
+		_ = crashed.Publish(ctx, newUserCreated("u"))
+		_ = fetch(t, crashed)
+	}
+
+	restarted := newTestRedisStreamBroker(t, server.Addr(), "c1", WithRedisClaim(0, 0))
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]int)
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, err := restarted.FetchMessageWithHeaders(ctx)
+				if err != nil || msg == nil {
+					return
+				}
+				mu.Lock()
+				seen[msg.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 20 {
+		t.Errorf("recovered %d pending entries, want 20", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("entry %s recovered %d times", id, n)
+		}
+	}
+}
+
+func TestRedisStreamBrokerClose(t *testing.T) {
+	server := miniredis.RunT(t)
+	broker := newTestRedisStreamBroker(t, server.Addr(), "c1")
+
+	broker.Close()
+	broker.Close()
+
+	if _, err := broker.FetchMessageWithHeaders(context.Background()); err == nil {
+		t.Error("fetch on a closed broker succeeded")
+	}
+	if err := broker.Publish(context.Background(), newUserCreated("u1")); err == nil {
+		t.Error("publish on a closed broker succeeded")
+	}
+}
+
+func TestRedisStreamBrokerIgnoresNonPositiveBlock(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	for _, block := range []time.Duration{0, -time.Second} {
+		broker := newTestRedisStreamBroker(t, server.Addr(), "c1", WithRedisBlock(block))
+		if broker.block != 50*time.Millisecond {
+			t.Errorf("WithRedisBlock(%v) set block to %v, want it ignored", block, broker.block)
+		}
+		if msg := fetch(t, broker); msg != nil {
+			t.Errorf("fetched %+v from an empty stream", msg)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package infrastructure_event
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
+	"github.com/thebranchcrafter/go-kit/pkg/domain"
+)
+
+// JetStreamEventBus is an implementation of EventBus persisting events in a JetStream stream.
+//
+// Events are published to the subject of their name, which the stream subjects must match. Events
+// exposing an EventID, such as domain.BaseEvent, are published with it as message ID so the stream
+// drops duplicates within its Duplicates window.
+type JetStreamEventBus struct {
+	conn  *nats.Conn
+	js    jetstream.JetStream
+	codec application_event.Codec
+}
+
+// NewJetStreamEventBus connects to NATS and creates or updates the stream events are published to.
+func NewJetStreamEventBus(ctx context.Context, url string, stream jetstream.StreamConfig, options ...func(*JetStreamEventBus)) (*JetStreamEventBus, error) {
+	conn, err := nats.Connect(url, nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if _, err := js.CreateOrUpdateStream(ctx, stream); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to provision jetstream stream %s: %w", stream.Name, err)
+	}
+
+	b := &JetStreamEventBus{conn: conn, js: js, codec: application_event.NewJSONCodec()}
+	for _, opt := range options {
+		opt(b)
+	}
+	return b, nil
+}
+
+// WithJetStreamCodec sets the codec events are encoded with, application_event.JSONCodec by default.
+func WithJetStreamCodec(codec application_event.Codec) func(*JetStreamEventBus) {
+	return func(b *JetStreamEventBus) {
+		b.codec = codec
+	}
+}
+
+// Publish stores the event in the stream, returning once the server acknowledged it.
+func (b *JetStreamEventBus) Publish(ctx context.Context, event domain.Event) error {
+	encoded, err := b.codec.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(event.EventName())
+	msg.Data = encoded.Data
+	for k, v := range encoded.Headers {
+		msg.Header.Set(k, v)
+	}
+
+	var opts []jetstream.PublishOpt
+	if e, ok := event.(interface{ EventID() string }); ok && e.EventID() != "" {
+		opts = append(opts, jetstream.WithMsgID(e.EventID()))
+	}
+
+	if _, err := b.js.PublishMsg(ctx, msg, opts...); err != nil {
+		return fmt.Errorf("failed to publish event to JetStream: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the NATS connection.
+func (b *JetStreamEventBus) Close() {
+	b.conn.Close()
+}
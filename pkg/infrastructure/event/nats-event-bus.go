@@ -2,8 +2,8 @@ package infrastructure_event
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
 	"github.com/thebranchcrafter/go-kit/pkg/domain"
 	"time"
 
@@ -12,11 +12,12 @@ import (
 
 // NATSEventBus is an implementation of EventBus using NATS.
 type NATSEventBus struct {
-	conn *nats.Conn
+	conn  *nats.Conn
+	codec application_event.Codec
 }
 
 // NewNATSEventBus creates a new instance of NATSEventBus with reconnection options.
-func NewNATSEventBus(url string) (*NATSEventBus, error) {
+func NewNATSEventBus(url string, options ...func(*NATSEventBus)) (*NATSEventBus, error) {
 	conn, err := nats.Connect(
 		url,
 		nats.MaxReconnects(-1),            // Unlimited reconnection attempts
@@ -35,20 +36,35 @@ func NewNATSEventBus(url string) (*NATSEventBus, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
-	return &NATSEventBus{conn: conn}, nil
+	b := &NATSEventBus{conn: conn, codec: application_event.NewJSONCodec()}
+	for _, opt := range options {
+		opt(b)
+	}
+	return b, nil
+}
+
+// WithCodec sets the codec events are encoded with, application_event.JSONCodec by default.
+func WithCodec(codec application_event.Codec) func(*NATSEventBus) {
+	return func(b *NATSEventBus) {
+		b.codec = codec
+	}
 }
 
 // Publish publishes an event to a NATS subject.
 func (b *NATSEventBus) Publish(_ context.Context, event domain.Event) error {
-	// Serialize the event to JSON
-	data, err := json.Marshal(event)
+	// Serialize the event with the codec
+	encoded, err := b.codec.Encode(event)
 	if err != nil {
-		return fmt.Errorf("failed to serialize event: %w", err)
+		return err
 	}
 
 	// Publish the event to a NATS subject based on the event name
-	subject := event.EventName()
-	if err := b.conn.Publish(subject, data); err != nil {
+	msg := nats.NewMsg(event.EventName())
+	msg.Data = encoded.Data
+	for k, v := range encoded.Headers {
+		msg.Header.Set(k, v)
+	}
+	if err := b.conn.PublishMsg(msg); err != nil {
 		return fmt.Errorf("failed to publish event to NATS: %w", err)
 	}
 
@@ -0,0 +1,95 @@
+package infrastructure_event
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
+)
+
+// claimScript sets the claim token unless the key exists, returning the status of the event. Keys hold
+// the token of their claim, or inboxProcessed once processed.
+var claimScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if value == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 0
+end
+if value == ARGV[3] then
+	return 2
+end
+return 1
+`)
+
+// markProcessedScript marks the key processed unless another claim replaced the one of the token.
+var markProcessedScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if value ~= false and value ~= ARGV[1] and value ~= ARGV[3] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[2])
+return 1
+`)
+
+// releaseScript drops the claim of the token, leaving other claims and processed entries untouched.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisInboxStore implements application_event.InboxStore with Redis keys expiring with the lease or
+// retention, so the inbox is shared by every instance.
+type RedisInboxStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisInboxStore creates a new RedisInboxStore, keys being prefixed with prefix, "inbox" by default.
+func NewRedisInboxStore(client redis.UniversalClient, prefix string) *RedisInboxStore {
+	if prefix == "" {
+		prefix = "inbox"
+	}
+	return &RedisInboxStore{client: client, prefix: prefix}
+}
+
+func (s *RedisInboxStore) Claim(ctx context.Context, scope, id string, lease time.Duration) (application_event.InboxStatus, string, error) {
+	token := application_event.NewInboxToken()
+	status, err := claimScript.Run(ctx, s.client, []string{s.key(scope, id)}, token, lease.Milliseconds(), inboxProcessed).Int()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to claim inbox event: %w", err)
+	}
+
+	switch status {
+	case 0:
+		return application_event.InboxClaimed, token, nil
+	case 2:
+		return application_event.InboxProcessed, "", nil
+	}
+	return application_event.InboxInProgress, "", nil
+}
+
+func (s *RedisInboxStore) MarkProcessed(ctx context.Context, scope, id, token string, ttl time.Duration) error {
+	marked, err := markProcessedScript.Run(ctx, s.client, []string{s.key(scope, id)}, token, ttl.Milliseconds(), inboxProcessed).Int()
+	if err != nil {
+		return fmt.Errorf("failed to mark inbox event processed: %w", err)
+	}
+	if marked == 0 {
+		return application_event.ErrInboxClaimLost
+	}
+	return nil
+}
+
+func (s *RedisInboxStore) Release(ctx context.Context, scope, id, token string) error {
+	if err := releaseScript.Run(ctx, s.client, []string{s.key(scope, id)}, token).Err(); err != nil {
+		return fmt.Errorf("failed to release inbox claim: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisInboxStore) key(scope, id string) string {
+	return s.prefix + ":" + scope + ":" + id
+}
@@ -0,0 +1,92 @@
+package infrastructure_event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
+)
+
+func newTestRedisInboxStore(t *testing.T) (*RedisInboxStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return NewRedisInboxStore(client, ""), server
+}
+
+func TestRedisInboxStoreClaims(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisInboxStore(t)
+
+	status, token, err := store.Claim(ctx, "s", "1", time.Minute)
+	if err != nil || status != application_event.InboxClaimed || token == "" {
+		t.Fatalf("Claim = %v, %q, %v, want claimed with a token", status, token, err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxInProgress {
+		t.Errorf("second Claim = %v, want in progress", status)
+	}
+
+	if err := store.Release(ctx, "s", "1", "other"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxInProgress {
+		t.Errorf("Claim after a foreign release = %v, want in progress", status)
+	}
+
+	if err := store.MarkProcessed(ctx, "s", "1", token, time.Hour); err != nil {
+		t.Fatalf("MarkProcessed: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxProcessed {
+		t.Errorf("Claim once processed = %v, want processed", status)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxClaimed {
+		t.Errorf("Claim once the retention elapsed = %v, want claimed", status)
+	}
+}
+
+func TestRedisInboxStoreExpiredClaim(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisInboxStore(t)
+
+	_, expired, _ := store.Claim(ctx, "s", "1", time.Minute)
+	server.FastForward(2 * time.Minute)
+	status, token, _ := store.Claim(ctx, "s", "1", time.Minute)
+	if status != application_event.InboxClaimed {
+		t.Fatalf("Claim once the lease elapsed = %v, want claimed", status)
+	}
+
+	// The consumer whose lease expired neither releases nor overwrites the new claim
+	if err := store.Release(ctx, "s", "1", expired); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := store.MarkProcessed(ctx, "s", "1", expired, time.Hour); !errors.Is(err, application_event.ErrInboxClaimLost) {
+		t.Fatalf("MarkProcessed = %v, want %v", err, application_event.ErrInboxClaimLost)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxInProgress {
+		t.Errorf("Claim = %v, want in progress", status)
+	}
+
+	if err := store.Release(ctx, "s", "1", token); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxClaimed {
+		t.Errorf("Claim once released = %v, want claimed", status)
+	}
+
+	// A lease expiring without any new claim still lets the event be recorded
+	_, token, _ = store.Claim(ctx, "s", "2", time.Minute)
+	server.FastForward(2 * time.Minute)
+	if err := store.MarkProcessed(ctx, "s", "2", token, time.Hour); err != nil {
+		t.Fatalf("MarkProcessed = %v, want nil once the lease expired unclaimed", err)
+	}
+}
@@ -0,0 +1,138 @@
+package infrastructure_event
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
+	infrastructure_sql "github.com/thebranchcrafter/go-kit/pkg/infrastructure/sql"
+)
+
+const (
+	inboxClaimed   = "claimed"
+	inboxProcessed = "processed"
+)
+
+// SQLInboxStore implements application_event.InboxStore with a SQL table, which must exist:
+//
+//	CREATE TABLE inbox (
+//		scope      VARCHAR(255) NOT NULL,
+//		id         VARCHAR(255) NOT NULL,
+//		token      VARCHAR(32)  NOT NULL,
+//		status     VARCHAR(16)  NOT NULL,
+//		expires_at BIGINT       NOT NULL,
+//		PRIMARY KEY (scope, id)
+//	);
+//
+// expires_at holds Unix milliseconds. Expired rows are replaced when claimed again, deleting the ones
+// left behind is up to the application.
+type SQLInboxStore struct {
+	db    *sql.DB
+	table infrastructure_sql.Table
+	now   func() time.Time
+}
+
+// NewSQLInboxStore creates a new SQLInboxStore on table, which is used verbatim in the queries and must
+// be an identifier.
+func NewSQLInboxStore(db *sql.DB, table string, options ...func(*SQLInboxStore)) *SQLInboxStore {
+	s := &SQLInboxStore{db: db, table: infrastructure_sql.NewTable(table), now: time.Now}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// WithDollarPlaceholders uses $1 placeholders, as PostgreSQL drivers expect, instead of ?.
+func WithDollarPlaceholders() func(*SQLInboxStore) {
+	return func(s *SQLInboxStore) {
+		s.table = s.table.WithDollarPlaceholders()
+	}
+}
+
+func (s *SQLInboxStore) Claim(ctx context.Context, scope, id string, lease time.Duration) (application_event.InboxStatus, string, error) {
+	now := s.now()
+
+	// Expired claims and processed entries are replaced
+	if _, err := s.exec(ctx, "DELETE FROM {table} WHERE scope = ? AND id = ? AND expires_at <= ?", scope, id, now.UnixMilli()); err != nil {
+		return 0, "", err
+	}
+
+	status, exists, err := s.status(ctx, scope, id)
+	if err != nil || exists {
+		return status, "", err
+	}
+
+	token := application_event.NewInboxToken()
+	_, err = s.exec(ctx, "INSERT INTO {table} (scope, id, token, status, expires_at) VALUES (?, ?, ?, ?, ?)",
+		scope, id, token, inboxClaimed, now.Add(lease).UnixMilli())
+	if err != nil {
+		// Another consumer claimed it meanwhile, violating the primary key
+		if status, exists, statusErr := s.status(ctx, scope, id); statusErr == nil && exists {
+			return status, "", nil
+		}
+		return 0, "", err
+	}
+
+	return application_event.InboxClaimed, token, nil
+}
+
+func (s *SQLInboxStore) MarkProcessed(ctx context.Context, scope, id, token string, ttl time.Duration) error {
+	now := s.now()
+	expiresAt := now.Add(ttl).UnixMilli()
+
+	res, err := s.exec(ctx, "UPDATE {table} SET status = ?, expires_at = ? WHERE scope = ? AND id = ? AND (token = ? OR status = ?)",
+		inboxProcessed, expiresAt, scope, id, token, inboxProcessed)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	// The claim expired, it is recorded unless another consumer claimed the event meanwhile
+	if _, err := s.exec(ctx, "DELETE FROM {table} WHERE scope = ? AND id = ? AND expires_at <= ?", scope, id, now.UnixMilli()); err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, "INSERT INTO {table} (scope, id, token, status, expires_at) VALUES (?, ?, ?, ?, ?)",
+		scope, id, token, inboxProcessed, expiresAt)
+	if err != nil {
+		if status, exists, statusErr := s.status(ctx, scope, id); statusErr == nil && exists && status == application_event.InboxInProgress {
+			return application_event.ErrInboxClaimLost
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *SQLInboxStore) Release(ctx context.Context, scope, id, token string) error {
+	_, err := s.exec(ctx, "DELETE FROM {table} WHERE scope = ? AND id = ? AND token = ? AND status = ?", scope, id, token, inboxClaimed)
+	return err
+}
+
+// status returns the status of id, if it exists.
+func (s *SQLInboxStore) status(ctx context.Context, scope, id string) (application_event.InboxStatus, bool, error) {
+	var status string
+	err := s.db.QueryRowContext(ctx, s.table.Query("SELECT status FROM {table} WHERE scope = ? AND id = ?"), scope, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("inbox store: %w", err)
+	}
+
+	if status == inboxProcessed {
+		return application_event.InboxProcessed, true, nil
+	}
+	return application_event.InboxInProgress, true, nil
+}
+
+func (s *SQLInboxStore) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := s.db.ExecContext(ctx, s.table.Query(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("inbox store: %w", err)
+	}
+	return res, nil
+}
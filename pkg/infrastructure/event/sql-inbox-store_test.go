@@ -0,0 +1,134 @@
+package infrastructure_event
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	application_event "github.com/thebranchcrafter/go-kit/pkg/application/event"
+	_ "modernc.org/sqlite"
+)
+
+// newTestSQLInboxStore creates the inbox table in an in-memory SQLite database, the store reading the
+// time from clock.
+func newTestSQLInboxStore(t *testing.T, clock *time.Time, options ...func(*SQLInboxStore)) *SQLInboxStore {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	// Every connection opens its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE inbox (
+		scope      VARCHAR(255) NOT NULL,
+		id         VARCHAR(255) NOT NULL,
+		token      VARCHAR(32)  NOT NULL,
+		status     VARCHAR(16)  NOT NULL,
+		expires_at BIGINT       NOT NULL,
+		PRIMARY KEY (scope, id)
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create inbox table: %v", err)
+	}
+
+	store := NewSQLInboxStore(db, "inbox", options...)
+	store.now = func() time.Time { return *clock }
+	return store
+}
+
+func TestSQLInboxStoreClaims(t *testing.T) {
+	for name, options := range map[string][]func(*SQLInboxStore){
+		"question mark placeholders": nil,
+		"dollar placeholders":        {WithDollarPlaceholders()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := time.Now()
+			store := newTestSQLInboxStore(t, &clock, options...)
+
+			status, token, err := store.Claim(ctx, "s", "1", time.Minute)
+			if err != nil || status != application_event.InboxClaimed || token == "" {
+				t.Fatalf("Claim = %v, %q, %v, want claimed with a token", status, token, err)
+			}
+			if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxInProgress {
+				t.Errorf("second Claim = %v, want in progress", status)
+			}
+			if status, _, _ := store.Claim(ctx, "other", "1", time.Minute); status != application_event.InboxClaimed {
+				t.Errorf("Claim in another scope = %v, want claimed", status)
+			}
+
+			if err := store.Release(ctx, "s", "1", "other"); err != nil {
+				t.Fatalf("Release: %v", err)
+			}
+			if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxInProgress {
+				t.Errorf("Claim after a foreign release = %v, want in progress", status)
+			}
+
+			if err := store.MarkProcessed(ctx, "s", "1", token, time.Hour); err != nil {
+				t.Fatalf("MarkProcessed: %v", err)
+			}
+			if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxProcessed {
+				t.Errorf("Claim once processed = %v, want processed", status)
+			}
+			if err := store.Release(ctx, "s", "1", token); err != nil {
+				t.Fatalf("Release: %v", err)
+			}
+			if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxProcessed {
+				t.Errorf("Claim once processed and released = %v, want processed", status)
+			}
+
+			clock = clock.Add(2 * time.Hour)
+			if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxClaimed {
+				t.Errorf("Claim once the retention elapsed = %v, want claimed", status)
+			}
+		})
+	}
+}
+
+func TestSQLInboxStoreExpiredClaim(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	store := newTestSQLInboxStore(t, &clock)
+
+	_, expired, _ := store.Claim(ctx, "s", "1", time.Minute)
+	clock = clock.Add(2 * time.Minute)
+	status, token, _ := store.Claim(ctx, "s", "1", time.Minute)
+	if status != application_event.InboxClaimed || token == expired {
+		t.Fatalf("Claim once the lease elapsed = %v, want claimed with a new token", status)
+	}
+
+	// The consumer whose lease expired neither releases nor overwrites the new claim
+	if err := store.Release(ctx, "s", "1", expired); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := store.MarkProcessed(ctx, "s", "1", expired, time.Hour); !errors.Is(err, application_event.ErrInboxClaimLost) {
+		t.Fatalf("MarkProcessed = %v, want %v", err, application_event.ErrInboxClaimLost)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxInProgress {
+		t.Errorf("Claim = %v, want in progress", status)
+	}
+
+	if err := store.Release(ctx, "s", "1", token); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "1", time.Minute); status != application_event.InboxClaimed {
+		t.Errorf("Claim once released = %v, want claimed", status)
+	}
+
+	// A lease expiring without any new claim still lets the event be recorded
+	_, token, _ = store.Claim(ctx, "s", "2", time.Minute)
+	clock = clock.Add(2 * time.Minute)
+	if err := store.MarkProcessed(ctx, "s", "2", token, time.Hour); err != nil {
+		t.Fatalf("MarkProcessed = %v, want nil once the lease expired unclaimed", err)
+	}
+	if status, _, _ := store.Claim(ctx, "s", "2", time.Minute); status != application_event.InboxProcessed {
+		t.Errorf("Claim = %v, want processed", status)
+	}
+}
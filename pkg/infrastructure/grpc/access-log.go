@@ -0,0 +1,61 @@
+package grpc_server
+
+import (
+	"context"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryAccessLog logs every call once it has been handled, as an error for server side failures,
+// a warning for client errors and info otherwise.
+func UnaryAccessLog(l logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, l, "grpc request", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamAccessLog is the streaming counterpart of UnaryAccessLog, logging streams once they are closed.
+func StreamAccessLog(l logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), l, "grpc stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, l logger.Logger, msg, method string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := map[string]interface{}{
+		"method":      method,
+		"code":        code.String(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["remote_addr"] = p.Addr.String()
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		fields["request_id"] = id
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+
+	switch code {
+	case codes.OK:
+		l.Info(ctx, msg, fields)
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		l.Error(ctx, msg, fields)
+	default:
+		l.Warn(ctx, msg, fields)
+	}
+}
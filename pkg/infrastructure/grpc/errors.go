@@ -0,0 +1,76 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryErrors converts the errors returned by the handler into gRPC statuses using StatusFromError.
+func UnaryErrors() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, StatusFromError(err)
+	}
+}
+
+// StreamErrors is the streaming counterpart of UnaryErrors.
+func StreamErrors() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return StatusFromError(handler(srv, ss))
+	}
+}
+
+// StatusFromError maps the errors produced by the buses to a gRPC status error, mirroring
+// http_request.StatusFromError. Errors already carrying a status are returned untouched.
+func StatusFromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return status.Error(codeFromError(err), err.Error())
+}
+
+func codeFromError(err error) codes.Code {
+	var validationError *application.ValidationError
+	if errors.As(err, &validationError) {
+		return codes.InvalidArgument
+	}
+
+	var bindingError *application.BindingError
+	if errors.As(err, &bindingError) {
+		return codes.InvalidArgument
+	}
+
+	var unauthenticated *application_auth.Unauthenticated
+	if errors.As(err, &unauthenticated) {
+		return codes.Unauthenticated
+	}
+
+	var forbidden *application_auth.Forbidden
+	if errors.As(err, &forbidden) {
+		return codes.PermissionDenied
+	}
+
+	var invalidDto application.InvalidDto
+	if errors.As(err, &invalidDto) {
+		return codes.InvalidArgument
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+
+	return codes.Internal
+}
@@ -0,0 +1,45 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusFromError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"validation", application.NewValidationError(application.FieldError{Field: "name", Tag: "required"}), codes.InvalidArgument},
+		{"binding", application.NewBindingError("invalid JSON body", errors.New("unexpected EOF")), codes.InvalidArgument},
+		{"unauthenticated", application_auth.NewUnauthenticated("missing token"), codes.Unauthenticated},
+		{"forbidden", fmt.Errorf("dispatching: %w", application_auth.NewForbidden("forbidden")), codes.PermissionDenied},
+		{"invalid dto", application.NewInvalidDto("invalid payload"), codes.InvalidArgument},
+		{"deadline exceeded", fmt.Errorf("querying: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{"canceled", context.Canceled, codes.Canceled},
+		{"status", status.Error(codes.AlreadyExists, "user exists"), codes.AlreadyExists},
+		{"other", errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The errors carrying a status are kept as they are
+			want := status.Convert(tt.err).Message()
+			err := StatusFromError(tt.err)
+			if s, ok := status.FromError(err); !ok || s.Code() != tt.code || s.Message() != want {
+				t.Errorf("StatusFromError = %v, want %s: %s", err, tt.code, want)
+			}
+		})
+	}
+
+	if err := StatusFromError(nil); err != nil {
+		t.Errorf("StatusFromError(nil) = %v", err)
+	}
+}
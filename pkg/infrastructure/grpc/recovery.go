@@ -0,0 +1,58 @@
+package grpc_server
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrInternal is returned to the client when a handler panics.
+var ErrInternal = status.Error(codes.Internal, "internal server error")
+
+// UnaryRecovery recovers from panics in the handler, logging them and answering with ErrInternal.
+func UnaryRecovery(l logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logPanic(ctx, l, info.FullMethod, rec)
+				resp, err = nil, ErrInternal
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery is the streaming counterpart of UnaryRecovery.
+func StreamRecovery(l logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logPanic(ss.Context(), l, info.FullMethod, rec)
+				err = ErrInternal
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+func logPanic(ctx context.Context, l logger.Logger, method string, rec interface{}) {
+	if l == nil {
+		return
+	}
+
+	// The panic is only logged, it may hold details that must not reach the client
+	l.Error(ctx, "recovered from panic", map[string]interface{}{
+		"error":      fmt.Sprint(rec),
+		"method":     method,
+		"stack":      string(debug.Stack()),
+		"request_id": requestid.FromContext(ctx),
+	})
+}
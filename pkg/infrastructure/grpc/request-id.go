@@ -0,0 +1,57 @@
+package grpc_server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDMetadataKey is the metadata key the request ID is read from and sent back in, the gRPC
+// counterpart of requestid.Header.
+var RequestIDMetadataKey = strings.ToLower(requestid.Header)
+
+// UnaryRequestID reuses the request ID sent in the incoming metadata, or generates one, and stores it
+// in the context with requestid.NewContext and in the response headers.
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestid.FromContext(ctx)))
+		return handler(ctx, req)
+	}
+}
+
+// StreamRequestID is the streaming counterpart of UnaryRequestID.
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, requestid.FromContext(ctx)))
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = requestid.New()
+	}
+
+	return requestid.NewContext(ctx, id)
+}
+
+// contextStream overrides the context of a grpc.ServerStream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
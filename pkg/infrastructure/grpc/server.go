@@ -0,0 +1,25 @@
+package grpc_server
+
+import (
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"google.golang.org/grpc"
+)
+
+// NewServer creates a grpc.Server with the default interceptor chain: request ID, access log,
+// recovery and error mapping, the same stack the HTTP middleware provide. Interceptors passed through
+// opts with grpc.ChainUnaryInterceptor or grpc.ChainStreamInterceptor run after the default ones.
+func NewServer(l logger.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{UnaryRequestID()}
+	stream := []grpc.StreamServerInterceptor{StreamRequestID()}
+	if l != nil {
+		unary = append(unary, UnaryAccessLog(l))
+		stream = append(stream, StreamAccessLog(l))
+	}
+	unary = append(unary, UnaryRecovery(l), UnaryErrors())
+	stream = append(stream, StreamRecovery(l), StreamErrors())
+
+	return grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, opts...)...)
+}
@@ -0,0 +1,280 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+// testLogger records the logged entries.
+type testLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *testLogger) log(level, msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *testLogger) Debug(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("debug", msg, fields)
+}
+
+func (l *testLogger) Info(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("info", msg, fields)
+}
+
+func (l *testLogger) Warn(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("warn", msg, fields)
+}
+
+func (l *testLogger) Error(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("error", msg, fields)
+}
+
+func (l *testLogger) WithField(context.Context, string, interface{}) logger.Logger {
+	return l
+}
+
+// find returns the entries logged with msg.
+func (l *testLogger) find(msg string) []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []logEntry
+	for _, e := range l.entries {
+		if e.msg == msg {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// echoServer answers the Echo calls and Stream streams of the test.Echo service with handle.
+type echoServer struct {
+	handle func(ctx context.Context, msg string) (string, error)
+}
+
+var echoService = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := &wrapperspb.StringValue{}
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				out, err := srv.(*echoServer).handle(ctx, req.(*wrapperspb.StringValue).GetValue())
+				if err != nil {
+					return nil, err
+				}
+				return wrapperspb.String(out), nil
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Echo"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in := &wrapperspb.StringValue{}
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			out, err := srv.(*echoServer).handle(stream.Context(), in.GetValue())
+			if err != nil {
+				return err
+			}
+			return stream.SendMsg(wrapperspb.String(out))
+		},
+	}},
+}
+
+// startServer serves handle with NewServer on an in-memory listener and returns a client connection.
+func startServer(t *testing.T, l logger.Logger, handle func(ctx context.Context, msg string) (string, error), opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+
+	server := NewServer(l, opts...)
+	server.RegisterService(&echoService, &echoServer{handle: handle})
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func echo(ctx context.Context, conn *grpc.ClientConn, msg string, opts ...grpc.CallOption) (string, error) {
+	out := &wrapperspb.StringValue{}
+	err := conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String(msg), out, opts...)
+	return out.GetValue(), err
+}
+
+// stream opens a Stream stream sending msg and returns its first message.
+func stream(ctx context.Context, conn *grpc.ClientConn, msg string) (string, metadata.MD, error) {
+	s, err := conn.NewStream(ctx, &echoService.Streams[0], "/test.Echo/Stream")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.SendMsg(wrapperspb.String(msg)); err != nil {
+		return "", nil, err
+	}
+	if err := s.CloseSend(); err != nil {
+		return "", nil, err
+	}
+	out := &wrapperspb.StringValue{}
+	err = s.RecvMsg(out)
+	header, _ := s.Header()
+	return out.GetValue(), header, err
+}
+
+func replyRequestID(ctx context.Context, _ string) (string, error) {
+	return requestid.FromContext(ctx), nil
+}
+
+func TestServerRequestID(t *testing.T) {
+	conn := startServer(t, nil, replyRequestID)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "req-1")
+	id, err := echo(ctx, conn, "", grpc.Header(&header))
+	if err != nil || id != "req-1" {
+		t.Fatalf("Echo = %q, %v, want the request ID sent", id, err)
+	}
+	if got := header.Get(RequestIDMetadataKey); len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("header %s = %v, want [req-1]", RequestIDMetadataKey, got)
+	}
+
+	id, err = echo(context.Background(), conn, "", grpc.Header(&header))
+	if err != nil || id == "" {
+		t.Fatalf("Echo = %q, %v, want a generated request ID", id, err)
+	}
+	if got := header.Get(RequestIDMetadataKey); len(got) != 1 || got[0] != id {
+		t.Errorf("header %s = %v, want [%s]", RequestIDMetadataKey, got, id)
+	}
+
+	id, header, err = stream(metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "req-2"), conn, "")
+	if err != nil || id != "req-2" || len(header.Get(RequestIDMetadataKey)) != 1 || header.Get(RequestIDMetadataKey)[0] != "req-2" {
+		t.Errorf("Stream = %q with header %v, %v, want the request ID sent", id, header, err)
+	}
+}
+
+func TestServerRecoversFromPanics(t *testing.T) {
+	l := &testLogger{}
+	conn := startServer(t, l, func(context.Context, string) (string, error) {
+		panic("secret detail")
+	})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "req-1")
+
+	if _, err := echo(ctx, conn, ""); status.Code(err) != codes.Internal || status.Convert(err).Message() != "internal server error" {
+		t.Errorf("Echo = %v, want %v", err, ErrInternal)
+	}
+	if _, _, err := stream(ctx, conn, ""); status.Code(err) != codes.Internal || status.Convert(err).Message() != "internal server error" {
+		t.Errorf("Stream = %v, want %v", err, ErrInternal)
+	}
+
+	panics := l.find("recovered from panic")
+	if len(panics) != 2 || panics[0].fields["error"] != "secret detail" || panics[0].fields["request_id"] != "req-1" ||
+		panics[0].fields["method"] != "/test.Echo/Echo" || panics[1].fields["method"] != "/test.Echo/Stream" {
+		t.Errorf("logged panics %+v, want both calls with their request ID", panics)
+	}
+}
+
+func TestServerMapsErrors(t *testing.T) {
+	errs := map[string]error{
+		"validation":      application.NewValidationError(application.FieldError{Field: "name", Tag: "required"}),
+		"forbidden":       application_auth.NewForbidden("forbidden", "users:write"),
+		"unauthenticated": application_auth.NewUnauthenticated("missing token"),
+		"status":          status.Error(codes.NotFound, "user not found"),
+		"other":           errors.New("boom"),
+	}
+	l := &testLogger{}
+	conn := startServer(t, l, func(_ context.Context, msg string) (string, error) {
+		return "", errs[msg]
+	})
+
+	tests := []struct {
+		name  string
+		code  codes.Code
+		level string
+	}{
+		{"validation", codes.InvalidArgument, "warn"},
+		{"forbidden", codes.PermissionDenied, "warn"},
+		{"unauthenticated", codes.Unauthenticated, "warn"},
+		{"status", codes.NotFound, "warn"},
+		{"other", codes.Internal, "error"},
+		{"", codes.OK, "info"},
+	}
+	for _, tt := range tests {
+		_, err := echo(context.Background(), conn, tt.name)
+		if status.Code(err) != tt.code {
+			t.Errorf("Echo(%s) = %v, want %s", tt.name, err, tt.code)
+		}
+		_, _, err = stream(context.Background(), conn, tt.name)
+		if status.Code(err) != tt.code {
+			t.Errorf("Stream(%s) = %v, want %s", tt.name, err, tt.code)
+		}
+	}
+
+	// The access log sees the mapped status
+	calls := l.find("grpc request")
+	if len(calls) != len(tests) {
+		t.Fatalf("logged %d calls, want %d", len(calls), len(tests))
+	}
+	for i, tt := range tests {
+		if calls[i].level != tt.level || calls[i].fields["code"] != tt.code.String() || calls[i].fields["request_id"] == "" {
+			t.Errorf("call %s logged %+v, want %s at %s with a request ID", tt.name, calls[i], tt.code, tt.level)
+		}
+	}
+	if streams := l.find("grpc stream"); len(streams) != len(tests) {
+		t.Errorf("logged %d streams, want %d", len(streams), len(tests))
+	}
+}
+
+func TestServerRunsGivenInterceptorsLast(t *testing.T) {
+	var seen string
+	conn := startServer(t, nil, replyRequestID, grpc.ChainUnaryInterceptor(
+		func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			seen = requestid.FromContext(ctx)
+			return nil, errors.New("rejected")
+		}))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "req-1")
+	if _, err := echo(ctx, conn, ""); status.Code(err) != codes.Internal || seen != "req-1" {
+		t.Errorf("Echo = %v with request ID %q, want the error mapped and the request ID set", err, seen)
+	}
+}
@@ -0,0 +1,56 @@
+package http_auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+// APIKeyHeader is the default header API keys are read from.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates requests with static API keys.
+type APIKeyAuthenticator struct {
+	header string
+	keys   map[[sha256.Size]byte]application_auth.Principal
+}
+
+// NewAPIKeyAuthenticator maps each API key to the principal it authenticates. An empty header defaults
+// to APIKeyHeader.
+func NewAPIKeyAuthenticator(header string, keys map[string]application_auth.Principal) *APIKeyAuthenticator {
+	if header == "" {
+		header = APIKeyHeader
+	}
+
+	// Keys are indexed by their hash so the lookup doesn't leak the key through timing
+	hashed := make(map[[sha256.Size]byte]application_auth.Principal, len(keys))
+	for key, principal := range keys {
+		hashed[sha256.Sum256([]byte(key))] = principal
+	}
+
+	return &APIKeyAuthenticator{header: header, keys: hashed}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*application_auth.Principal, error) {
+	key := r.Header.Get(a.header)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	for hash, principal := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			p := principal
+			p.Method = "api_key"
+			return &p, nil
+		}
+	}
+
+	return nil, application_auth.NewUnauthenticated("invalid API key")
+}
+
+func (a *APIKeyAuthenticator) Challenge() string {
+	return ""
+}
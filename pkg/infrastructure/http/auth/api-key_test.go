@@ -0,0 +1,77 @@
+package http_auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	authenticator := NewAPIKeyAuthenticator("", map[string]application_auth.Principal{
+		"key-1": {ID: "service-1", Roles: []string{"service"}},
+		"key-2": {ID: "service-2"},
+	})
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+		err  error
+	}{
+		{"first key", "key-1", "service-1", nil},
+		{"second key", "key-2", "service-2", nil},
+		{"missing", "", "", ErrNoCredentials},
+		{"unknown", "key-3", "", application_auth.NewUnauthenticated("invalid API key")},
+		{"prefix of a key", "key-", "", application_auth.NewUnauthenticated("invalid API key")},
+		{"longer than a key", "key-10", "", application_auth.NewUnauthenticated("invalid API key")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.key != "" {
+				r.Header.Set(APIKeyHeader, tt.key)
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if tt.err != nil {
+				var unauthenticated *application_auth.Unauthenticated
+				if !errors.As(err, &unauthenticated) || err.Error() != tt.err.Error() {
+					t.Errorf("Authenticate = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil || principal.ID != tt.want || principal.Method != "api_key" {
+				t.Errorf("Authenticate = %+v, %v, want %s", principal, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthenticatorCustomHeader(t *testing.T) {
+	authenticator := NewAPIKeyAuthenticator("X-Service-Key", map[string]application_auth.Principal{"key-1": {ID: "service-1"}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "key-1")
+	if _, err := authenticator.Authenticate(r); err != ErrNoCredentials {
+		t.Errorf("Authenticate with the default header = %v, want ErrNoCredentials", err)
+	}
+
+	r.Header.Set("X-Service-Key", "key-1")
+	if principal, err := authenticator.Authenticate(r); err != nil || principal.ID != "service-1" {
+		t.Errorf("Authenticate = %+v, %v", principal, err)
+	}
+}
+
+func TestAPIKeyAuthenticatorDoesNotSharePrincipals(t *testing.T) {
+	authenticator := NewAPIKeyAuthenticator("", map[string]application_auth.Principal{"key-1": {ID: "service-1"}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "key-1")
+	first, _ := authenticator.Authenticate(r)
+	first.ID = "changed"
+	if second, _ := authenticator.Authenticate(r); second.ID != "service-1" {
+		t.Errorf("ID = %q after a caller changed a previous principal, want service-1", second.ID)
+	}
+}
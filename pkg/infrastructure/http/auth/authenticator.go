@@ -0,0 +1,75 @@
+package http_auth
+
+import (
+	"errors"
+	"net/http"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no credentials it understands,
+// so the next authenticator can be tried.
+var ErrNoCredentials = application_auth.NewUnauthenticated("missing credentials")
+
+// Authenticator extracts and verifies the credentials of a request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*application_auth.Principal, error)
+	// Challenge is the WWW-Authenticate header value sent when authentication fails.
+	Challenge() string
+}
+
+// Middleware authenticates requests with the first authenticator that finds credentials in them, placing
+// the principal in the request context. Unauthenticated requests are answered with a 401.
+func Middleware(rw http_response.ResponseWriter, authenticators ...Authenticator) router.Middleware {
+	return middleware(rw, true, authenticators)
+}
+
+// Optional behaves like Middleware but lets requests without credentials through without a principal.
+// Requests with invalid credentials are still rejected.
+func Optional(rw http_response.ResponseWriter, authenticators ...Authenticator) router.Middleware {
+	return middleware(rw, false, authenticators)
+}
+
+func middleware(rw http_response.ResponseWriter, required bool, authenticators []Authenticator) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range authenticators {
+				principal, err := a.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					unauthorized(w, rw, a, err)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(application_auth.ContextWithPrincipal(r.Context(), principal)))
+				return
+			}
+
+			if required {
+				var challenger Authenticator
+				if len(authenticators) > 0 {
+					challenger = authenticators[0]
+				}
+				unauthorized(w, rw, challenger, ErrNoCredentials)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, rw http_response.ResponseWriter, a Authenticator, err error) {
+	if a != nil && a.Challenge() != "" {
+		w.Header().Set("WWW-Authenticate", a.Challenge())
+	}
+
+	var unauthenticated *application_auth.Unauthenticated
+	if !errors.As(err, &unauthenticated) {
+		unauthenticated = application_auth.NewUnauthenticated(err.Error())
+	}
+	rw.WriteErrorResponse(w, unauthenticated, http.StatusUnauthorized, unauthenticated)
+}
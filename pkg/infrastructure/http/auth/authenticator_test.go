@@ -0,0 +1,61 @@
+package http_auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+func TestMiddlewares(t *testing.T) {
+	authenticators := []Authenticator{
+		NewBasicAuthenticator("api", StaticCredentials(map[string]string{"alice": "s3cret"})),
+		NewAPIKeyAuthenticator("", map[string]application_auth.Principal{"key-1": {ID: "service-1"}}),
+	}
+	rw := http_response.NewJsonResponseWriter()
+
+	tests := []struct {
+		name       string
+		middleware router.Middleware
+		setup      func(r *http.Request)
+		status     int
+		principal  string
+		challenge  string
+	}{
+		{"required without credentials", Middleware(rw, authenticators...), func(*http.Request) {}, http.StatusUnauthorized, "", `Basic realm="api", charset="UTF-8"`},
+		{"optional without credentials", Optional(rw, authenticators...), func(*http.Request) {}, http.StatusOK, "", ""},
+		{"required with invalid credentials", Middleware(rw, authenticators...), func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }, http.StatusUnauthorized, "", `Basic realm="api", charset="UTF-8"`},
+		{"optional with invalid credentials", Optional(rw, authenticators...), func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }, http.StatusUnauthorized, "", `Basic realm="api", charset="UTF-8"`},
+		// The API key authenticator has no challenge
+		{"invalid API key", Optional(rw, authenticators...), func(r *http.Request) { r.Header.Set(APIKeyHeader, "wrong") }, http.StatusUnauthorized, "", ""},
+		{"required with basic credentials", Middleware(rw, authenticators...), func(r *http.Request) { r.SetBasicAuth("alice", "s3cret") }, http.StatusOK, "alice", ""},
+		{"required with an API key", Middleware(rw, authenticators...), func(r *http.Request) { r.Header.Set(APIKeyHeader, "key-1") }, http.StatusOK, "service-1", ""},
+		{"optional with an API key", Optional(rw, authenticators...), func(r *http.Request) { r.Header.Set(APIKeyHeader, "key-1") }, http.StatusOK, "service-1", ""},
+		{"required without authenticators", Middleware(rw), func(*http.Request) {}, http.StatusUnauthorized, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal string
+			handler := tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if p, ok := application_auth.PrincipalFromContext(r.Context()); ok {
+					principal = p.ID
+				}
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.setup(r)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status || principal != tt.principal {
+				t.Errorf("status = %d, principal = %q, want %d and %q", w.Code, principal, tt.status, tt.principal)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); challenge != tt.challenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", challenge, tt.challenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package http_auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+// BasicVerifier checks a username and password, returning the principal they authenticate.
+type BasicVerifier func(ctx context.Context, username, password string) (*application_auth.Principal, error)
+
+// BasicAuthenticator authenticates requests with HTTP basic auth.
+type BasicAuthenticator struct {
+	realm  string
+	verify BasicVerifier
+}
+
+func NewBasicAuthenticator(realm string, verify BasicVerifier) *BasicAuthenticator {
+	return &BasicAuthenticator{realm: realm, verify: verify}
+}
+
+func (b *BasicAuthenticator) Authenticate(r *http.Request) (*application_auth.Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	principal, err := b.verify(r.Context(), username, password)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, application_auth.NewUnauthenticated("invalid credentials")
+	}
+	principal.Method = "basic"
+
+	return principal, nil
+}
+
+func (b *BasicAuthenticator) Challenge() string {
+	return fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", b.realm)
+}
+
+// StaticCredentials returns a BasicVerifier checking usernames and passwords against a fixed map.
+func StaticCredentials(credentials map[string]string) BasicVerifier {
+	return func(_ context.Context, username, password string) (*application_auth.Principal, error) {
+		expected, ok := credentials[username]
+		given := sha256.Sum256([]byte(password))
+		want := sha256.Sum256([]byte(expected))
+		if subtle.ConstantTimeCompare(given[:], want[:]) != 1 || !ok {
+			return nil, application_auth.NewUnauthenticated("invalid credentials")
+		}
+
+		return &application_auth.Principal{ID: username}, nil
+	}
+}
@@ -0,0 +1,85 @@
+package http_auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+func TestBasicAuthenticatorWithStaticCredentials(t *testing.T) {
+	authenticator := NewBasicAuthenticator("api", StaticCredentials(map[string]string{
+		"alice": "s3cret",
+		"bob":   "",
+	}))
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		ok       bool
+	}{
+		{"valid", "alice", "s3cret", true},
+		{"wrong password", "alice", "secret", false},
+		{"password prefix", "alice", "s3cre", false},
+		{"empty password", "alice", "", false},
+		// An unknown user is compared against an empty password, which must not let it in
+		{"unknown user without password", "mallory", "", false},
+		{"unknown user", "mallory", "s3cret", false},
+		{"user with an empty password", "bob", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.SetBasicAuth(tt.username, tt.password)
+
+			principal, err := authenticator.Authenticate(r)
+			if !tt.ok {
+				var unauthenticated *application_auth.Unauthenticated
+				if !errors.As(err, &unauthenticated) || errors.Is(err, ErrNoCredentials) {
+					t.Errorf("Authenticate = %+v, %v, want invalid credentials", principal, err)
+				}
+				return
+			}
+			if err != nil || principal.ID != tt.username || principal.Method != "basic" {
+				t.Errorf("Authenticate = %+v, %v, want %s", principal, err, tt.username)
+			}
+		})
+	}
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	verifyErr := errors.New("directory unavailable")
+	authenticator := NewBasicAuthenticator("api", func(_ context.Context, username, _ string) (*application_auth.Principal, error) {
+		switch username {
+		case "failing":
+			return nil, verifyErr
+		case "unknown":
+			return nil, nil
+		default:
+			return &application_auth.Principal{ID: username}, nil
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, err := authenticator.Authenticate(r); err != ErrNoCredentials {
+		t.Errorf("Authenticate without credentials = %v, want ErrNoCredentials", err)
+	}
+
+	r.SetBasicAuth("failing", "x")
+	if _, err := authenticator.Authenticate(r); err != verifyErr {
+		t.Errorf("Authenticate = %v, want the verifier error", err)
+	}
+
+	r.SetBasicAuth("unknown", "x")
+	var unauthenticated *application_auth.Unauthenticated
+	if _, err := authenticator.Authenticate(r); !errors.As(err, &unauthenticated) {
+		t.Errorf("Authenticate = %v, want Unauthenticated without principal", err)
+	}
+
+	if challenge := authenticator.Challenge(); challenge != `Basic realm="api", charset="UTF-8"` {
+		t.Errorf("Challenge = %q", challenge)
+	}
+}
@@ -0,0 +1,233 @@
+package http_auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+// JWTConfig configures the JWTAuthenticator.
+type JWTConfig struct {
+	Keys []Key
+	// Issuer and Audience are checked against the iss and aud claims when not empty.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+	// RequireExpiration rejects tokens without an exp claim, which would never expire. Defaults to
+	// true when nil.
+	RequireExpiration *bool
+	// RolesClaim and PermissionsClaim default to "roles" and "permissions". A space separated
+	// "scope" claim is also read as permissions.
+	RolesClaim       string
+	PermissionsClaim string
+}
+
+// JWTAuthenticator authenticates requests with a bearer JWT signed with HMAC or RSA.
+type JWTAuthenticator struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTAuthenticator(config JWTConfig) *JWTAuthenticator {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.PermissionsClaim == "" {
+		config.PermissionsClaim = "permissions"
+	}
+	if config.RequireExpiration == nil {
+		requireExpiration := true
+		config.RequireExpiration = &requireExpiration
+	}
+
+	return &JWTAuthenticator{config: config, now: time.Now}
+}
+
+func (j *JWTAuthenticator) Authenticate(r *http.Request) (*application_auth.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := j.Verify(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &application_auth.Principal{
+		Method:      "jwt",
+		Roles:       stringsClaim(claims[j.config.RolesClaim]),
+		Permissions: stringsClaim(claims[j.config.PermissionsClaim]),
+		Claims:      claims,
+	}
+	principal.ID, _ = claims["sub"].(string)
+	if scope, ok := claims["scope"].(string); ok {
+		principal.Permissions = append(principal.Permissions, strings.Fields(scope)...)
+	}
+
+	return principal, nil
+}
+
+func (j *JWTAuthenticator) Challenge() string {
+	return "Bearer"
+}
+
+// Verify checks the token signature and registered claims, returning its claims.
+func (j *JWTAuthenticator) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+
+	if !j.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature) {
+		return nil, invalidToken("invalid signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+
+	if err := j.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (j *JWTAuthenticator) verifySignature(alg, kid, signingInput string, signature []byte) bool {
+	hash, ok := hashFor(alg)
+	if !ok {
+		return false
+	}
+
+	for _, key := range j.config.Keys {
+		// The algorithm is pinned by the key so a token can't pick how it is verified
+		if key.Algorithm != alg || (kid != "" && key.ID != "" && key.ID != kid) {
+			continue
+		}
+
+		switch {
+		case key.secret != nil && strings.HasPrefix(alg, "HS"):
+			mac := hmac.New(hash.New, key.secret)
+			mac.Write([]byte(signingInput))
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case key.publicKey != nil && strings.HasPrefix(alg, "RS"):
+			h := hash.New()
+			h.Write([]byte(signingInput))
+			if rsa.VerifyPKCS1v15(key.publicKey, hash, h.Sum(nil), signature) == nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (j *JWTAuthenticator) validateClaims(claims map[string]interface{}) error {
+	now := j.now()
+
+	exp, ok := numericClaim(claims["exp"])
+	if !ok && *j.config.RequireExpiration {
+		return invalidToken("missing expiration")
+	}
+	if ok && now.After(exp.Add(j.config.Leeway)) {
+		return invalidToken("token expired")
+	}
+	if nbf, ok := numericClaim(claims["nbf"]); ok && now.Add(j.config.Leeway).Before(nbf) {
+		return invalidToken("token not valid yet")
+	}
+	if iat, ok := numericClaim(claims["iat"]); ok && now.Add(j.config.Leeway).Before(iat) {
+		return invalidToken("token issued in the future")
+	}
+
+	if j.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != j.config.Issuer {
+			return invalidToken("invalid issuer")
+		}
+	}
+
+	if j.config.Audience != "" {
+		found := false
+		for _, aud := range stringsClaim(claims["aud"]) {
+			if aud == j.config.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalidToken("invalid audience")
+		}
+	}
+
+	return nil
+}
+
+func invalidToken(reason string) error {
+	return application_auth.NewUnauthenticated(fmt.Sprintf("invalid token: %s", reason))
+}
+
+func decodeSegment(segment string, dst interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(dst)
+}
+
+func numericClaim(value interface{}) (time.Time, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// stringsClaim reads a claim holding either a string or a list of strings.
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
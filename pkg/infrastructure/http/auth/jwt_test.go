@@ -0,0 +1,210 @@
+package http_auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+var (
+	testSecret = []byte("a secret of at least thirty two bytes")
+	testNow    = time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+)
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+// signJWT encodes header and claims and signs them with sign.
+func signJWT(t *testing.T, header, claims map[string]interface{}, sign func(input []byte) []byte) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := encode(header) + "." + encode(claims)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func hs256(secret []byte) func([]byte) []byte {
+	return func(input []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte {
+		sum := sha256.Sum256(input)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatalf("SignPKCS1v15: %v", err)
+		}
+		return signature
+	}
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":         "user-1",
+		"iss":         "https://issuer.example",
+		"aud":         []string{"api", "admin"},
+		"exp":         testNow.Add(time.Hour).Unix(),
+		"iat":         testNow.Add(-time.Minute).Unix(),
+		"roles":       []string{"admin"},
+		"permissions": "users:read",
+		"scope":       "users:write orders:read",
+	}
+}
+
+func withClaim(name string, value interface{}) map[string]interface{} {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func newTestJWTAuthenticator(config JWTConfig) *JWTAuthenticator {
+	authenticator := NewJWTAuthenticator(config)
+	authenticator.now = func() time.Time { return testNow }
+	return authenticator
+}
+
+func TestJWTAuthenticatorVerify(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	otherRSAKey := generateRSAKey(t)
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	authenticator := newTestJWTAuthenticator(JWTConfig{
+		Keys: []Key{
+			NewHMACKey("hmac", "HS256", testSecret),
+			NewRSAKey("rsa", "RS256", &rsaKey.PublicKey),
+		},
+		Issuer:   "https://issuer.example",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+	})
+
+	hs := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	rs := map[string]interface{}{"alg": "RS256", "kid": "rsa"}
+
+	tests := []struct {
+		name  string
+		token string
+		// reason is the expected rejection, empty for valid tokens
+		reason string
+	}{
+		{"HS256", signJWT(t, hs, validClaims(), hs256(testSecret)), ""},
+		{"RS256", signJWT(t, rs, validClaims(), rs256(t, rsaKey)), ""},
+		{"RS256 without kid", signJWT(t, map[string]interface{}{"alg": "RS256"}, validClaims(), rs256(t, rsaKey)), ""},
+		{"wrong HMAC secret", signJWT(t, hs, validClaims(), hs256([]byte("another secret"))), "invalid signature"},
+		{"wrong RSA key", signJWT(t, rs, validClaims(), rs256(t, otherRSAKey)), "invalid signature"},
+		{"kid of another key", signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "hmac"}, validClaims(), rs256(t, rsaKey)), "invalid signature"},
+		{"unknown kid", signJWT(t, map[string]interface{}{"alg": "HS256", "kid": "rotated"}, validClaims(), hs256(testSecret)), "invalid signature"},
+		{"alg none", signJWT(t, map[string]interface{}{"alg": "none"}, validClaims(), func([]byte) []byte { return nil }), "invalid signature"},
+		// The RSA public key used as HMAC secret, the key being pinned to RS256
+		{"HS256 signed with the RSA public key", signJWT(t, map[string]interface{}{"alg": "HS256", "kid": "rsa"}, validClaims(), hs256(publicDER)), "invalid signature"},
+		{"malformed", "not.a-token", "malformed token"},
+		{"malformed header", "bm90IGpzb24.e30.c2ln", "malformed header"},
+		{"expired", signJWT(t, hs, withClaim("exp", testNow.Add(-time.Minute).Unix()), hs256(testSecret)), "token expired"},
+		{"expired within leeway", signJWT(t, hs, withClaim("exp", testNow.Add(-10*time.Second).Unix()), hs256(testSecret)), ""},
+		{"missing exp", signJWT(t, hs, withClaim("exp", nil), hs256(testSecret)), "missing expiration"},
+		{"not valid yet", signJWT(t, hs, withClaim("nbf", testNow.Add(time.Minute).Unix()), hs256(testSecret)), "token not valid yet"},
+		{"nbf within leeway", signJWT(t, hs, withClaim("nbf", testNow.Add(10*time.Second).Unix()), hs256(testSecret)), ""},
+		{"issued in the future", signJWT(t, hs, withClaim("iat", testNow.Add(time.Minute).Unix()), hs256(testSecret)), "token issued in the future"},
+		{"iat within leeway", signJWT(t, hs, withClaim("iat", testNow.Add(10*time.Second).Unix()), hs256(testSecret)), ""},
+		{"wrong issuer", signJWT(t, hs, withClaim("iss", "https://evil.example"), hs256(testSecret)), "invalid issuer"},
+		{"missing issuer", signJWT(t, hs, withClaim("iss", nil), hs256(testSecret)), "invalid issuer"},
+		{"single audience", signJWT(t, hs, withClaim("aud", "api"), hs256(testSecret)), ""},
+		{"wrong audience", signJWT(t, hs, withClaim("aud", []string{"admin"}), hs256(testSecret)), "invalid audience"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := authenticator.Verify(tt.token)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("sub = %v, want user-1", claims["sub"])
+				}
+				return
+			}
+
+			var unauthenticated *application_auth.Unauthenticated
+			if !errors.As(err, &unauthenticated) || unauthenticated.Message != "invalid token: "+tt.reason {
+				t.Errorf("Verify = %v, want invalid token: %s", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorOptionalExpiration(t *testing.T) {
+	requireExpiration := false
+	authenticator := newTestJWTAuthenticator(JWTConfig{
+		Keys:              []Key{NewHMACKey("", "HS256", testSecret)},
+		RequireExpiration: &requireExpiration,
+	})
+
+	token := signJWT(t, map[string]interface{}{"alg": "HS256"}, withClaim("exp", nil), hs256(testSecret))
+	if _, err := authenticator.Verify(token); err != nil {
+		t.Errorf("Verify = %v, want tokens without exp accepted", err)
+	}
+}
+
+func TestJWTAuthenticatorPrincipal(t *testing.T) {
+	authenticator := newTestJWTAuthenticator(JWTConfig{Keys: []Key{NewHMACKey("", "HS256", testSecret)}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, err := authenticator.Authenticate(r); err != ErrNoCredentials {
+		t.Fatalf("Authenticate without header = %v, want ErrNoCredentials", err)
+	}
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	if _, err := authenticator.Authenticate(r); err != ErrNoCredentials {
+		t.Fatalf("Authenticate with basic auth = %v, want ErrNoCredentials", err)
+	}
+
+	r.Header.Set("Authorization", "bearer "+signJWT(t, map[string]interface{}{"alg": "HS256"}, validClaims(), hs256(testSecret)))
+	principal, err := authenticator.Authenticate(r)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.ID != "user-1" || principal.Method != "jwt" {
+		t.Errorf("principal = %+v", principal)
+	}
+	if !reflect.DeepEqual(principal.Roles, []string{"admin"}) {
+		t.Errorf("Roles = %v, want [admin]", principal.Roles)
+	}
+	if want := []string{"users:read", "users:write", "orders:read"}; !reflect.DeepEqual(principal.Permissions, want) {
+		t.Errorf("Permissions = %v, want %v", principal.Permissions, want)
+	}
+}
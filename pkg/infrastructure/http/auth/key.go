@@ -0,0 +1,142 @@
+package http_auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Key is a key JWT signatures are verified with.
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	publicKey *rsa.PublicKey
+}
+
+// NewHMACKey returns a key verifying HS256, HS384 or HS512 signatures.
+func NewHMACKey(id, algorithm string, secret []byte) Key {
+	return Key{ID: id, Algorithm: algorithm, secret: secret}
+}
+
+// NewRSAKey returns a key verifying RS256, RS384 or RS512 signatures.
+func NewRSAKey(id, algorithm string, publicKey *rsa.PublicKey) Key {
+	return Key{ID: id, Algorithm: algorithm, publicKey: publicKey}
+}
+
+// ParseRSAPublicKeyPEM parses a PEM encoded PKIX or PKCS1 RSA public key.
+func ParseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+// jwk is a JSON Web Key as found in a JWKS document.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// LoadJWKSFile reads the RSA and symmetric keys of a local JWKS file. Keys not meant for
+// signatures are skipped.
+func LoadJWKSFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses the RSA and symmetric keys of a JWKS document.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("invalid modulus for key %s: %w", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exponent for key %s: %w", k.Kid, err)
+			}
+			alg := k.Alg
+			if alg == "" {
+				alg = "RS256"
+			}
+			keys = append(keys, NewRSAKey(k.Kid, alg, &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}))
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, fmt.Errorf("invalid secret for key %s: %w", k.Kid, err)
+			}
+			alg := k.Alg
+			if alg == "" {
+				alg = "HS256"
+			}
+			keys = append(keys, NewHMACKey(k.Kid, alg, secret))
+		}
+	}
+
+	return keys, nil
+}
+
+// hashFor returns the hash used by a JWS algorithm.
+func hashFor(algorithm string) (crypto.Hash, bool) {
+	switch algorithm {
+	case "HS256", "RS256":
+		return crypto.SHA256, true
+	case "HS384", "RS384":
+		return crypto.SHA384, true
+	case "HS512", "RS512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,100 @@
+package http_auth
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	n := base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())
+	k := base64.RawURLEncoding.EncodeToString(testSecret)
+
+	keys, err := ParseJWKS([]byte(fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa","use":"sig","n":%q,"e":%q},
+		{"kty":"RSA","kid":"rsa-512","alg":"RS512","n":%q,"e":%q},
+		{"kty":"oct","kid":"hmac","k":%q},
+		{"kty":"RSA","kid":"encryption","use":"enc","n":%q,"e":%q},
+		{"kty":"EC","kid":"ec","crv":"P-256"}
+	]}`, n, e, n, e, k, n, e)))
+	if err != nil {
+		t.Fatalf("ParseJWKS: %v", err)
+	}
+
+	want := []struct{ id, algorithm string }{{"rsa", "RS256"}, {"rsa-512", "RS512"}, {"hmac", "HS256"}}
+	if len(keys) != len(want) {
+		t.Fatalf("parsed %d keys, want %d", len(keys), len(want))
+	}
+	for i, w := range want {
+		if keys[i].ID != w.id || keys[i].Algorithm != w.algorithm {
+			t.Errorf("key %d = %s %s, want %s %s", i, keys[i].ID, keys[i].Algorithm, w.id, w.algorithm)
+		}
+	}
+	if !keys[0].publicKey.Equal(&rsaKey.PublicKey) || string(keys[2].secret) != string(testSecret) {
+		t.Error("parsed key material differs from the JWKS")
+	}
+
+	// The parsed keys verify the tokens they signed, selected by kid
+	authenticator := newTestJWTAuthenticator(JWTConfig{Keys: keys})
+	token := signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, validClaims(), rs256(t, rsaKey))
+	if _, err := authenticator.Verify(token); err != nil {
+		t.Errorf("Verify with JWKS keys: %v", err)
+	}
+}
+
+func TestParseJWKSRejectsInvalidDocuments(t *testing.T) {
+	for _, doc := range []string{
+		`not json`,
+		`{"keys":[{"kty":"RSA","kid":"rsa","n":"not base64!","e":"AQAB"}]}`,
+		`{"keys":[{"kty":"oct","kid":"hmac","k":"not base64!"}]}`,
+	} {
+		if _, err := ParseJWKS([]byte(doc)); err == nil {
+			t.Errorf("ParseJWKS(%s) = nil error", doc)
+		}
+	}
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data := fmt.Sprintf(`{"keys":[{"kty":"oct","kid":"hmac","alg":"HS512","k":%q}]}`, base64.RawURLEncoding.EncodeToString(testSecret))
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	keys, err := LoadJWKSFile(path)
+	if err != nil || len(keys) != 1 || keys[0].Algorithm != "HS512" {
+		t.Fatalf("LoadJWKSFile = %+v, %v", keys, err)
+	}
+	if _, err := LoadJWKSFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadJWKSFile of a missing file = nil error")
+	}
+}
+
+func TestParseRSAPublicKeyPEM(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	pkix, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	for name, block := range map[string]*pem.Block{
+		"PKIX":  {Type: "PUBLIC KEY", Bytes: pkix},
+		"PKCS1": {Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+	} {
+		key, err := ParseRSAPublicKeyPEM(pem.EncodeToMemory(block))
+		if err != nil || !key.Equal(&rsaKey.PublicKey) {
+			t.Errorf("ParseRSAPublicKeyPEM(%s) = %v, want the public key", name, err)
+		}
+	}
+
+	if _, err := ParseRSAPublicKeyPEM([]byte("not a PEM")); err == nil {
+		t.Error("ParseRSAPublicKeyPEM without block = nil error")
+	}
+}
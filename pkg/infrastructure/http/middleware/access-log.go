@@ -0,0 +1,45 @@
+package http_middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// AccessLog logs every request once it has been served, as an error for 5xx responses,
+// a warning for 4xx ones and info otherwise.
+func AccessLog(l logger.Logger) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := newStatusRecorder(w)
+
+			next.ServeHTTP(recorder, r)
+
+			fields := map[string]interface{}{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"query":       r.URL.RawQuery,
+				"status":      recorder.status,
+				"size":        recorder.size,
+				"duration_ms": time.Since(start).Milliseconds(),
+				"remote_addr": r.RemoteAddr,
+				"user_agent":  r.UserAgent(),
+			}
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				fields["request_id"] = id
+			}
+
+			switch {
+			case recorder.status >= http.StatusInternalServerError:
+				l.Error(r.Context(), "http request", fields)
+			case recorder.status >= http.StatusBadRequest:
+				l.Warn(r.Context(), "http request", fields)
+			default:
+				l.Info(r.Context(), "http request", fields)
+			}
+		})
+	}
+}
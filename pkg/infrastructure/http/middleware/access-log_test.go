@@ -0,0 +1,106 @@
+package http_middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+)
+
+type accessLogEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+// accessLogger records the logged entries with their level.
+type accessLogger struct {
+	mu      sync.Mutex
+	entries []accessLogEntry
+}
+
+func (l *accessLogger) log(level, msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, accessLogEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *accessLogger) Debug(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("debug", msg, fields)
+}
+
+func (l *accessLogger) Info(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("info", msg, fields)
+}
+
+func (l *accessLogger) Warn(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("warn", msg, fields)
+}
+
+func (l *accessLogger) Error(_ context.Context, msg string, fields map[string]interface{}) {
+	l.log("error", msg, fields)
+}
+
+func (l *accessLogger) WithField(context.Context, string, interface{}) logger.Logger {
+	return l
+}
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		// write answers with WriteHeader and a body when set, an implicit 200 otherwise
+		write bool
+		level string
+	}{
+		{"implicit ok", http.StatusOK, false, "info"},
+		{"created", http.StatusCreated, true, "info"},
+		{"not found", http.StatusNotFound, true, "warn"},
+		{"server error", http.StatusBadGateway, true, "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &accessLogger{}
+			handler := RequestID("")(AccessLog(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.write {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write([]byte("hello"))
+			})))
+
+			r := httptest.NewRequest(http.MethodPost, "/users?page=2", nil)
+			r.Header.Set(RequestIDHeader, "req-1")
+			r.Header.Set("User-Agent", "test")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if len(l.entries) != 1 {
+				t.Fatalf("logged %d entries, want 1", len(l.entries))
+			}
+			entry := l.entries[0]
+			if entry.level != tt.level || entry.msg != "http request" {
+				t.Errorf("logged %q at %s, want http request at %s", entry.msg, entry.level, tt.level)
+			}
+			want := map[string]interface{}{
+				"method":      http.MethodPost,
+				"path":        "/users",
+				"query":       "page=2",
+				"status":      tt.status,
+				"size":        5,
+				"remote_addr": r.RemoteAddr,
+				"user_agent":  "test",
+				"request_id":  "req-1",
+			}
+			for name, value := range want {
+				if entry.fields[name] != value {
+					t.Errorf("%s = %v (%T), want %v (%T)", name, entry.fields[name], entry.fields[name], value, value)
+				}
+			}
+			if _, ok := entry.fields["duration_ms"]; !ok {
+				t.Error("duration_ms not logged")
+			}
+		})
+	}
+}
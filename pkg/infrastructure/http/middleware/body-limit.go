@@ -0,0 +1,26 @@
+package http_middleware
+
+import (
+	"net/http"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// BodyLimit rejects requests whose body is larger than maxBytes. Requests announcing a bigger
+// Content-Length are answered right away with ErrBodyTooLarge, the others fail while reading the body.
+func BodyLimit(maxBytes int64, rw http_response.ResponseWriter) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				rw.WriteErrorResponse(w, ErrBodyTooLarge, ErrBodyTooLarge.Status, ErrBodyTooLarge)
+				return
+			}
+
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package http_middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+)
+
+func TestBodyLimit(t *testing.T) {
+	var readErr error
+	reached := false
+	handler := BodyLimit(8, http_response.NewJsonResponseWriter())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	tests := []struct {
+		name string
+		body string
+		// contentLength overrides the length announced by the request, -1 for an unknown one
+		contentLength int64
+		status        int
+		reached       bool
+		tooLarge      bool
+	}{
+		{"within the limit", "12345678", 8, http.StatusOK, true, false},
+		{"announced too large", "123456789", 9, http.StatusRequestEntityTooLarge, false, false},
+		{"chunked too large", "123456789", -1, http.StatusOK, true, true},
+		{"chunked within the limit", "1234", -1, http.StatusOK, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached, readErr = false, nil
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.ContentLength = tt.contentLength
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status || reached != tt.reached {
+				t.Fatalf("status = %d, handler reached %v, want %d and %v", w.Code, reached, tt.status, tt.reached)
+			}
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(readErr, &maxBytesErr) != tt.tooLarge {
+				t.Errorf("read error = %v, want a MaxBytesError %v", readErr, tt.tooLarge)
+			}
+			if tt.status == http.StatusRequestEntityTooLarge {
+				var body map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !strings.Contains(w.Body.String(), ErrBodyTooLarge.Message) {
+					t.Errorf("body = %s, want %q", w.Body.String(), ErrBodyTooLarge.Message)
+				}
+			}
+		})
+	}
+}
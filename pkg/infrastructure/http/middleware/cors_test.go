@@ -0,0 +1,134 @@
+package http_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// serveCORS sends a request from origin through the CORS middleware, a preflight one when
+// requestMethod is set, and reports whether it reached the handler.
+func serveCORS(config CORSConfig, method, origin, requestMethod string) (*httptest.ResponseRecorder, bool) {
+	reached := false
+	handler := CORS(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(method, "/users", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if requestMethod != "" {
+		r.Header.Set("Access-Control-Request-Method", requestMethod)
+		r.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w, reached
+}
+
+func TestCORSPreflight(t *testing.T) {
+	config := CORSConfig{
+		AllowedOrigins: []string{"https://app.example"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         time.Hour,
+	}
+
+	w, reached := serveCORS(config, http.MethodOptions, "https://APP.example", http.MethodPost)
+	if w.Code != http.StatusNoContent || reached {
+		t.Fatalf("status = %d, handler reached %v, want %d answered by the middleware", w.Code, reached, http.StatusNoContent)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://APP.example",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Content-Type",
+		"Access-Control-Max-Age":       "3600",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+	if got, want := w.Header().Values("Vary"), []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Vary = %v, want %v", got, want)
+	}
+
+	// Without allowed headers, the requested ones are allowed
+	config.AllowedHeaders = nil
+	w, _ = serveCORS(config, http.MethodOptions, "https://app.example", http.MethodPost)
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "X-Custom" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want the requested X-Custom", got)
+	}
+}
+
+func TestCORSDeniedOrigin(t *testing.T) {
+	config := CORSConfig{AllowedOrigins: []string{"https://app.example"}, AllowedMethods: []string{http.MethodGet}}
+
+	w, reached := serveCORS(config, http.MethodOptions, "https://evil.example", http.MethodGet)
+	if w.Code != http.StatusNoContent || reached {
+		t.Errorf("preflight status = %d, handler reached %v, want %d answered by the middleware", w.Code, reached, http.StatusNoContent)
+	}
+	for _, name := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers"} {
+		if got := w.Header().Get(name); got != "" {
+			t.Errorf("preflight %s = %q, want none", name, got)
+		}
+	}
+
+	// The browser enforces the missing headers on simple requests
+	w, reached = serveCORS(config, http.MethodGet, "https://evil.example", "")
+	if !reached || w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "Origin" {
+		t.Errorf("request reached %v with headers %v, want it served without CORS headers", reached, w.Header())
+	}
+}
+
+func TestCORSSimpleRequests(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.ExposedHeaders = []string{RequestIDHeader}
+
+	w, reached := serveCORS(config, http.MethodGet, "https://app.example", "")
+	if !reached || w.Code != http.StatusOK {
+		t.Fatalf("status = %d, handler reached %v, want the request served", w.Code, reached)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != RequestIDHeader {
+		t.Errorf("Access-Control-Expose-Headers = %q, want %s", got, RequestIDHeader)
+	}
+
+	// Requests without Origin aren't cross-origin
+	w, reached = serveCORS(config, http.MethodGet, "", "")
+	if !reached || len(w.Header()) != 0 {
+		t.Errorf("request reached %v with headers %v, want it served without CORS headers", reached, w.Header())
+	}
+
+	// OPTIONS requests that aren't preflights reach the handler
+	if _, reached := serveCORS(config, http.MethodOptions, "https://app.example", ""); !reached {
+		t.Error("OPTIONS request without Access-Control-Request-Method not served")
+	}
+}
+
+func TestCORSCredentialsWithWildcard(t *testing.T) {
+	config := DefaultCORSConfig()
+	config.AllowCredentials = true
+
+	for _, requestMethod := range []string{"", http.MethodPost} {
+		w, _ := serveCORS(config, http.MethodOptions, "https://app.example", requestMethod)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want the origin echoed back instead of *", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+		}
+		if got := w.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+			t.Errorf("Vary = %v, want Origin", got)
+		}
+	}
+}
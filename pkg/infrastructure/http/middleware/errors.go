@@ -0,0 +1,23 @@
+package http_middleware
+
+import "net/http"
+
+// Error is the error written through the ResponseWriter when a middleware rejects a request.
+type Error struct {
+	Status  int    `json:"-"`
+	Message string `json:"message"`
+}
+
+func NewError(status int, message string) *Error {
+	return &Error{Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrInternal       = NewError(http.StatusInternalServerError, "internal server error")
+	ErrRequestTimeout = NewError(http.StatusServiceUnavailable, "request timed out")
+	ErrBodyTooLarge   = NewError(http.StatusRequestEntityTooLarge, "request body too large")
+)
@@ -0,0 +1,126 @@
+package http_middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// Gzip compresses responses for clients accepting gzip with the given compression level
+// (gzip.DefaultCompression when 0).
+func Gzip(level int) router.Middleware {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	pool := sync.Pool{New: func() interface{} {
+		gz, err := gzip.NewWriterLevel(nil, level)
+		if err != nil {
+			panic(err)
+		}
+		return gz
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, pool: &pool}
+			defer gw.close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipResponseWriter only starts compressing on the first write, so bodiless responses are left untouched.
+// The header is held until then, so the content type can be sniffed from the uncompressed body.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	gz          *gzip.Writer
+	status      int
+	wroteHeader bool
+	passThrough bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status != 0 {
+		return
+	}
+	g.status = status
+
+	if g.Header().Get("Content-Encoding") != "" || status == http.StatusNoContent || status == http.StatusNotModified {
+		g.passThrough = true
+		g.wroteHeader = true
+		g.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.status == 0 {
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.wroteHeader {
+		g.writeHeader(b)
+	}
+	if g.passThrough {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+// writeHeader writes the held header for a compressed body starting with b.
+func (g *gzipResponseWriter) writeHeader(b []byte) {
+	g.wroteHeader = true
+
+	h := g.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(b))
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.status)
+
+	g.gz = g.pool.Get().(*gzip.Writer)
+	g.gz.Reset(g.ResponseWriter)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if g.status == 0 {
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.wroteHeader {
+		g.writeHeader(nil)
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) close() {
+	// The status was written without a body
+	if g.status != 0 && !g.wroteHeader {
+		g.ResponseWriter.WriteHeader(g.status)
+	}
+	if g.gz == nil {
+		return
+	}
+	_ = g.gz.Close()
+	g.pool.Put(g.gz)
+	g.gz = nil
+}
@@ -0,0 +1,124 @@
+package http_middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveGzip(t *testing.T, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	Gzip(0)(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func gunzip(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	return string(body)
+}
+
+func TestGzipCompressesWhenAccepted(t *testing.T) {
+	rec := serveGzip(t, "gzip, deflate", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "13")
+		_, _ = w.Write([]byte(`{"ok":"true"}`))
+	})
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q, want none", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+	if got := gunzip(t, rec); got != `{"ok":"true"}` {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestGzipSniffsContentTypeWhenHeaderWrittenFirst(t *testing.T) {
+	rec := serveGzip(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("<html><body>hello</body></html>"))
+	})
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want the type of the uncompressed body", got)
+	}
+	if got := gunzip(t, rec); got != "<html><body>hello</body></html>" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestGzipSkipsWhenNotAccepted(t *testing.T) {
+	rec := serveGzip(t, "", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("plain"))
+	})
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+	if got := rec.Body.String(); got != "plain" {
+		t.Errorf("body = %q, want plain", got)
+	}
+}
+
+func TestGzipLeavesBodilessResponsesUntouched(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusAccepted} {
+		rec := serveGzip(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+
+		if rec.Code != status {
+			t.Errorf("status = %d, want %d", rec.Code, status)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("status %d: Content-Encoding = %q, want none", status, got)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("status %d: body = %q, want none", status, rec.Body.String())
+		}
+	}
+}
+
+func TestGzipKeepsEncodedResponses(t *testing.T) {
+	rec := serveGzip(t, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte("encoded"))
+	})
+
+	if got := rec.Header().Get("Content-Encoding"); got != "br" {
+		t.Errorf("Content-Encoding = %q, want br", got)
+	}
+	if got := rec.Body.String(); got != "encoded" {
+		t.Errorf("body = %q, want encoded", got)
+	}
+}
@@ -0,0 +1,203 @@
+package http_idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	http_middleware "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/middleware"
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+const (
+	// Header is the request header holding the idempotency key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// defaultLease is how long a key stays reserved by a request in progress.
+const defaultLease = time.Minute
+
+var (
+	ErrRequestInProgress = http_middleware.NewError(http.StatusConflict, "a request with this idempotency key is in progress")
+	ErrKeyReused         = http_middleware.NewError(http.StatusUnprocessableEntity, "idempotency key reused with a different request")
+	ErrStoreUnavailable  = http_middleware.NewError(http.StatusServiceUnavailable, "idempotency keys can't be checked")
+)
+
+// Options tunes the idempotency Middleware.
+type Options struct {
+	lease      time.Duration
+	failClosed bool
+}
+
+// WithLease sets how long a key stays reserved by a request in progress, 1 minute by default. A key
+// reserved by an instance that crashed can be retried once its lease expired, a request running for
+// longer than its lease can be served twice.
+func WithLease(lease time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.lease = lease
+	}
+}
+
+// WithFailClosed rejects the requests with a key with 503 when the store can't reserve it, instead of
+// serving them without deduplication.
+func WithFailClosed() func(*Options) {
+	return func(o *Options) {
+		o.failClosed = true
+	}
+}
+
+// Middleware stores the response of the first request sent with an Idempotency-Key header and replays it
+// for the following requests with the same key, for ttl. Keys are scoped by principal, and reusing a key
+// for a different request is rejected. Server errors aren't stored so the request can be retried.
+// Safe methods and requests without the header aren't affected.
+//
+// When the store fails to reserve a key the request is served without deduplication, unless
+// WithFailClosed is given.
+func Middleware(store Store, ttl time.Duration, rw http_response.ResponseWriter, options ...func(*Options)) router.Middleware {
+	o := Options{lease: defaultLease}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.lease <= 0 || o.lease > ttl {
+		o.lease = ttl
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || isSafe(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fingerprint, err := fingerprintRequest(r)
+			if err != nil {
+				rw.WriteErrorResponse(w, err, http.StatusBadRequest, err)
+				return
+			}
+
+			if p, ok := application_auth.PrincipalFromContext(r.Context()); ok {
+				key = p.ID + ":" + key
+			}
+
+			existing, err := store.Reserve(r.Context(), key, fingerprint, o.lease)
+			if err != nil {
+				if o.failClosed {
+					rw.WriteErrorResponse(w, ErrStoreUnavailable, ErrStoreUnavailable.Status, err)
+					return
+				}
+				// Without the store the request is served as if it had no key
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if existing != nil {
+				replay(w, rw, existing, fingerprint)
+				return
+			}
+
+			// The response is stored, or the key released, even if the client went away meanwhile
+			ctx := context.WithoutCancel(r.Context())
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					_ = store.Release(ctx, key)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			completed = store.Complete(ctx, key, Record{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      recorder.status,
+				Header:      recorder.Header().Clone(),
+				Body:        recorder.body.Bytes(),
+			}, ttl) == nil
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, rw http_response.ResponseWriter, record *Record, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		rw.WriteErrorResponse(w, ErrKeyReused, ErrKeyReused.Status, ErrKeyReused)
+		return
+	}
+	if !record.Completed {
+		rw.WriteErrorResponse(w, ErrRequestInProgress, ErrRequestInProgress.Status, ErrRequestInProgress)
+		return
+	}
+
+	h := w.Header()
+	for k, v := range record.Header {
+		h[k] = v
+	}
+	h.Set(ReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	_, _ = w.Write(record.Body)
+}
+
+// fingerprintRequest hashes the method, path and body of the request, restoring the body afterwards.
+func fingerprintRequest(r *http.Request) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func isSafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// responseRecorder writes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,181 @@
+package http_idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+)
+
+// contextStore fails the calls made with a done context, like a network store would.
+type contextStore struct {
+	Store
+	reserveErr error
+}
+
+func (s *contextStore) Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error) {
+	if s.reserveErr != nil {
+		return nil, s.reserveErr
+	}
+	return s.Store.Reserve(ctx, key, fingerprint, lease)
+}
+
+func (s *contextStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Complete(ctx, key, record, ttl)
+}
+
+func (s *contextStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Release(ctx, key)
+}
+
+// countingHandler answers with status and counts its calls.
+func countingHandler(calls *atomic.Int32, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("X-Call", strconv.Itoa(int(n)))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("created"))
+	})
+}
+
+func send(handler http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareReplaysCompletedRequests(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewMemoryStore(), time.Hour, http_response.NewJsonResponseWriter())(countingHandler(&calls, http.StatusCreated))
+
+	first := send(handler, http.MethodPost, "k1", `{"id":1}`)
+	second := send(handler, http.MethodPost, "k1", `{"id":1}`)
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler called %d times, want 1", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != "created" || second.Header().Get("X-Call") != "1" {
+		t.Errorf("replay = %d %q %v, want the first response", second.Code, second.Body.String(), second.Header())
+	}
+	if second.Header().Get(ReplayedHeader) != "true" || first.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("%s = %q then %q, want only the replay marked", ReplayedHeader, first.Header().Get(ReplayedHeader), second.Header().Get(ReplayedHeader))
+	}
+}
+
+func TestMiddlewareIgnoresSafeMethodsAndMissingKeys(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewMemoryStore(), time.Hour, http_response.NewJsonResponseWriter())(countingHandler(&calls, http.StatusOK))
+
+	send(handler, http.MethodGet, "k1", "")
+	send(handler, http.MethodGet, "k1", "")
+	send(handler, http.MethodPost, "", `{}`)
+	send(handler, http.MethodPost, "", `{}`)
+
+	if calls.Load() != 4 {
+		t.Errorf("handler called %d times, want 4", calls.Load())
+	}
+}
+
+func TestMiddlewareRejectsKeyReuse(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewMemoryStore(), time.Hour, http_response.NewJsonResponseWriter())(countingHandler(&calls, http.StatusCreated))
+
+	send(handler, http.MethodPost, "k1", `{"id":1}`)
+	if w := send(handler, http.MethodPost, "k1", `{"id":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
+
+func TestMiddlewareRejectsRequestsInProgress(t *testing.T) {
+	store := NewMemoryStore()
+	var calls atomic.Int32
+	handler := Middleware(store, time.Hour, http_response.NewJsonResponseWriter(), WithLease(50*time.Millisecond))(countingHandler(&calls, http.StatusCreated))
+
+	fingerprint, _ := fingerprintRequest(httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`)))
+	if _, err := store.Reserve(context.Background(), "k1", fingerprint, 50*time.Millisecond); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	if w := send(handler, http.MethodPost, "k1", `{}`); w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// A reservation left by a request that never completed expires with its lease
+	time.Sleep(60 * time.Millisecond)
+	if w := send(handler, http.MethodPost, "k1", `{}`); w.Code != http.StatusCreated || calls.Load() != 1 {
+		t.Errorf("status = %d after the lease, %d calls, want %d and 1 call", w.Code, calls.Load(), http.StatusCreated)
+	}
+}
+
+func TestMiddlewareReleasesKeyOnServerError(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewMemoryStore(), time.Hour, http_response.NewJsonResponseWriter())(countingHandler(&calls, http.StatusServiceUnavailable))
+
+	send(handler, http.MethodPost, "k1", `{}`)
+	if w := send(handler, http.MethodPost, "k1", `{}`); w.Header().Get(ReplayedHeader) != "" {
+		t.Error("server error replayed, want the request retried")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler called %d times, want 2", calls.Load())
+	}
+}
+
+func TestMiddlewareCompletesAfterClientDisconnect(t *testing.T) {
+	store := &contextStore{Store: NewMemoryStore()}
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := Middleware(store, time.Hour, http_response.NewJsonResponseWriter())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The client goes away while the request is handled
+		cancel()
+		countingHandler(&calls, http.StatusCreated).ServeHTTP(w, r)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`)).WithContext(ctx)
+	r.Header.Set(Header, "k1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if w := send(handler, http.MethodPost, "k1", `{}`); w.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("response not stored once the client went away, status %d", w.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
+
+func TestMiddlewareStoreFailure(t *testing.T) {
+	store := &contextStore{Store: NewMemoryStore(), reserveErr: errors.New("store down")}
+	var calls atomic.Int32
+	rw := http_response.NewJsonResponseWriter()
+
+	open := Middleware(store, time.Hour, rw)(countingHandler(&calls, http.StatusCreated))
+	if w := send(open, http.MethodPost, "k1", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("fail open status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	closed := Middleware(store, time.Hour, rw, WithFailClosed())(countingHandler(&calls, http.StatusCreated))
+	if w := send(closed, http.MethodPost, "k1", `{}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("fail closed status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,80 @@
+package http_idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps the idempotency records in Redis so they are shared by every instance.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "idempotency"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (r *RedisStore) Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error) {
+	data, err := json.Marshal(Record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize idempotency record: %w", err)
+	}
+
+	reserved, err := r.client.SetNX(ctx, r.key(key), data, lease).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// The record expired or was released in between, try again
+		return r.Reserve(ctx, key, fingerprint, lease)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(existing, &record); err != nil {
+		return nil, fmt.Errorf("failed to deserialize idempotency record: %w", err)
+	}
+
+	return &record, nil
+}
+
+func (r *RedisStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to serialize idempotency record: %w", err)
+	}
+
+	if err := r.client.Set(ctx, r.key(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisStore) Release(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.key(key)).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisStore) key(key string) string {
+	return r.prefix + ":" + key
+}
@@ -0,0 +1,84 @@
+package http_idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Record is what is stored for an idempotency key: the request fingerprint and, once the first
+// request has been served, its response.
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Store keeps the idempotency records.
+type Store interface {
+	// Reserve stores an in-progress record for key, expiring after lease, unless one already exists,
+	// which is then returned.
+	Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error)
+	// Complete stores the response of the request that reserved key.
+	Complete(ctx context.Context, key string, record Record, ttl time.Duration) error
+	// Release drops the record for key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore keeps the idempotency records in memory, it is only suited to single instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+}
+
+type memoryRecord struct {
+	record    Record
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+func (m *MemoryStore) Reserve(_ context.Context, key, fingerprint string, lease time.Duration) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	if existing, ok := m.records[key]; ok {
+		record := existing.record
+		return &record, nil
+	}
+
+	m.records[key] = memoryRecord{record: Record{Fingerprint: fingerprint}, expiresAt: now.Add(lease)}
+	return nil, nil
+}
+
+func (m *MemoryStore) Complete(_ context.Context, key string, record Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[key] = memoryRecord{record: record, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Release(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, r := range m.records {
+		if now.After(r.expiresAt) {
+			delete(m.records, key)
+		}
+	}
+}
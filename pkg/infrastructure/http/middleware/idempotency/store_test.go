@@ -0,0 +1,50 @@
+package http_idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testStore checks the Store contract, wait letting the given duration elapse for the store.
+func testStore(t *testing.T, store Store, wait func(time.Duration)) {
+	ctx := context.Background()
+
+	existing, err := store.Reserve(ctx, "k1", "f1", 50*time.Millisecond)
+	if err != nil || existing != nil {
+		t.Fatalf("Reserve = %+v, %v, want the key reserved", existing, err)
+	}
+
+	existing, err = store.Reserve(ctx, "k1", "f2", 50*time.Millisecond)
+	if err != nil || existing == nil || existing.Fingerprint != "f1" || existing.Completed {
+		t.Fatalf("second Reserve = %+v, %v, want the in-progress record", existing, err)
+	}
+
+	// The reservation expires with its lease, the completed record with its ttl
+	wait(60 * time.Millisecond)
+	if existing, err := store.Reserve(ctx, "k1", "f3", 50*time.Millisecond); err != nil || existing != nil {
+		t.Fatalf("Reserve after the lease = %+v, %v, want the key reserved again", existing, err)
+	}
+
+	record := Record{Fingerprint: "f3", Completed: true, Status: 201, Header: http.Header{"X-Id": {"1"}}, Body: []byte("created")}
+	if err := store.Complete(ctx, "k1", record, time.Hour); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	wait(60 * time.Millisecond)
+	existing, err = store.Reserve(ctx, "k1", "f3", 50*time.Millisecond)
+	if err != nil || existing == nil || !existing.Completed || existing.Status != 201 || string(existing.Body) != "created" || existing.Header.Get("X-Id") != "1" {
+		t.Fatalf("Reserve after Complete = %+v, %v, want the completed record", existing, err)
+	}
+
+	if err := store.Release(ctx, "k1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if existing, err := store.Reserve(ctx, "k1", "f4", time.Hour); err != nil || existing != nil {
+		t.Errorf("Reserve after Release = %+v, %v, want the key reserved", existing, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(), time.Sleep)
+}
@@ -0,0 +1,151 @@
+package http_ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Result is the outcome of a rate limit check.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the limit is replenished.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key is allowed.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Store keeps the rate limiting state, each operation must be atomic for a key.
+type Store interface {
+	// TakeToken refills the bucket at rate tokens per second up to burst and takes a token if one is
+	// available, returning the tokens left.
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (tokens float64, allowed bool, err error)
+	// IncrementWindow counts a request in the current window unless the weighted count of the current and
+	// previous windows already reached limit, returning both counts.
+	IncrementWindow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (counts WindowCounts, allowed bool, err error)
+}
+
+// WindowCounts holds the requests counted in the previous and current fixed windows.
+type WindowCounts struct {
+	Previous int
+	Current  int
+}
+
+// TokenBucket allows bursts of up to burst requests, refilled at limit requests per period.
+type TokenBucket struct {
+	store Store
+	rate  float64
+	burst int
+	now   func() time.Time
+}
+
+// NewTokenBucket panics unless limit and period are positive, a zero rate never refilling the bucket.
+func NewTokenBucket(store Store, limit int, period time.Duration, burst int) *TokenBucket {
+	if limit <= 0 || period <= 0 {
+		panic(fmt.Sprintf("http_ratelimit: invalid token bucket rate %d per %s", limit, period))
+	}
+	if burst <= 0 {
+		burst = limit
+	}
+	return &TokenBucket{
+		store: store,
+		rate:  float64(limit) / period.Seconds(),
+		burst: burst,
+		now:   time.Now,
+	}
+}
+
+func (t *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	tokens, allowed, err := t.store.TakeToken(ctx, key, t.rate, t.burst, t.now())
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      t.burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((float64(t.burst) - tokens) / t.rate),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / t.rate)
+	}
+
+	return result, nil
+}
+
+// SlidingWindow allows limit requests in any window, approximated by weighting the previous fixed
+// window with the part of it still covered by the sliding one.
+type SlidingWindow struct {
+	store  Store
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewSlidingWindow panics unless limit and window are positive.
+func NewSlidingWindow(store Store, limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic(fmt.Sprintf("http_ratelimit: invalid sliding window of %d requests per %s", limit, window))
+	}
+	return &SlidingWindow{store: store, limit: limit, window: window, now: time.Now}
+}
+
+func (s *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	now := s.now()
+	counts, allowed, err := s.store.IncrementWindow(ctx, key, s.limit, s.window, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	elapsed := now.Sub(now.Truncate(s.window))
+	weight := float64(s.window-elapsed) / float64(s.window)
+	used := float64(counts.Previous)*weight + float64(counts.Current)
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      s.limit,
+		Remaining:  int(math.Max(0, math.Floor(float64(s.limit)-used))),
+		ResetAfter: s.window - elapsed,
+	}
+	if !allowed {
+		result.RetryAfter = s.retryAfter(counts, elapsed)
+	}
+
+	return result, nil
+}
+
+// retryAfter returns when the weighted count leaves room for one more request.
+func (s *SlidingWindow) retryAfter(counts WindowCounts, elapsed time.Duration) time.Duration {
+	window := float64(s.window)
+	room := float64(s.limit - 1)
+
+	// Room may appear later in the current window as the previous one fades out
+	if float64(counts.Current) <= room && counts.Previous > 0 {
+		at := window * (1 - (room-float64(counts.Current))/float64(counts.Previous))
+		return time.Duration(at) - elapsed
+	}
+
+	// Otherwise the current window becomes the previous one and has to fade out
+	untilNext := s.window - elapsed
+	if counts.Current == 0 {
+		return untilNext
+	}
+	at := math.Max(0, window*(1-room/float64(counts.Current)))
+	return untilNext + time.Duration(at)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
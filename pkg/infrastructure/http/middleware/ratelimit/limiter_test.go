@@ -0,0 +1,147 @@
+package http_ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// stores returns a MemoryStore and a RedisStore backed by miniredis.
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client, ""),
+	}
+}
+
+func allow(t *testing.T, limiter Limiter, key string) Result {
+	t.Helper()
+
+	result, err := limiter.Allow(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	return result
+}
+
+func TestTokenBucket(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			c := &clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+			limiter := NewTokenBucket(store, 2, time.Second, 4)
+			limiter.now = c.Now
+
+			for i := 3; i >= 0; i-- {
+				result := allow(t, limiter, "burst")
+				if !result.Allowed || result.Remaining != i || result.Limit != 4 {
+					t.Fatalf("request %d: %+v, want allowed with %d remaining", 4-i, result, i)
+				}
+			}
+
+			result := allow(t, limiter, "burst")
+			if result.Allowed {
+				t.Fatal("request past the burst allowed")
+			}
+			if result.RetryAfter != 500*time.Millisecond {
+				t.Errorf("RetryAfter = %s, want 500ms", result.RetryAfter)
+			}
+			if result.ResetAfter != 2*time.Second {
+				t.Errorf("ResetAfter = %s, want 2s", result.ResetAfter)
+			}
+
+			c.Advance(500 * time.Millisecond)
+			if result := allow(t, limiter, "burst"); !result.Allowed || result.Remaining != 0 {
+				t.Errorf("after refill: %+v, want allowed with 0 remaining", result)
+			}
+
+			if result := allow(t, limiter, "other"); !result.Allowed || result.Remaining != 3 {
+				t.Errorf("other key: %+v, want its own bucket", result)
+			}
+		})
+	}
+}
+
+func TestTokenBucketDefaultsBurstToLimit(t *testing.T) {
+	limiter := NewTokenBucket(NewMemoryStore(), 3, time.Minute, 0)
+	if result := allow(t, limiter, "key"); result.Limit != 3 || result.Remaining != 2 {
+		t.Errorf("%+v, want a burst of 3", result)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			c := &clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+			limiter := NewSlidingWindow(store, 4, time.Minute)
+			limiter.now = c.Now
+
+			for i := 3; i >= 0; i-- {
+				result := allow(t, limiter, "key")
+				if !result.Allowed || result.Remaining != i {
+					t.Fatalf("request %d: %+v, want allowed with %d remaining", 4-i, result, i)
+				}
+			}
+			result := allow(t, limiter, "key")
+			if result.Allowed {
+				t.Fatal("request past the limit allowed")
+			}
+			if result.RetryAfter != 75*time.Second {
+				t.Errorf("RetryAfter = %s, want 75s", result.RetryAfter)
+			}
+
+			// Half of the previous window still counts, as 2 requests
+			c.Advance(90 * time.Second)
+			for i := 0; i < 2; i++ {
+				if result := allow(t, limiter, "key"); !result.Allowed {
+					t.Fatalf("request %d in the next window limited", i+1)
+				}
+			}
+			if result := allow(t, limiter, "key"); result.Allowed {
+				t.Error("request past the weighted limit allowed")
+			}
+		})
+	}
+}
+
+func TestLimitersRejectInvalidRates(t *testing.T) {
+	for name, newLimiter := range map[string]func(){
+		"token bucket without limit":  func() { NewTokenBucket(NewMemoryStore(), 0, time.Second, 1) },
+		"token bucket without period": func() { NewTokenBucket(NewMemoryStore(), 1, 0, 1) },
+		"sliding window without limit": func() {
+			NewSlidingWindow(NewMemoryStore(), 0, time.Second)
+		},
+		"sliding window without window": func() {
+			NewSlidingWindow(NewMemoryStore(), 1, 0)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			newLimiter()
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package http_ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the rate limiting state in memory, it is only suited to single instance deployments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*windowCounter
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+type windowCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		windows:   make(map[string]*windowCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryStore) TakeToken(_ context.Context, key string, rate float64, burst int, now time.Time) (float64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updatedAt: now}
+		m.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.updatedAt = now
+	}
+	// The bucket is full again, and can be forgotten, once every token has been refilled
+	b.expiresAt = now.Add(secondsToDuration(float64(burst) / rate))
+
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+	b.tokens--
+
+	return b.tokens, true, nil
+}
+
+func (m *MemoryStore) IncrementWindow(_ context.Context, key string, limit int, window time.Duration, now time.Time) (WindowCounts, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	start := now.Truncate(window)
+	current := m.window(key, start)
+	previous := m.window(key, start.Add(-window))
+	counts := WindowCounts{Current: current.count, Previous: previous.count}
+
+	weight := float64(window-now.Sub(start)) / float64(window)
+	if float64(counts.Previous)*weight+float64(counts.Current)+1 > float64(limit) {
+		return counts, false, nil
+	}
+
+	current.count++
+	current.expiresAt = start.Add(2 * window)
+	m.windows[windowKey(key, start)] = current
+	counts.Current++
+
+	return counts, true, nil
+}
+
+func (m *MemoryStore) window(key string, start time.Time) *windowCounter {
+	if w, ok := m.windows[windowKey(key, start)]; ok {
+		return w
+	}
+	return &windowCounter{}
+}
+
+// sweep drops expired state, at most once per sweepInterval.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.After(b.expiresAt) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, w := range m.windows {
+		if now.After(w.expiresAt) {
+			delete(m.windows, key)
+		}
+	}
+}
+
+func windowKey(key string, start time.Time) string {
+	return key + ":" + strconv.FormatInt(start.UnixMilli(), 10)
+}
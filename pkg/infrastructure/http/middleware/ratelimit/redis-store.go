@@ -0,0 +1,103 @@
+package http_ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+if previous * ((window - elapsed) / window) + current + 1 > limit then
+	return {0, previous, current}
+end
+current = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, previous, current}
+`)
+
+// RedisStore keeps the rate limiting state in Redis so limits are shared by every instance.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "ratelimit"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (r *RedisStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (float64, bool, error) {
+	// Rates are given per millisecond so the script works with millisecond timestamps
+	res, err := tokenBucketScript.Run(ctx, r.client, []string{r.key(key, "tb")},
+		strconv.FormatFloat(rate/1000, 'f', -1, 64), burst, now.UnixMilli()).Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to run token bucket script: %w", err)
+	}
+
+	allowed, _ := res[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid token bucket state: %w", err)
+	}
+
+	return tokens, allowed == 1, nil
+}
+
+func (r *RedisStore) IncrementWindow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (WindowCounts, bool, error) {
+	start := now.Truncate(window)
+	keys := []string{
+		r.key(key, strconv.FormatInt(start.UnixMilli(), 10)),
+		r.key(key, strconv.FormatInt(start.Add(-window).UnixMilli(), 10)),
+	}
+
+	res, err := slidingWindowScript.Run(ctx, r.client, keys, limit, window.Milliseconds(), now.Sub(start).Milliseconds()).Slice()
+	if err != nil {
+		return WindowCounts{}, false, fmt.Errorf("failed to run sliding window script: %w", err)
+	}
+
+	allowed, _ := res[0].(int64)
+	previous, _ := res[1].(int64)
+	current, _ := res[2].(int64)
+
+	return WindowCounts{Previous: int(previous), Current: int(current)}, allowed == 1, nil
+}
+
+// key uses a hash tag so every key of a limit lands in the same cluster slot.
+func (r *RedisStore) key(key, suffix string) string {
+	return fmt.Sprintf("%s:{%s}:%s", r.prefix, key, suffix)
+}
@@ -0,0 +1,48 @@
+package http_middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// Recovery recovers from panics in the next handler, logging them and answering with ErrInternal.
+func Recovery(rw http_response.ResponseWriter, l logger.Logger) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// Let the server abort the connection as it would without the middleware
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				err, ok := rec.(error)
+				if !ok {
+					err = errors.New(fmt.Sprint(rec))
+				}
+
+				if l != nil {
+					l.Error(r.Context(), "recovered from panic", map[string]interface{}{
+						"error":      err.Error(),
+						"stack":      string(debug.Stack()),
+						"request_id": RequestIDFromContext(r.Context()),
+					})
+				}
+
+				// The panic is only logged, it may hold details that must not reach the client
+				rw.WriteErrorResponse(w, ErrInternal, ErrInternal.Status, ErrInternal)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package http_middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/logger"
+)
+
+type recordingLogger struct {
+	mu     sync.Mutex
+	errors []map[string]interface{}
+}
+
+func (l *recordingLogger) Debug(context.Context, string, map[string]interface{}) {}
+func (l *recordingLogger) Info(context.Context, string, map[string]interface{})  {}
+func (l *recordingLogger) Warn(context.Context, string, map[string]interface{})  {}
+
+func (l *recordingLogger) Error(_ context.Context, _ string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fields)
+}
+
+func (l *recordingLogger) WithField(context.Context, string, interface{}) logger.Logger {
+	return l
+}
+
+func TestRecoveryAnswersWithInternalError(t *testing.T) {
+	l := &recordingLogger{}
+	handler := RequestID("")(Recovery(http_response.NewJsonResponseWriter(), l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("secret details")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(rec.Body.String(), "secret details") {
+		t.Errorf("body %q leaks the panic", rec.Body.String())
+	}
+
+	if len(l.errors) != 1 {
+		t.Fatalf("logged %d errors, want 1", len(l.errors))
+	}
+	if got := l.errors[0]["error"]; got != "secret details" {
+		t.Errorf("logged error = %v, want secret details", got)
+	}
+	if got := l.errors[0]["request_id"]; got != "abc" {
+		t.Errorf("logged request ID = %v, want abc", got)
+	}
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	handler := Recovery(http_response.NewJsonResponseWriter(), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,43 @@
+package http_middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/requestid"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// RequestIDHeader is the header the request ID is read from and written to.
+const RequestIDHeader = requestid.Header
+
+// RequestID reuses the request ID sent in header, or generates one, and stores it in the request
+// context and the response headers. An empty header defaults to RequestIDHeader.
+func RequestID(header string) router.Middleware {
+	if header == "" {
+		header = RequestIDHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if id == "" {
+				id = requestid.New()
+			}
+
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx holding the request ID, see requestid.NewContext.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return requestid.NewContext(ctx, id)
+}
+
+// RequestIDFromContext returns the request ID stored by the RequestID middleware, if any, see
+// requestid.FromContext.
+func RequestIDFromContext(ctx context.Context) string {
+	return requestid.FromContext(ctx)
+}
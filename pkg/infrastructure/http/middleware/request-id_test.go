@@ -0,0 +1,46 @@
+package http_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDReusesHeader(t *testing.T) {
+	var fromContext string
+	handler := RequestID("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if fromContext != "abc" {
+		t.Errorf("context request ID = %q, want abc", fromContext)
+	}
+	if got := rec.Header().Get(RequestIDHeader); got != "abc" {
+		t.Errorf("response request ID = %q, want abc", got)
+	}
+}
+
+func TestRequestIDGeneratesOne(t *testing.T) {
+	var fromContext string
+	handler := RequestID("X-Correlation-ID")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = RequestIDFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(fromContext) != 32 {
+		t.Errorf("context request ID = %q, want 32 hex characters", fromContext)
+	}
+	if got := rec.Header().Get("X-Correlation-ID"); got != fromContext {
+		t.Errorf("response request ID = %q, want %q", got, fromContext)
+	}
+	if got := rec.Header().Get(RequestIDHeader); got != "" {
+		t.Errorf("default header = %q, want none", got)
+	}
+}
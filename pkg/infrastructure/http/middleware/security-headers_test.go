@@ -0,0 +1,61 @@
+package http_middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func serveSecurityHeaders(config SecurityHeadersConfig, secure bool) http.Header {
+	handler := SecurityHeaders(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if secure {
+		r.TLS = &tls.ConnectionState{}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Header()
+}
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+	want := http.Header{
+		"X-Content-Type-Options":     {"nosniff"},
+		"X-Frame-Options":            {"DENY"},
+		"Referrer-Policy":            {"no-referrer"},
+		"Content-Security-Policy":    {"default-src 'none'; frame-ancestors 'none'"},
+		"Cross-Origin-Opener-Policy": {"same-origin"},
+	}
+	if got := serveSecurityHeaders(DefaultSecurityHeadersConfig(), false); !reflect.DeepEqual(got, want) {
+		t.Errorf("headers = %v, want %v", got, want)
+	}
+
+	// Strict-Transport-Security is only sent over TLS
+	want.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+	if got := serveSecurityHeaders(DefaultSecurityHeadersConfig(), true); !reflect.DeepEqual(got, want) {
+		t.Errorf("TLS headers = %v, want %v", got, want)
+	}
+}
+
+func TestSecurityHeadersConfig(t *testing.T) {
+	config := SecurityHeadersConfig{
+		FrameOptions:      "SAMEORIGIN",
+		PermissionsPolicy: "camera=()",
+		HSTSMaxAge:        60,
+		HSTSPreload:       true,
+	}
+	want := http.Header{
+		"X-Frame-Options":           {"SAMEORIGIN"},
+		"Permissions-Policy":        {"camera=()"},
+		"Strict-Transport-Security": {"max-age=60; preload"},
+	}
+	if got := serveSecurityHeaders(config, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("headers = %v, want %v", got, want)
+	}
+
+	if got := serveSecurityHeaders(SecurityHeadersConfig{}, true); len(got) != 0 {
+		t.Errorf("headers = %v, want none with an empty config", got)
+	}
+}
@@ -0,0 +1,97 @@
+package http_middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+// Timeout cancels the request context after timeout and answers with ErrRequestTimeout if the next
+// handler hasn't finished by then. The response is buffered until the handler returns.
+func Timeout(timeout time.Duration, rw http_response.ResponseWriter) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicChan := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				dst := w.Header()
+				for k, v := range tw.header {
+					dst[k] = v
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				_, _ = w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				tw.timedOut = true
+				rw.WriteErrorResponse(w, ErrRequestTimeout, ErrRequestTimeout.Status, ErrRequestTimeout)
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response so it can be dropped when the request times out.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (t *timeoutWriter) Header() http.Header {
+	return t.header
+}
+
+func (t *timeoutWriter) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if t.status == 0 {
+		t.status = http.StatusOK
+	}
+	return t.body.Write(b)
+}
+
+func (t *timeoutWriter) WriteHeader(status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timedOut || t.status != 0 {
+		return
+	}
+	t.status = status
+}
@@ -0,0 +1,73 @@
+package http_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+)
+
+func TestTimeoutWritesBufferedResponse(t *testing.T) {
+	handler := Timeout(time.Second, http_response.NewJsonResponseWriter())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "done")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Header().Get("X-Handler"); got != "done" {
+		t.Errorf("X-Handler = %q, want done", got)
+	}
+	if got := rec.Body.String(); got != "created" {
+		t.Errorf("body = %q, want created", got)
+	}
+}
+
+func TestTimeoutAnswersWhenHandlerIsTooSlow(t *testing.T) {
+	canceled := make(chan struct{})
+	written := make(chan error, 1)
+	handler := Timeout(20*time.Millisecond, http_response.NewJsonResponseWriter())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(canceled)
+		// Wait for the timeout response before writing
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write([]byte("late"))
+		written <- err
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != ErrRequestTimeout.Status {
+		t.Errorf("status = %d, want %d", rec.Code, ErrRequestTimeout.Status)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context not canceled")
+	}
+	if err := <-written; err != http.ErrHandlerTimeout {
+		t.Errorf("late write error = %v, want %v", err, http.ErrHandlerTimeout)
+	}
+}
+
+func TestTimeoutPropagatesPanics(t *testing.T) {
+	handler := Timeout(time.Second, http_response.NewJsonResponseWriter())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want boom", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,231 @@
+package http_openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var wildcardParam = regexp.MustCompile(`\{([^{}/]+)\.\.\.\}`)
+
+// OperationSpec describes a route to document.
+type OperationSpec struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	// Request is the struct the request is bound into with http_request.Bind: `path` and `query` fields
+	// become parameters and the other JSON fields the body.
+	Request reflect.Type
+	// Response is the type written on success, SuccessStatus defaults to 200.
+	Response      reflect.Type
+	SuccessStatus int
+	// Security lists the security schemes accepted by the operation.
+	Security []string
+}
+
+// Generator builds an OpenAPI 3.1 document from operation specs.
+type Generator struct {
+	doc            *Document
+	componentTypes map[string]reflect.Type
+}
+
+func NewGenerator(info Info) *Generator {
+	return &Generator{
+		doc: &Document{
+			OpenAPI: "3.1.0",
+			Info:    info,
+			Paths:   make(map[string]*PathItem),
+			Components: Components{
+				Schemas: map[string]*Schema{
+					"Error": {Type: "object", Properties: map[string]*Schema{
+						"error":         {Description: "the error, with its fields when it is structured"},
+						"previousError": {Type: "string"},
+					}},
+				},
+			},
+		},
+		componentTypes: map[string]reflect.Type{"Error": nil},
+	}
+}
+
+// AddServer documents a server the API is reachable at.
+func (g *Generator) AddServer(server Server) {
+	g.doc.Servers = append(g.doc.Servers, server)
+}
+
+// AddSecurityScheme documents a security scheme operations can refer to by name.
+func (g *Generator) AddSecurityScheme(name string, scheme SecurityScheme) {
+	if g.doc.Components.SecuritySchemes == nil {
+		g.doc.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	g.doc.Components.SecuritySchemes[name] = &scheme
+}
+
+// AddOperation documents a route.
+func (g *Generator) AddOperation(spec OperationSpec) {
+	path := wildcardParam.ReplaceAllString(spec.Path, "{$1}")
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+
+	op := &Operation{
+		OperationID: spec.OperationID,
+		Summary:     spec.Summary,
+		Description: spec.Description,
+		Tags:        spec.Tags,
+		Responses:   make(map[string]*Response),
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(spec)
+	}
+	for _, name := range spec.Security {
+		op.Security = append(op.Security, map[string][]string{name: {}})
+	}
+
+	if spec.Request != nil {
+		g.addRequest(op, spec.Request)
+	}
+	for _, name := range pathParams(path) {
+		if !hasParameter(op, name, "path") {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	g.addResponses(op, spec)
+	(*item)[strings.ToLower(spec.Method)] = op
+}
+
+// Document returns the generated document.
+func (g *Generator) Document() *Document {
+	return g.doc
+}
+
+func (g *Generator) addRequest(op *Operation, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	g.addParameters(op, t)
+
+	body := g.structSchema(t, func(field reflect.StructField) bool {
+		_, isPath := field.Tag.Lookup("path")
+		_, isQuery := field.Tag.Lookup("query")
+		return isPath || isQuery
+	})
+	if len(body.Properties) == 0 {
+		return
+	}
+
+	op.RequestBody = &RequestBody{
+		Required: len(body.Required) > 0,
+		Content:  map[string]MediaType{"application/json": {Schema: body}},
+	}
+}
+
+func (g *Generator) addParameters(op *Operation, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			g.addParameters(op, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, in := range []string{"path", "query"} {
+			name, ok := field.Tag.Lookup(in)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+
+			schema := g.schemaFor(field.Type)
+			required := applyValidation(schema, field.Tag.Get("validate"))
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       in,
+				Required: required || in == "path",
+				Schema:   schema,
+			})
+		}
+	}
+}
+
+func (g *Generator) addResponses(op *Operation, spec OperationSpec) {
+	status := spec.SuccessStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := &Response{Description: http.StatusText(status)}
+	if spec.Response != nil && status != http.StatusNoContent {
+		success.Content = map[string]MediaType{"application/json": {Schema: g.schemaFor(spec.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	errorStatuses := []int{http.StatusInternalServerError}
+	if spec.Request != nil {
+		errorStatuses = append(errorStatuses, http.StatusBadRequest, http.StatusUnprocessableEntity)
+	}
+	if len(spec.Security) > 0 {
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	for _, s := range errorStatuses {
+		op.Responses[strconv.Itoa(s)] = &Response{
+			Description: http.StatusText(s),
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		}
+	}
+}
+
+func hasParameter(op *Operation, name, in string) bool {
+	for _, p := range op.Parameters {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, strings.Trim(segment, "{}"))
+		}
+	}
+	return params
+}
+
+// operationID derives an operation id from the request type name or, without one, the method and path.
+func operationID(spec OperationSpec) string {
+	if spec.Request != nil {
+		t := spec.Request
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Name() != "" {
+			return t.Name()
+		}
+	}
+
+	id := strings.ToLower(spec.Method)
+	for _, segment := range strings.Split(spec.Path, "/") {
+		segment = strings.Trim(segment, "{}.")
+		if segment == "" {
+			continue
+		}
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return id
+}
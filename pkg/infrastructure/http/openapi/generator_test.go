@@ -0,0 +1,115 @@
+package http_openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type updateOrder struct {
+	ID       string   `path:"id" json:"-"`
+	DryRun   bool     `query:"dry_run" json:"-"`
+	Tags     []string `query:"tag" json:"-" validate:"max=3"`
+	Customer string   `json:"customer" validate:"required,email"`
+	Quantity int      `json:"quantity" validate:"min=1,max=10"`
+	Note     string   `json:"note,omitempty"`
+}
+
+type order struct {
+	ID string `json:"id"`
+}
+
+// toJSON returns the JSON encoding of v, the form the generated documents are compared in.
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return string(data)
+}
+
+func responseStatuses(op *Operation) []string {
+	statuses := make([]string, 0, len(op.Responses))
+	for status := range op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+func TestGeneratorOperation(t *testing.T) {
+	g := NewGenerator(Info{Title: "Orders", Version: "1.0.0"})
+	g.AddSecurityScheme("bearer", SecurityScheme{Type: "http", Scheme: "bearer"})
+	g.AddOperation(OperationSpec{
+		Method:   http.MethodPut,
+		Path:     "/orders/{id}",
+		Summary:  "Update an order",
+		Request:  reflect.TypeOf(&updateOrder{}),
+		Response: reflect.TypeOf(order{}),
+		Security: []string{"bearer"},
+	})
+
+	op := (*g.Document().Paths["/orders/{id}"])["put"]
+	if op == nil {
+		t.Fatalf("paths = %s, want PUT /orders/{id}", toJSON(t, g.Document().Paths))
+	}
+	if op.OperationID != "updateOrder" || op.Summary != "Update an order" {
+		t.Errorf("operation %q %q", op.OperationID, op.Summary)
+	}
+
+	wantParameters := `[{"name":"id","in":"path","required":true,"schema":{"type":"string"}},` +
+		`{"name":"dry_run","in":"query","schema":{"type":"boolean"}},` +
+		`{"name":"tag","in":"query","schema":{"type":"array","items":{"type":"string"},"maxItems":3}}]`
+	if got := toJSON(t, op.Parameters); got != wantParameters {
+		t.Errorf("parameters = %s, want %s", got, wantParameters)
+	}
+
+	wantBody := `{"required":true,"content":{"application/json":{"schema":{"type":"object","properties":{` +
+		`"customer":{"type":"string","format":"email"},"note":{"type":"string"},` +
+		`"quantity":{"type":"integer","format":"int32","minimum":1,"maximum":10}},"required":["customer"]}}}}`
+	if got := toJSON(t, op.RequestBody); got != wantBody {
+		t.Errorf("request body = %s, want %s", got, wantBody)
+	}
+
+	if want := []string{"200", "400", "401", "403", "422", "500"}; !reflect.DeepEqual(responseStatuses(op), want) {
+		t.Errorf("responses = %v, want %v", responseStatuses(op), want)
+	}
+	if got := toJSON(t, op.Responses["200"].Content["application/json"].Schema); got != `{"$ref":"#/components/schemas/order"}` {
+		t.Errorf("success schema = %s", got)
+	}
+	if got := toJSON(t, op.Security); got != `[{"bearer":[]}]` {
+		t.Errorf("security = %s", got)
+	}
+	if g.Document().Components.SecuritySchemes["bearer"] == nil || g.Document().Components.Schemas["order"] == nil {
+		t.Errorf("components = %s, want the bearer scheme and the order schema", toJSON(t, g.Document().Components))
+	}
+}
+
+func TestGeneratorOperationWithoutRequest(t *testing.T) {
+	g := NewGenerator(Info{Title: "Files", Version: "1.0.0"})
+	g.AddOperation(OperationSpec{Method: http.MethodDelete, Path: "/files/{path...}", SuccessStatus: http.StatusNoContent, Response: reflect.TypeOf(order{})})
+	g.AddOperation(OperationSpec{Method: http.MethodGet, Path: "/files/{path...}"})
+
+	item := g.Document().Paths["/files/{path}"]
+	if item == nil || len(*item) != 2 {
+		t.Fatalf("paths = %s, want the wildcard documented as a path parameter", toJSON(t, g.Document().Paths))
+	}
+
+	op := (*item)["delete"]
+	if op.OperationID != "deleteFilesPath" || op.RequestBody != nil {
+		t.Errorf("operation %q with body %v", op.OperationID, op.RequestBody)
+	}
+	if got := toJSON(t, op.Parameters); got != `[{"name":"path","in":"path","required":true,"schema":{"type":"string"}}]` {
+		t.Errorf("parameters = %s", got)
+	}
+	if want := []string{"204", "500"}; !reflect.DeepEqual(responseStatuses(op), want) {
+		t.Errorf("responses = %v, want %v", responseStatuses(op), want)
+	}
+	if op.Responses["204"].Content != nil {
+		t.Error("204 response documented with content")
+	}
+}
@@ -0,0 +1,118 @@
+package http_openapi
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// Handler serves the document as JSON, it is encoded once.
+func Handler(doc *Document) http.HandlerFunc {
+	data, err := json.Marshal(doc)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not encode OpenAPI document: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}
+}
+
+// DefaultSwaggerUIAssets is the swagger-ui-dist release the Swagger UI page loads its assets from,
+// pinned so that the page doesn't change with the releases published on unpkg.
+const DefaultSwaggerUIAssets = "https://unpkg.com/swagger-ui-dist@5.18.2"
+
+// SwaggerUIOptions tunes the page served by SwaggerUIHandler.
+type SwaggerUIOptions struct {
+	assets       string
+	cssIntegrity string
+	jsIntegrity  string
+}
+
+// WithSwaggerUIAssets loads swagger-ui.css and swagger-ui-bundle.js from assets instead of
+// DefaultSwaggerUIAssets, e.g. "/docs/assets" when the application serves a copy of swagger-ui-dist
+// itself with ServeStatic.
+func WithSwaggerUIAssets(assets string) func(*SwaggerUIOptions) {
+	return func(o *SwaggerUIOptions) {
+		o.assets = strings.TrimSuffix(assets, "/")
+	}
+}
+
+// WithSwaggerUIIntegrity sets the Subresource Integrity hashes of swagger-ui.css and
+// swagger-ui-bundle.js (e.g. "sha384-..."), browsers refusing to run assets that don't match them.
+func WithSwaggerUIIntegrity(css, js string) func(*SwaggerUIOptions) {
+	return func(o *SwaggerUIOptions) {
+		o.cssIntegrity = css
+		o.jsIntegrity = js
+	}
+}
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Assets}}/swagger-ui.css"{{with .CSSIntegrity}} integrity="{{.}}"{{end}} crossorigin>
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets}}/swagger-ui-bundle.js"{{with .JSIntegrity}} integrity="{{.}}"{{end}} crossorigin></script>
+  <script nonce="{{.Nonce}}">
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: '#swagger-ui'});
+    };
+  </script>
+</body>
+</html>
+`))
+
+// swaggerUICSP allows the Swagger UI assets and the inline script with the nonce, the document being
+// fetched from the same origin.
+const swaggerUICSP = "default-src 'none'; script-src %[1]s 'nonce-%[2]s'; style-src %[1]s 'unsafe-inline'; " +
+	"img-src %[3]s; connect-src 'self'; frame-ancestors 'none'"
+
+// SwaggerUIHandler serves a Swagger UI page displaying the document at specURL, its assets being loaded
+// from DefaultSwaggerUIAssets unless WithSwaggerUIAssets is given. It replaces the
+// Content-Security-Policy set by the SecurityHeaders middleware, which would block the assets, with one
+// only allowing them.
+func SwaggerUIHandler(title, specURL string, options ...func(*SwaggerUIOptions)) http.HandlerFunc {
+	o := SwaggerUIOptions{assets: DefaultSwaggerUIAssets}
+	for _, option := range options {
+		option(&o)
+	}
+
+	// Assets served by the application are allowed as 'self', the others by the path of their release
+	source, images := "'self'", "'self' data:"
+	if !strings.HasPrefix(o.assets, "/") || strings.HasPrefix(o.assets, "//") {
+		source = o.assets + "/"
+		images += " " + source
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		nonce := newNonce()
+		w.Header().Set("Content-Security-Policy", fmt.Sprintf(swaggerUICSP, source, nonce, images))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = swaggerUITemplate.Execute(w, map[string]string{
+			"Title":        title,
+			"SpecURL":      specURL,
+			"Nonce":        nonce,
+			"Assets":       o.assets,
+			"CSSIntegrity": o.cssIntegrity,
+			"JSIntegrity":  o.jsIntegrity,
+		})
+	}
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,72 @@
+package http_openapi
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler(NewGenerator(Info{Title: "Orders", Version: "1.0.0"}).Document())(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if w.Header().Get("Content-Type") != "application/json" || !strings.Contains(w.Body.String(), `"openapi":"3.1.0"`) {
+		t.Errorf("Handler = %s %s, want the JSON document", w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
+var nonceAttribute = regexp.MustCompile(`nonce="([^"]+)"`)
+
+func serveSwaggerUI(t *testing.T, options ...func(*SwaggerUIOptions)) (csp, page string) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	SwaggerUIHandler("Orders <API>", "/openapi.json", options...)(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	page = w.Body.String()
+	csp = w.Header().Get("Content-Security-Policy")
+	match := nonceAttribute.FindStringSubmatch(page)
+	if match == nil || !strings.Contains(csp, "'nonce-"+html.UnescapeString(match[1])+"'") {
+		t.Errorf("inline script nonce %v not allowed by %q", match, csp)
+	}
+	if !strings.Contains(page, "<title>Orders &lt;API&gt;</title>") {
+		t.Errorf("page title not escaped in %s", page)
+	}
+	return csp, page
+}
+
+func TestSwaggerUIHandlerLoadsPinnedAssets(t *testing.T) {
+	csp, page := serveSwaggerUI(t)
+
+	for _, asset := range []string{DefaultSwaggerUIAssets + "/swagger-ui.css", DefaultSwaggerUIAssets + "/swagger-ui-bundle.js"} {
+		if !strings.Contains(page, `"`+asset+`"`) {
+			t.Errorf("page doesn't load %s", asset)
+		}
+	}
+	if !regexp.MustCompile(`@\d+\.\d+\.\d+$`).MatchString(DefaultSwaggerUIAssets) {
+		t.Errorf("DefaultSwaggerUIAssets = %s, want an exact release", DefaultSwaggerUIAssets)
+	}
+	if strings.Contains(page, "integrity=") {
+		t.Error("page has integrity attributes without WithSwaggerUIIntegrity")
+	}
+
+	// Only the pinned release is trusted, not the whole CDN
+	if !strings.Contains(csp, "script-src "+DefaultSwaggerUIAssets+"/ 'nonce-") || strings.Contains(csp, "https://unpkg.com ") {
+		t.Errorf("Content-Security-Policy = %q, want the scripts limited to the pinned release", csp)
+	}
+}
+
+func TestSwaggerUIHandlerSelfServedAssets(t *testing.T) {
+	csp, page := serveSwaggerUI(t, WithSwaggerUIAssets("/docs/assets/"), WithSwaggerUIIntegrity("sha384-css", "sha384-js"))
+
+	if !strings.Contains(page, `href="/docs/assets/swagger-ui.css" integrity="sha384-css"`) ||
+		!strings.Contains(page, `src="/docs/assets/swagger-ui-bundle.js" integrity="sha384-js"`) {
+		t.Errorf("page doesn't load the assets with their integrity: %s", page)
+	}
+	if !strings.Contains(csp, "script-src 'self' 'nonce-") || !strings.Contains(csp, "img-src 'self' data:;") || strings.Contains(csp, "unpkg") {
+		t.Errorf("Content-Security-Policy = %q, want the assets allowed from the same origin only", csp)
+	}
+}
@@ -0,0 +1,215 @@
+package http_openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// schemaFor returns the schema of t, registering named structs as components.
+func (g *Generator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &Schema{Type: "string"}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Types with custom marshalling, such as json.RawMessage, can hold anything
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, nil)
+		}
+		return g.componentRef(t)
+	default:
+		// Interfaces and types with custom marshalling can hold anything
+		return &Schema{}
+	}
+}
+
+// componentRef registers the named struct t in the components and returns a reference to it.
+func (g *Generator) componentRef(t reflect.Type) *Schema {
+	if reflect.PointerTo(t).Implements(jsonMarshalerType) || t.Implements(jsonMarshalerType) {
+		return &Schema{}
+	}
+
+	name := g.componentName(t)
+	if _, ok := g.doc.Components.Schemas[name]; !ok {
+		// Reserve the name first so recursive types end up referencing themselves
+		g.doc.Components.Schemas[name] = &Schema{}
+		*g.doc.Components.Schemas[name] = *g.structSchema(t, nil)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName returns a unique component name for t, qualifying it with its package on collisions.
+func (g *Generator) componentName(t reflect.Type) string {
+	for name, seen := range g.componentTypes {
+		if seen == t {
+			return name
+		}
+	}
+
+	name := t.Name()
+	if _, taken := g.componentTypes[name]; taken {
+		pkg := t.PkgPath()
+		name = strings.ReplaceAll(pkg[strings.LastIndex(pkg, "/")+1:], "_", "") + "." + name
+	}
+	g.componentTypes[name] = t
+
+	return name
+}
+
+// structSchema returns the object schema of the JSON fields of t, skipping the fields accepted by skip.
+func (g *Generator) structSchema(t reflect.Type, skip func(reflect.StructField) bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t, skip)
+	return schema
+}
+
+func (g *Generator) addFields(schema *Schema, t reflect.Type, skip func(reflect.StructField) bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if skip != nil && skip(field) {
+			continue
+		}
+
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(schema, embedded, skip)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := g.schemaFor(field.Type)
+		schema.Properties[name] = fieldSchema
+		if applyValidation(fieldSchema, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// jsonName returns the JSON name of field, empty when not renamed, ok is false for fields that aren't serialized.
+func jsonName(field reflect.StructField) (name string, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	return strings.Split(tag, ",")[0], true
+}
+
+// applyValidation translates the validate tag rules to schema keywords, reporting whether the field is required.
+func applyValidation(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, v)
+			}
+		case "min", "gte":
+			setBound(schema, param, true)
+		case "max", "lte":
+			setBound(schema, param, false)
+		case "len":
+			setBound(schema, param, true)
+			setBound(schema, param, false)
+		}
+	}
+
+	return required
+}
+
+func setBound(schema *Schema, param string, lower bool) {
+	switch schema.Type {
+	case "string", "array":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		switch {
+		case schema.Type == "string" && lower:
+			schema.MinLength = &n
+		case schema.Type == "string":
+			schema.MaxLength = &n
+		case lower:
+			schema.MinItems = &n
+		default:
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if lower {
+			schema.Minimum = &f
+		} else {
+			schema.Maximum = &f
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package http_openapi
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type category struct {
+	Name   string    `json:"name" validate:"oneof=books games"`
+	Parent *category `json:"parent,omitempty"`
+}
+
+type audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type customer struct {
+	audit
+	IP       net.IP            `json:"ip"`
+	Name     string            `json:"name" validate:"required,min=2,max=50"`
+	Score    float64           `json:"score"`
+	Visits   int64             `json:"visits"`
+	Timeout  time.Duration     `json:"timeout"`
+	Avatar   []byte            `json:"avatar"`
+	Address  address           `json:"address"`
+	Labels   map[string]string `json:"labels"`
+	Extra    interface{}       `json:"extra"`
+	Raw      json.RawMessage   `json:"raw"`
+	Password string            `json:"-"`
+	internal string
+	Untagged string
+	Inline   struct {
+		Code string `json:"code" validate:"len=2"`
+	} `json:"inline"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	g := NewGenerator(Info{})
+
+	if got := toJSON(t, g.schemaFor(reflect.TypeOf(&customer{}))); got != `{"$ref":"#/components/schemas/customer"}` {
+		t.Fatalf("schemaFor = %s, want a component reference", got)
+	}
+
+	want := `{"type":"object","properties":{` +
+		`"Untagged":{"type":"string"},` +
+		`"address":{"$ref":"#/components/schemas/address"},` +
+		`"avatar":{"type":"string","format":"byte"},` +
+		`"created_at":{"type":"string","format":"date-time"},` +
+		`"extra":{},` +
+		`"inline":{"type":"object","properties":{"code":{"type":"string","minLength":2,"maxLength":2}}},` +
+		`"ip":{"type":"string"},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"name":{"type":"string","minLength":2,"maxLength":50},` +
+		`"raw":{},` +
+		`"score":{"type":"number","format":"double"},` +
+		`"timeout":{"type":"integer","format":"int64","description":"duration in nanoseconds"},` +
+		`"visits":{"type":"integer","format":"int64"}},` +
+		`"required":["name"]}`
+	if got := toJSON(t, g.Document().Components.Schemas["customer"]); got != want {
+		t.Errorf("customer schema =\n%s\nwant\n%s", got, want)
+	}
+	if got := toJSON(t, g.Document().Components.Schemas["address"]); got != `{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}` {
+		t.Errorf("address schema = %s", got)
+	}
+}
+
+func TestSchemaForRecursiveTypes(t *testing.T) {
+	g := NewGenerator(Info{})
+	g.schemaFor(reflect.TypeOf(category{}))
+
+	want := `{"type":"object","properties":{"name":{"type":"string","enum":["books","games"]},"parent":{"$ref":"#/components/schemas/category"}}}`
+	if got := toJSON(t, g.Document().Components.Schemas["category"]); got != want {
+		t.Errorf("category schema = %s, want %s", got, want)
+	}
+}
+
+// Error collides with the Error component of every document.
+type Error struct {
+	Code string `json:"code"`
+}
+
+func TestSchemaForNameCollisions(t *testing.T) {
+	g := NewGenerator(Info{})
+
+	ref := g.schemaFor(reflect.TypeOf(Error{}))
+	if ref.Ref != "#/components/schemas/openapi.Error" {
+		t.Errorf("schemaFor = %s, want the name qualified by the package", ref.Ref)
+	}
+	if again := g.schemaFor(reflect.TypeOf(&Error{})); again.Ref != ref.Ref {
+		t.Errorf("schemaFor of the same type = %s, want %s", again.Ref, ref.Ref)
+	}
+	if g.Document().Components.Schemas["Error"].Properties["previousError"] == nil {
+		t.Error("the Error component of the document was replaced")
+	}
+}
@@ -0,0 +1,214 @@
+package http_request
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	pathTag  = "path"
+	queryTag = "query"
+)
+
+// Validatable is implemented by DTOs that need validation rules that can't be expressed with tags.
+type Validatable interface {
+	Validate() error
+}
+
+var (
+	validate     *validator.Validate
+	validateOnce sync.Once
+)
+
+// Bind fills dst from the request path params (`path` tag), query params (`query` tag)
+// and JSON body, then validates it. dst must be a pointer to a struct.
+func Bind(r *http.Request, dst interface{}) error {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return NewBindingError("only pointer to structs can be bound", nil)
+	}
+
+	if err := bindBody(r, dst); err != nil {
+		return err
+	}
+
+	// Path and query params are bound after the body so they can't be overridden by it
+	if err := bindValues(value.Elem(), pathTag, r.PathValue); err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	lookupQuery := func(name string) string { return strings.Join(query[name], ",") }
+	if err := bindValues(value.Elem(), queryTag, lookupQuery); err != nil {
+		return err
+	}
+
+	return Validate(dst)
+}
+
+// Validate runs the `validate` tag rules on dst and, if it implements Validatable, its Validate method.
+func Validate(dst interface{}) error {
+	if err := structValidator().Struct(dst); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			return newValidationErrorFromValidator(validationErrors)
+		}
+
+		var invalidValidationError *validator.InvalidValidationError
+		if !errors.As(err, &invalidValidationError) {
+			return err
+		}
+	}
+
+	if v, ok := dst.(Validatable); ok {
+		if err := v.Validate(); err != nil {
+			var validationError *ValidationError
+			if errors.As(err, &validationError) {
+				return validationError
+			}
+			return NewValidationError(FieldError{Message: err.Error()})
+		}
+	}
+
+	return nil
+}
+
+func bindBody(r *http.Request, dst interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return NewBindingError("invalid JSON body", err)
+	}
+
+	return nil
+}
+
+func bindValues(v reflect.Value, tag string, lookup func(string) string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindValues(v.Field(i), tag, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := field.Tag.Lookup(tag)
+		if !ok || name == "" || name == "-" {
+			continue
+		}
+
+		raw := lookup(name)
+		if raw == "" {
+			continue
+		}
+
+		if err := setValue(v.Field(i), raw); err != nil {
+			return NewValidationError(FieldError{
+				Field:   name,
+				Tag:     "type",
+				Message: fmt.Sprintf("%s must be a valid %s", name, field.Type.String()),
+			})
+		}
+	}
+
+	return nil
+}
+
+func setValue(field reflect.Value, raw string) error {
+	if field.CanAddr() {
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(raw))
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(elem)
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		parts := strings.Split(raw, ",")
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return fmt.Errorf("unsupported kind %s", field.Kind())
+	}
+
+	return nil
+}
+
+// structValidator returns the shared validator, reporting field names as they are seen by clients.
+func structValidator() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New(validator.WithRequiredStructEnabled())
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", pathTag, queryTag} {
+				name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+				if name == "-" {
+					continue
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	})
+
+	return validate
+}
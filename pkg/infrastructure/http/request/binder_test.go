@@ -0,0 +1,168 @@
+package http_request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type createOrder struct {
+	ID       string   `path:"id" json:"id"`
+	Page     int      `query:"page" json:"page"`
+	Tags     []string `query:"tag" json:"tags"`
+	Express  *bool    `query:"express" json:"express"`
+	Customer string   `json:"customer" validate:"required,email"`
+	Quantity int      `json:"quantity" validate:"min=1,max=10"`
+}
+
+// paidOrder rejects orders without amount through the Validatable hook.
+type paidOrder struct {
+	Amount int `json:"amount"`
+	err    error
+}
+
+func (o *paidOrder) Validate() error {
+	if o.Amount == 0 {
+		return o.err
+	}
+	return nil
+}
+
+func newBindRequest(target, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.SetPathValue("id", "o1")
+	return r
+}
+
+func TestBindSources(t *testing.T) {
+	r := newBindRequest("/orders/o1?page=2&tag=a&tag=b,c&express=true",
+		`{"id":"body","page":7,"tags":["body"],"customer":"ada@example.com","quantity":3}`)
+
+	var order createOrder
+	if err := Bind(r, &order); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+
+	// Path and query params override the body
+	express := true
+	want := createOrder{ID: "o1", Page: 2, Tags: []string{"a", "b", "c"}, Express: &express, Customer: "ada@example.com", Quantity: 3}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Bind = %+v, want %+v", order, want)
+	}
+}
+
+func TestBindWithoutBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/orders?page=2", nil)
+
+	var order createOrder
+	err := Bind(r, &order)
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) || order.Page != 2 {
+		t.Errorf("Bind = %v with page %d, want a ValidationError with page 2", err, order.Page)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		dst    interface{}
+		status int
+		fields []FieldError
+	}{
+		{
+			name:   "invalid JSON",
+			target: "/orders/o1",
+			body:   `{"customer":`,
+			dst:    &createOrder{},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not a pointer to a struct",
+			target: "/orders/o1",
+			dst:    createOrder{},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "query type",
+			target: "/orders/o1?page=two",
+			body:   `{"customer":"ada@example.com","quantity":1}`,
+			dst:    &createOrder{},
+			status: http.StatusUnprocessableEntity,
+			fields: []FieldError{{Field: "page", Tag: "type", Message: "page must be a valid int"}},
+		},
+		{
+			name:   "pointer query type",
+			target: "/orders/o1?express=maybe",
+			body:   `{"customer":"ada@example.com","quantity":1}`,
+			dst:    &createOrder{},
+			status: http.StatusUnprocessableEntity,
+			fields: []FieldError{{Field: "express", Tag: "type", Message: "express must be a valid *bool"}},
+		},
+		{
+			name:   "validation rules",
+			target: "/orders/o1",
+			body:   `{"customer":"ada","quantity":11}`,
+			dst:    &createOrder{},
+			status: http.StatusUnprocessableEntity,
+			fields: []FieldError{
+				{Field: "customer", Tag: "email", Message: "customer must be a valid email"},
+				{Field: "quantity", Tag: "max", Message: "quantity must be at most 10"},
+			},
+		},
+		{
+			name:   "required",
+			target: "/orders/o1",
+			body:   `{"quantity":0}`,
+			dst:    &createOrder{},
+			status: http.StatusUnprocessableEntity,
+			fields: []FieldError{
+				{Field: "customer", Tag: "required", Message: "customer is required"},
+				{Field: "quantity", Tag: "min", Message: "quantity must be at least 1"},
+			},
+		},
+		{
+			name:   "Validatable error",
+			target: "/orders/o1",
+			body:   `{}`,
+			dst:    &paidOrder{err: errors.New("amount is required")},
+			status: http.StatusUnprocessableEntity,
+			fields: []FieldError{{Message: "amount is required"}},
+		},
+		{
+			name:   "Validatable ValidationError",
+			target: "/orders/o1",
+			body:   `{}`,
+			dst:    &paidOrder{err: NewValidationError(FieldError{Field: "amount", Tag: "required", Message: "amount is required"})},
+			status: http.StatusUnprocessableEntity,
+			fields: []FieldError{{Field: "amount", Tag: "required", Message: "amount is required"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Bind(newBindRequest(tt.target, tt.body), tt.dst)
+			if status := StatusFromError(err); status != tt.status {
+				t.Fatalf("Bind = %v with status %d, want status %d", err, status, tt.status)
+			}
+
+			var validationError *ValidationError
+			if errors.As(err, &validationError) && !reflect.DeepEqual(validationError.Fields, tt.fields) {
+				t.Errorf("Fields = %+v, want %+v", validationError.Fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestBindBodyTooLarge(t *testing.T) {
+	r := newBindRequest("/orders/o1", `{"customer":"ada@example.com","quantity":1}`)
+	r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 8)
+
+	err := Bind(r, &createOrder{})
+	if status := StatusFromError(err); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Bind = %v with status %d, want %d", err, status, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,80 @@
+package http_request
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	application_command "github.com/thebranchcrafter/go-kit/pkg/application/command"
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+)
+
+// CommandPointer constrains PC to be a pointer to C that is also a command, which is what the CommandBus expects.
+type CommandPointer[C any] interface {
+	*C
+	application.Command
+}
+
+// DispatchCommand returns a handler that binds the request into a new C, validates it and dispatches it
+// through the bus, answering with successStatus and no body when the command is handled.
+func DispatchCommand[C any, PC CommandPointer[C]](
+	bus application_command.Bus,
+	rw http_response.ResponseWriter,
+	successStatus int,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cmd := PC(new(C))
+		if err := Bind(r, cmd); err != nil {
+			WriteError(w, rw, err)
+			return
+		}
+
+		if err := bus.Dispatch(r.Context(), cmd); err != nil {
+			WriteError(w, rw, err)
+			return
+		}
+
+		w.WriteHeader(successStatus)
+	}
+}
+
+// WriteError writes err through the ResponseWriter with the status returned by StatusFromError.
+func WriteError(w http.ResponseWriter, rw http_response.ResponseWriter, err error) {
+	rw.WriteErrorResponse(w, err, StatusFromError(err), err)
+}
+
+// StatusFromError maps the errors produced while binding and dispatching to an HTTP status.
+func StatusFromError(err error) int {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		return http.StatusUnprocessableEntity
+	}
+
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	var bindingError *BindingError
+	if errors.As(err, &bindingError) {
+		return http.StatusBadRequest
+	}
+
+	var unauthenticated *application_auth.Unauthenticated
+	if errors.As(err, &unauthenticated) {
+		return http.StatusUnauthorized
+	}
+
+	var forbidden *application_auth.Forbidden
+	if errors.As(err, &forbidden) {
+		return http.StatusForbidden
+	}
+
+	var invalidDto application.InvalidDto
+	if errors.As(err, &invalidDto) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
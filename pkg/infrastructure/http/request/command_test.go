@@ -0,0 +1,35 @@
+package http_request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+)
+
+func TestStatusFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"validation", NewValidationError(FieldError{Field: "name", Tag: "required"}), http.StatusUnprocessableEntity},
+		{"max bytes", NewBindingError("invalid JSON body", &http.MaxBytesError{Limit: 8}), http.StatusRequestEntityTooLarge},
+		{"binding", NewBindingError("invalid JSON body", errors.New("unexpected EOF")), http.StatusBadRequest},
+		{"unauthenticated", application_auth.NewUnauthenticated("missing token"), http.StatusUnauthorized},
+		{"forbidden", application_auth.NewForbidden("forbidden", "users:write"), http.StatusForbidden},
+		{"invalid dto", application.NewInvalidDto("invalid payload"), http.StatusBadRequest},
+		{"wrapped", fmt.Errorf("dispatching: %w", application_auth.NewForbidden("forbidden")), http.StatusForbidden},
+		{"other", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := StatusFromError(tt.err); status != tt.status {
+				t.Errorf("StatusFromError(%v) = %d, want %d", tt.err, status, tt.status)
+			}
+		})
+	}
+}
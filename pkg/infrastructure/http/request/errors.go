@@ -0,0 +1,56 @@
+package http_request
+
+import (
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+)
+
+// FieldError, ValidationError and BindingError are defined by the application package so the
+// transports other than HTTP can return them too.
+type (
+	FieldError      = application.FieldError
+	ValidationError = application.ValidationError
+	BindingError    = application.BindingError
+)
+
+func NewValidationError(fields ...FieldError) *ValidationError {
+	return application.NewValidationError(fields...)
+}
+
+func NewBindingError(message string, err error) *BindingError {
+	return application.NewBindingError(message, err)
+}
+
+func newValidationErrorFromValidator(errs validator.ValidationErrors) *ValidationError {
+	fields := make([]FieldError, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, FieldError{
+			Field:   e.Field(),
+			Tag:     e.Tag(),
+			Message: fieldErrorMessage(e),
+		})
+	}
+	return NewValidationError(fields...)
+}
+
+func fieldErrorMessage(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", e.Field())
+	case "email":
+		return fmt.Sprintf("%s must be a valid email", e.Field())
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", e.Field(), e.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", e.Field(), e.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", e.Field(), e.Param())
+	default:
+		if e.Param() != "" {
+			return fmt.Sprintf("%s failed on %s=%s", e.Field(), e.Tag(), e.Param())
+		}
+		return fmt.Sprintf("%s failed on %s", e.Field(), e.Tag())
+	}
+}
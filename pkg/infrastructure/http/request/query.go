@@ -0,0 +1,39 @@
+package http_request
+
+import (
+	"net/http"
+
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
+	http_response "github.com/thebranchcrafter/go-kit/pkg/infrastructure/http/response"
+)
+
+// QueryPointer constrains PQ to be a pointer to Q that is also a query.
+type QueryPointer[Q any] interface {
+	*Q
+	application.Query
+}
+
+// AskQuery returns a handler that binds the request into a new Q, validates it and asks the bus,
+// writing the handler result with successStatus.
+func AskQuery[Q any, PQ QueryPointer[Q]](
+	bus application_query.Bus,
+	rw http_response.ResponseWriter,
+	successStatus int,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := PQ(new(Q))
+		if err := Bind(r, query); err != nil {
+			WriteError(w, rw, err)
+			return
+		}
+
+		response, err := bus.Ask(r.Context(), query)
+		if err != nil {
+			WriteError(w, rw, err)
+			return
+		}
+
+		rw.WriteResponse(w, response, successStatus)
+	}
+}
@@ -0,0 +1,147 @@
+package infrastructure_query
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
+	infrastructure_bus "github.com/thebranchcrafter/go-kit/pkg/infrastructure/bus"
+)
+
+var _ application_query.Bus = (*NATSQueryBus)(nil)
+
+// NATSQueryBus asks queries to other processes through NATS request-reply. Queries registered in it are
+// handled by the local bus and served on the "<prefix>.<query Id>" subject, the others are sent to that
+// subject and answered by the process registering them.
+//
+// Results of remote queries are returned as json.RawMessage, AskAs decodes them.
+type NATSQueryBus struct {
+	conn  *nats.Conn
+	local application_query.Bus
+	cfg   infrastructure_bus.NATSConfig
+	lock  sync.RWMutex
+	types map[string]reflect.Type
+	subs  []*nats.Subscription
+}
+
+// NewNATSQueryBus creates a NATSQueryBus handling registered queries with local. The subject prefix
+// defaults to "queries".
+func NewNATSQueryBus(conn *nats.Conn, local application_query.Bus, cfg infrastructure_bus.NATSConfig) *NATSQueryBus {
+	return &NATSQueryBus{
+		conn:  conn,
+		local: local,
+		cfg:   cfg.WithDefaults("queries"),
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// RegisterQuery registers the handler in the local bus and serves the query to the other processes.
+// Queries are decoded from JSON into a new value of the type of q, which must be a pointer to a struct.
+func (bus *NATSQueryBus) RegisterQuery(q application.Query, handler application_query.QueryHandler) error {
+	t := reflect.TypeOf(q)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return application.NewInvalidDto("query must be a pointer to a struct")
+	}
+
+	if err := bus.local.RegisterQuery(q, handler); err != nil {
+		return err
+	}
+
+	sub, err := infrastructure_bus.Subscribe(bus.conn, bus.cfg, q.Id(), bus.handler(t))
+	if err != nil {
+		return err
+	}
+
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	bus.types[q.Id()] = t
+	bus.subs = append(bus.subs, sub)
+
+	return nil
+}
+
+// Ask handles the query locally when registered in this bus, otherwise it is sent to the remote handler
+// and its JSON encoded result is returned as json.RawMessage, or nil when the handler returned none.
+func (bus *NATSQueryBus) Ask(ctx context.Context, q application.Query) (interface{}, error) {
+	if bus.isLocal(q) {
+		return bus.local.Ask(ctx, q)
+	}
+
+	result, err := infrastructure_bus.Request(ctx, bus.conn, bus.cfg, q.Id(), q)
+	if errors.Is(err, infrastructure_bus.ErrNoHandler) {
+		return nil, application_query.NewQueryNotRegistered("Query not registered", q.Id())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A nil json.RawMessage would be returned as a non nil interface
+	if result == nil {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// Close stops serving the registered queries.
+func (bus *NATSQueryBus) Close() error {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	var errs []error
+	for _, sub := range bus.subs {
+		errs = append(errs, sub.Unsubscribe())
+	}
+	bus.subs = nil
+
+	return errors.Join(errs...)
+}
+
+func (bus *NATSQueryBus) isLocal(q application.Query) bool {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+
+	_, ok := bus.types[q.Id()]
+	return ok
+}
+
+// handler decodes the incoming queries into new values of t and asks them to the local bus.
+func (bus *NATSQueryBus) handler(t reflect.Type) infrastructure_bus.HandlerFunc {
+	return func(ctx context.Context, msg *nats.Msg) (interface{}, error) {
+		q := reflect.New(t.Elem()).Interface().(application.Query)
+		if err := json.Unmarshal(msg.Data, q); err != nil {
+			return nil, application.NewInvalidDto("invalid query payload: " + err.Error())
+		}
+
+		return bus.local.Ask(ctx, q)
+	}
+}
+
+// AskAs asks q to the bus and returns its result as T, decoding the JSON results of remote queries.
+func AskAs[T any](ctx context.Context, bus application_query.Bus, q application.Query) (T, error) {
+	var result T
+
+	response, err := bus.Ask(ctx, q)
+	if err != nil {
+		return result, err
+	}
+
+	switch r := response.(type) {
+	case T:
+		return r, nil
+	case json.RawMessage:
+		if err := json.Unmarshal(r, &result); err != nil {
+			return result, err
+		}
+		return result, nil
+	case nil:
+		return result, nil
+	default:
+		return result, fmt.Errorf("unexpected query result type %T", response)
+	}
+}
@@ -0,0 +1,133 @@
+package infrastructure_query
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/thebranchcrafter/go-kit/pkg/application"
+	application_auth "github.com/thebranchcrafter/go-kit/pkg/application/auth"
+	application_query "github.com/thebranchcrafter/go-kit/pkg/application/query"
+	infrastructure_bus "github.com/thebranchcrafter/go-kit/pkg/infrastructure/bus"
+)
+
+type getUser struct {
+	ID string `json:"id"`
+}
+
+func (q *getUser) Id() string {
+	return "get_user"
+}
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type queryHandlerFunc func(ctx context.Context, q application.Query) (interface{}, error)
+
+func (f queryHandlerFunc) Handle(ctx context.Context, q application.Query) (interface{}, error) {
+	return f(ctx, q)
+}
+
+// newNATSQueryBuses returns a bus serving get_user with handler and a bus asking it from another connection.
+func newNATSQueryBuses(t *testing.T, handler queryHandlerFunc) (serving, client *NATSQueryBus) {
+	t.Helper()
+
+	url := runNATS(t)
+	serving = NewNATSQueryBus(connect(t, url), application_query.InitQueryBus(nil), infrastructure_bus.NATSConfig{})
+	if err := serving.RegisterQuery(&getUser{}, handler); err != nil {
+		t.Fatalf("RegisterQuery: %v", err)
+	}
+	t.Cleanup(func() { _ = serving.Close() })
+
+	client = NewNATSQueryBus(connect(t, url), application_query.InitQueryBus(nil), infrastructure_bus.NATSConfig{})
+	return serving, client
+}
+
+func runNATS(t *testing.T) string {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+
+	return s.ClientURL()
+}
+
+func connect(t *testing.T, url string) *nats.Conn {
+	t.Helper()
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("failed to connect to nats: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+func TestNATSQueryBusAsksRemoteHandler(t *testing.T) {
+	serving, client := newNATSQueryBuses(t, func(_ context.Context, q application.Query) (interface{}, error) {
+		return &user{ID: q.(*getUser).ID, Name: "gopher"}, nil
+	})
+
+	got, err := AskAs[user](context.Background(), client, &getUser{ID: "u1"})
+	if err != nil {
+		t.Fatalf("AskAs: %v", err)
+	}
+	if got != (user{ID: "u1", Name: "gopher"}) {
+		t.Errorf("AskAs = %+v", got)
+	}
+
+	// The serving bus answers its own queries without going through NATS
+	local, err := AskAs[*user](context.Background(), serving, &getUser{ID: "u2"})
+	if err != nil || local.ID != "u2" {
+		t.Errorf("local AskAs = %+v, %v", local, err)
+	}
+}
+
+func TestNATSQueryBusNilResult(t *testing.T) {
+	_, client := newNATSQueryBuses(t, func(context.Context, application.Query) (interface{}, error) {
+		return nil, nil
+	})
+
+	result, err := client.Ask(context.Background(), &getUser{ID: "u1"})
+	if err != nil || result != nil {
+		t.Fatalf("Ask = %#v, %v, want nil, nil", result, err)
+	}
+
+	got, err := AskAs[*user](context.Background(), client, &getUser{ID: "u1"})
+	if err != nil || got != nil {
+		t.Errorf("AskAs = %+v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestNATSQueryBusRemoteError(t *testing.T) {
+	_, client := newNATSQueryBuses(t, func(context.Context, application.Query) (interface{}, error) {
+		return nil, application_auth.NewForbidden("forbidden", "users:read")
+	})
+
+	var forbidden *application_auth.Forbidden
+	if _, err := client.Ask(context.Background(), &getUser{ID: "u1"}); !errors.As(err, &forbidden) {
+		t.Errorf("Ask = %v, want Forbidden", err)
+	}
+}
+
+func TestNATSQueryBusWithoutHandler(t *testing.T) {
+	client := NewNATSQueryBus(connect(t, runNATS(t)), application_query.InitQueryBus(nil), infrastructure_bus.NATSConfig{})
+
+	var notRegistered application_query.QueryNotRegistered
+	if _, err := client.Ask(context.Background(), &getUser{ID: "u1"}); !errors.As(err, &notRegistered) {
+		t.Errorf("Ask = %v, want QueryNotRegistered", err)
+	}
+}
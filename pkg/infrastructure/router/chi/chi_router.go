@@ -0,0 +1,78 @@
+package chi_router
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+var wildcardParam = regexp.MustCompile(`\{([^{}/]+)\.\.\.\}$`)
+
+var _ router.Router = (*ChiRouter)(nil)
+
+// ChiRouter implements router.Router on top of chi.
+type ChiRouter struct {
+	mux        *chi.Mux
+	prefix     string
+	middleware []router.Middleware
+}
+
+func NewChiRouter() *ChiRouter {
+	return &ChiRouter{mux: chi.NewRouter()}
+}
+
+// NewChiRouterFromMux wraps an already configured chi router.
+func NewChiRouterFromMux(mux *chi.Mux) *ChiRouter {
+	return &ChiRouter{mux: mux}
+}
+
+func (c *ChiRouter) Handle(method, path string, handler http.Handler, middleware ...router.Middleware) {
+	finalHandler := router.Wrap(router.Wrap(handler, middleware...), c.middleware...)
+	path = router.JoinPath(c.prefix, path)
+
+	// chi names the catch-all param "*", expose it with the name used in the pattern
+	if match := wildcardParam.FindStringSubmatch(path); match != nil {
+		name := match[1]
+		path = strings.TrimSuffix(path, match[0]) + "*"
+		next := finalHandler
+		finalHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.SetPathValue(name, chi.URLParam(r, "*"))
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	// chi only routes the standard methods and the ones registered, panicking on the others
+	chi.RegisterMethod(method)
+	c.mux.Method(method, path, finalHandler)
+}
+
+// Group returns a ChiRouter sharing the same mux that registers routes under prefix.
+func (c *ChiRouter) Group(prefix string, middleware ...router.Middleware) router.Router {
+	return &ChiRouter{
+		mux:        c.mux,
+		prefix:     router.JoinPath(c.prefix, prefix),
+		middleware: append(append([]router.Middleware{}, middleware...), c.middleware...),
+	}
+}
+
+func (c *ChiRouter) Serve(addr string) error {
+	return http.ListenAndServe(addr, c.mux)
+}
+
+func (c *ChiRouter) Handler() http.Handler {
+	return c.mux
+}
+
+func (c *ChiRouter) ServeStatic(url, absPath string) {
+	prefix := strings.TrimSuffix(router.JoinPath(c.prefix, url), "/")
+	fileServer := router.Wrap(http.StripPrefix(prefix, http.FileServer(http.Dir(absPath))), c.middleware...)
+	c.mux.Handle(prefix+"/*", fileServer)
+}
+
+// Mux returns the underlying chi router for chi specific configuration.
+func (c *ChiRouter) Mux() *chi.Mux {
+	return c.mux
+}
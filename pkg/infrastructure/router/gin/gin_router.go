@@ -1,13 +1,25 @@
 package gin_router
 
 import (
+	"net/http"
+	"regexp"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
-	"net/http"
 )
 
+var (
+	wildcardParam = regexp.MustCompile(`\{([^{}/]+)\.\.\.\}`)
+	segmentParam  = regexp.MustCompile(`\{([^{}/]+)\}`)
+)
+
+var _ router.Router = (*GinRouter)(nil)
+
 type GinRouter struct {
-	engine *gin.Engine
+	engine     *gin.Engine
+	prefix     string
+	middleware []router.Middleware
 }
 
 func NewGinRouter() *GinRouter {
@@ -15,25 +27,25 @@ func NewGinRouter() *GinRouter {
 	return &GinRouter{engine: engine}
 }
 
-func (g *GinRouter) Handle(method, path string, handler gin.HandlerFunc, middleware ...router.Middleware) {
-	// Wrap the gin.HandlerFunc with middleware
-	finalHandler := handler
-	for _, m := range middleware {
-		finalHandler = m(finalHandler)
-	}
+// NewGinRouterFromEngine wraps an already configured gin engine.
+func NewGinRouterFromEngine(engine *gin.Engine) *GinRouter {
+	return &GinRouter{engine: engine}
+}
+
+func (g *GinRouter) Handle(method, path string, handler http.Handler, middleware ...router.Middleware) {
+	// Wrap the handler with the route middleware first and the group middleware around it
+	finalHandler := toGinHandler(router.Wrap(router.Wrap(handler, middleware...), g.middleware...))
 
-	// Register the route
-	switch method {
-	case http.MethodGet:
-		g.engine.GET(path, finalHandler)
-	case http.MethodPost:
-		g.engine.POST(path, finalHandler)
-	case http.MethodPut:
-		g.engine.PUT(path, finalHandler)
-	case http.MethodDelete:
-		g.engine.DELETE(path, finalHandler)
-	default:
-		panic("unsupported HTTP method: " + method)
+	// Register the route, gin accepts any HTTP method
+	g.engine.Handle(method, toGinPath(router.JoinPath(g.prefix, path)), finalHandler)
+}
+
+// Group returns a GinRouter sharing the same engine that registers routes under prefix.
+func (g *GinRouter) Group(prefix string, middleware ...router.Middleware) router.Router {
+	return &GinRouter{
+		engine:     g.engine,
+		prefix:     router.JoinPath(g.prefix, prefix),
+		middleware: append(append([]router.Middleware{}, middleware...), g.middleware...),
 	}
 }
 
@@ -45,6 +57,36 @@ func (g *GinRouter) Handler() http.Handler {
 	return g.engine
 }
 
+// ServeStatic serves the files of absPath under url, wrapped with the group middleware.
 func (g *GinRouter) ServeStatic(url, absPath string) {
-	g.engine.Static(url, absPath)
+	prefix := strings.TrimSuffix(router.JoinPath(g.prefix, url), "/")
+	fileServer := toGinHandler(router.Wrap(http.StripPrefix(prefix, http.FileServer(http.Dir(absPath))), g.middleware...))
+	g.engine.GET(prefix+"/*filepath", fileServer)
+	g.engine.HEAD(prefix+"/*filepath", fileServer)
+}
+
+// Engine returns the underlying gin engine for gin specific configuration.
+func (g *GinRouter) Engine() *gin.Engine {
+	return g.engine
+}
+
+// toGinHandler adapts an http.Handler, exposing gin params through http.Request.PathValue.
+func toGinHandler(handler http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, p := range c.Params {
+			value := p.Value
+			// gin keeps the leading slash on catch-all params, ServeMux doesn't
+			if len(value) > 0 && value[0] == '/' {
+				value = value[1:]
+			}
+			c.Request.SetPathValue(p.Key, value)
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// toGinPath converts "/users/{id}" and "/files/{path...}" into "/users/:id" and "/files/*path".
+func toGinPath(path string) string {
+	path = wildcardParam.ReplaceAllString(path, "*$1")
+	return segmentParam.ReplaceAllString(path, ":$1")
 }
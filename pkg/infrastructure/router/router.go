@@ -1,23 +1,57 @@
 package router
 
 import (
-	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
 )
 
-type Middleware func(gin.HandlerFunc) gin.HandlerFunc
+// Middleware wraps an http.Handler, it is shared by every Router implementation.
+type Middleware func(http.Handler) http.Handler
 
 // Route represents an HTTP route with its method, path, handler, and middleware.
 type Route struct {
 	Method      string
 	Path        string
-	Handler     gin.HandlerFunc
+	Handler     http.HandlerFunc
 	Middlewares []Middleware
 }
 
+// Router is the framework-neutral router used by the kernel.
+//
+// Paths use the net/http ServeMux syntax: "/users/{id}" for a single segment and
+// "/files/{path...}" for the remaining path. Implementations expose path params
+// through http.Request.PathValue.
 type Router interface {
-	Handle(method, path string, handler http.HandlerFunc, middleware ...Middleware)
+	Handle(method, path string, handler http.Handler, middleware ...Middleware)
 	Serve(addr string) error
 	Handler() http.Handler
-	ServeStatic(url, absPath string) http.Handler
+	ServeStatic(url, absPath string)
+	// Group returns a Router registering its routes under prefix, wrapped with the given middleware.
+	Group(prefix string, middleware ...Middleware) Router
+}
+
+// Wrap applies the middlewares to the handler, the last middleware being the outermost.
+func Wrap(handler http.Handler, middleware ...Middleware) http.Handler {
+	for _, m := range middleware {
+		handler = m(handler)
+	}
+	return handler
+}
+
+// JoinPath joins a group prefix and a route path, "/api" and "/" resulting in "/api".
+func JoinPath(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if path == "" || path == "/" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return prefix + path
 }
@@ -0,0 +1,212 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+	chi_router "github.com/thebranchcrafter/go-kit/pkg/infrastructure/router/chi"
+	gin_router "github.com/thebranchcrafter/go-kit/pkg/infrastructure/router/gin"
+	servemux_router "github.com/thebranchcrafter/go-kit/pkg/infrastructure/router/servemux"
+)
+
+// routers returns a new instance of every Router implementation, by name.
+func routers() map[string]func() router.Router {
+	gin.SetMode(gin.TestMode)
+	return map[string]func() router.Router{
+		"chi":      func() router.Router { return chi_router.NewChiRouter() },
+		"gin":      func() router.Router { return gin_router.NewGinRouter() },
+		"servemux": func() router.Router { return servemux_router.NewServeMuxRouter() },
+	}
+}
+
+// forEachRouter runs test against every Router implementation.
+func forEachRouter(t *testing.T, test func(t *testing.T, r router.Router)) {
+	for name, newRouter := range routers() {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRouter())
+		})
+	}
+}
+
+func serve(r router.Router, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+// writePathValues answers with the path values named names, joined by "|".
+func writePathValues(names ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = r.PathValue(name)
+		}
+		_, _ = io.WriteString(w, strings.Join(values, "|"))
+	})
+}
+
+func TestRouterPathValues(t *testing.T) {
+	forEachRouter(t, func(t *testing.T, r router.Router) {
+		r.Handle(http.MethodGet, "/users/{id}", writePathValues("id"))
+		r.Handle(http.MethodGet, "/users/{id}/orders/{orderID}", writePathValues("id", "orderID"))
+		r.Handle(http.MethodGet, "/files/{path...}", writePathValues("path"))
+
+		tests := []struct {
+			target string
+			status int
+			body   string
+		}{
+			{"/users/42", http.StatusOK, "42"},
+			{"/users/42/orders/o1", http.StatusOK, "42|o1"},
+			{"/files/docs/guide/index.html", http.StatusOK, "docs/guide/index.html"},
+			{"/files/readme", http.StatusOK, "readme"},
+			{"/missing", http.StatusNotFound, ""},
+		}
+		for _, tt := range tests {
+			w := serve(r, http.MethodGet, tt.target)
+			if w.Code != tt.status || (tt.status == http.StatusOK && w.Body.String() != tt.body) {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.target, w.Code, w.Body.String(), tt.status, tt.body)
+			}
+		}
+	})
+}
+
+func TestRouterMethods(t *testing.T) {
+	methods := []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodTrace, "PURGE",
+	}
+
+	forEachRouter(t, func(t *testing.T, r router.Router) {
+		for _, method := range methods {
+			r.Handle(method, "/resource", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("X-Method", method)
+			}))
+		}
+		r.Handle(http.MethodPost, "/post-only", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+		for _, method := range methods {
+			if w := serve(r, method, "/resource"); w.Code != http.StatusOK || w.Header().Get("X-Method") != method {
+				t.Errorf("%s /resource = %d handled by %q", method, w.Code, w.Header().Get("X-Method"))
+			}
+		}
+		if w := serve(r, http.MethodDelete, "/post-only"); w.Code == http.StatusOK {
+			t.Error("DELETE /post-only routed to the POST handler")
+		}
+	})
+}
+
+// recorder records the order middleware and handlers run in.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (rec *recorder) record(name string) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.calls = append(rec.calls, name)
+}
+
+func (rec *recorder) middleware(name string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec.record(name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rec *recorder) handler(name string) http.Handler {
+	return http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		rec.record(name)
+	})
+}
+
+func (rec *recorder) reset() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	calls := rec.calls
+	rec.calls = nil
+	return calls
+}
+
+func TestRouterGroups(t *testing.T) {
+	forEachRouter(t, func(t *testing.T, r router.Router) {
+		rec := &recorder{}
+		api := r.Group("/api", rec.middleware("api"))
+		v1 := api.Group("v1/", rec.middleware("v1"))
+
+		r.Handle(http.MethodGet, "/health", rec.handler("health"))
+		api.Handle(http.MethodGet, "/", rec.handler("api root"))
+		v1.Handle(http.MethodGet, "/users/{id}", rec.handler("user"), rec.middleware("first"), rec.middleware("last"))
+
+		tests := []struct {
+			target string
+			calls  string
+		}{
+			{"/health", "health"},
+			{"/api", "api, api root"},
+			// Outer groups run first, the last middleware of a list being the outermost
+			{"/api/v1/users/42", "api, v1, last, first, user"},
+			{"/v1/users/42", ""},
+		}
+		for _, tt := range tests {
+			serve(r, http.MethodGet, tt.target)
+			if calls := strings.Join(rec.reset(), ", "); calls != tt.calls {
+				t.Errorf("GET %s ran %q, want %q", tt.target, calls, tt.calls)
+			}
+		}
+	})
+}
+
+func TestRouterServeStatic(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	forEachRouter(t, func(t *testing.T, r router.Router) {
+		rec := &recorder{}
+		r.ServeStatic("/assets/", dir)
+		r.Group("/admin", rec.middleware("admin")).ServeStatic("/static", dir)
+
+		for _, target := range []string{"/assets/app.js", "/admin/static/app.js"} {
+			if w := serve(r, http.MethodGet, target); w.Code != http.StatusOK || w.Body.String() != "console.log(1)" {
+				t.Errorf("GET %s = %d %q, want the file", target, w.Code, w.Body.String())
+			}
+		}
+		if calls := rec.reset(); len(calls) != 1 || calls[0] != "admin" {
+			t.Errorf("middleware ran %v, want the group middleware once", calls)
+		}
+		if w := serve(r, http.MethodGet, "/assets/missing.js"); w.Code != http.StatusNotFound {
+			t.Errorf("GET /assets/missing.js = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		prefix, path, want string
+	}{
+		{"", "", "/"},
+		{"", "/", "/"},
+		{"", "users", "/users"},
+		{"/api", "/", "/api"},
+		{"api/", "users", "/api/users"},
+		{"/api", "/users/{id}", "/api/users/{id}"},
+	}
+	for _, tt := range tests {
+		if got := router.JoinPath(tt.prefix, tt.path); got != tt.want {
+			t.Errorf("JoinPath(%q, %q) = %q, want %q", tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
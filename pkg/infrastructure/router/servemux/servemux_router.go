@@ -0,0 +1,49 @@
+package servemux_router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/thebranchcrafter/go-kit/pkg/infrastructure/router"
+)
+
+var _ router.Router = (*ServeMuxRouter)(nil)
+
+// ServeMuxRouter implements router.Router on top of the standard library http.ServeMux (Go 1.22 patterns).
+type ServeMuxRouter struct {
+	mux        *http.ServeMux
+	prefix     string
+	middleware []router.Middleware
+}
+
+func NewServeMuxRouter() *ServeMuxRouter {
+	return &ServeMuxRouter{mux: http.NewServeMux()}
+}
+
+func (s *ServeMuxRouter) Handle(method, path string, handler http.Handler, middleware ...router.Middleware) {
+	finalHandler := router.Wrap(router.Wrap(handler, middleware...), s.middleware...)
+	s.mux.Handle(method+" "+router.JoinPath(s.prefix, path), finalHandler)
+}
+
+// Group returns a ServeMuxRouter sharing the same mux that registers routes under prefix.
+func (s *ServeMuxRouter) Group(prefix string, middleware ...router.Middleware) router.Router {
+	return &ServeMuxRouter{
+		mux:        s.mux,
+		prefix:     router.JoinPath(s.prefix, prefix),
+		middleware: append(append([]router.Middleware{}, middleware...), s.middleware...),
+	}
+}
+
+func (s *ServeMuxRouter) Serve(addr string) error {
+	return http.ListenAndServe(addr, s.mux)
+}
+
+func (s *ServeMuxRouter) Handler() http.Handler {
+	return s.mux
+}
+
+func (s *ServeMuxRouter) ServeStatic(url, absPath string) {
+	prefix := strings.TrimSuffix(router.JoinPath(s.prefix, url), "/")
+	fileServer := router.Wrap(http.StripPrefix(prefix, http.FileServer(http.Dir(absPath))), s.middleware...)
+	s.mux.Handle(http.MethodGet+" "+prefix+"/", fileServer)
+}
@@ -0,0 +1,186 @@
+package infrastructure_saga
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	application_saga "github.com/thebranchcrafter/go-kit/pkg/application/saga"
+	infrastructure_sql "github.com/thebranchcrafter/go-kit/pkg/infrastructure/sql"
+)
+
+var _ application_saga.SagaStore = (*SQLSagaStore)(nil)
+
+// SQLSagaStore implements application_saga.SagaStore with a SQL table, which must exist:
+//
+//	CREATE TABLE sagas (
+//		name       VARCHAR(255) NOT NULL,
+//		id         VARCHAR(255) NOT NULL,
+//		status     VARCHAR(16)  NOT NULL,
+//		data       TEXT         NOT NULL,
+//		deadline   BIGINT,
+//		outbox     TEXT,
+//		version    INT          NOT NULL,
+//		updated_at BIGINT       NOT NULL,
+//		PRIMARY KEY (name, id)
+//	);
+//
+// deadline and updated_at hold Unix milliseconds, deadline being NULL for instances without timeout.
+// outbox holds the JSON encoded commands left to dispatch, NULL when there is none.
+type SQLSagaStore struct {
+	db    *sql.DB
+	table infrastructure_sql.Table
+	now   func() time.Time
+}
+
+// NewSQLSagaStore creates a new SQLSagaStore on table, which is used verbatim in the queries and must
+// be an identifier.
+func NewSQLSagaStore(db *sql.DB, table string, options ...func(*SQLSagaStore)) *SQLSagaStore {
+	s := &SQLSagaStore{db: db, table: infrastructure_sql.NewTable(table), now: time.Now}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// WithDollarPlaceholders uses $1 placeholders, as PostgreSQL drivers expect, instead of ?.
+func WithDollarPlaceholders() func(*SQLSagaStore) {
+	return func(s *SQLSagaStore) {
+		s.table = s.table.WithDollarPlaceholders()
+	}
+}
+
+func (s *SQLSagaStore) Load(ctx context.Context, name, id string) (*application_saga.State, error) {
+	row := s.db.QueryRowContext(ctx, s.table.Query(
+		"SELECT name, id, status, data, deadline, outbox, version, updated_at FROM {table} WHERE name = ? AND id = ?"), name, id)
+
+	state, err := scanState(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saga %s %s: %w", name, id, err)
+	}
+	return state, nil
+}
+
+func (s *SQLSagaStore) Save(ctx context.Context, state *application_saga.State) error {
+	now := s.now()
+	var deadline sql.NullInt64
+	if !state.Deadline.IsZero() {
+		deadline = sql.NullInt64{Int64: state.Deadline.UnixMilli(), Valid: true}
+	}
+	var outbox sql.NullString
+	if len(state.Outbox) > 0 {
+		data, err := json.Marshal(state.Outbox)
+		if err != nil {
+			return fmt.Errorf("failed to encode saga %s %s outbox: %w", state.Name, state.ID, err)
+		}
+		outbox = sql.NullString{String: string(data), Valid: true}
+	}
+
+	if state.Version == 0 {
+		_, err := s.db.ExecContext(ctx, s.table.Query(
+			"INSERT INTO {table} (name, id, status, data, deadline, outbox, version, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+			state.Name, state.ID, string(state.Status), string(state.Data), deadline, outbox, 1, now.UnixMilli())
+		if err != nil {
+			// The instance has been started concurrently, violating the primary key
+			if existing, loadErr := s.Load(ctx, state.Name, state.ID); loadErr == nil && existing != nil {
+				return application_saga.NewConcurrencyError(state.Name, state.ID)
+			}
+			return fmt.Errorf("failed to save saga %s %s: %w", state.Name, state.ID, err)
+		}
+	} else {
+		res, err := s.db.ExecContext(ctx, s.table.Query(
+			"UPDATE {table} SET status = ?, data = ?, deadline = ?, outbox = ?, version = ?, updated_at = ? WHERE name = ? AND id = ? AND version = ?"),
+			string(state.Status), string(state.Data), deadline, outbox, state.Version+1, now.UnixMilli(), state.Name, state.ID, state.Version)
+		if err != nil {
+			return fmt.Errorf("failed to save saga %s %s: %w", state.Name, state.ID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return application_saga.NewConcurrencyError(state.Name, state.ID)
+		}
+	}
+
+	state.Version++
+	state.UpdatedAt = now
+	return nil
+}
+
+func (s *SQLSagaStore) Expired(ctx context.Context, name string, now time.Time, limit int) ([]*application_saga.State, error) {
+	rows, err := s.db.QueryContext(ctx, s.table.Query(
+		"SELECT name, id, status, data, deadline, outbox, version, updated_at FROM {table} "+
+			"WHERE name = ? AND status = ? AND deadline IS NOT NULL AND deadline < ? ORDER BY deadline"+limitClause(limit)),
+		name, string(application_saga.StatusRunning), now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expired sagas %s: %w", name, err)
+	}
+	return scanStates(rows)
+}
+
+func (s *SQLSagaStore) Pending(ctx context.Context, name string, before time.Time, limit int) ([]*application_saga.State, error) {
+	rows, err := s.db.QueryContext(ctx, s.table.Query(
+		"SELECT name, id, status, data, deadline, outbox, version, updated_at FROM {table} "+
+			"WHERE name = ? AND outbox IS NOT NULL AND updated_at < ? ORDER BY updated_at"+limitClause(limit)),
+		name, before.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending sagas %s: %w", name, err)
+	}
+	return scanStates(rows)
+}
+
+// limitClause returns the LIMIT clause of limit, none when it is not positive as MemoryStore does.
+func limitClause(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(limit)
+}
+
+// scanStates scans and closes rows selected with the columns of the table, in order.
+func scanStates(rows *sql.Rows) ([]*application_saga.State, error) {
+	defer rows.Close()
+
+	var states []*application_saga.State
+	for rows.Next() {
+		state, err := scanState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+// scanState scans a row selected with the columns of the table, in order.
+func scanState(row interface{ Scan(...interface{}) error }) (*application_saga.State, error) {
+	var (
+		state     application_saga.State
+		status    string
+		data      string
+		deadline  sql.NullInt64
+		outbox    sql.NullString
+		updatedAt int64
+	)
+	if err := row.Scan(&state.Name, &state.ID, &status, &data, &deadline, &outbox, &state.Version, &updatedAt); err != nil {
+		return nil, err
+	}
+	if outbox.Valid {
+		if err := json.Unmarshal([]byte(outbox.String), &state.Outbox); err != nil {
+			return nil, fmt.Errorf("failed to decode saga %s %s outbox: %w", state.Name, state.ID, err)
+		}
+	}
+
+	state.Status = application_saga.Status(status)
+	state.Data = []byte(data)
+	if deadline.Valid {
+		state.Deadline = time.UnixMilli(deadline.Int64)
+	}
+	state.UpdatedAt = time.UnixMilli(updatedAt)
+	return &state, nil
+}
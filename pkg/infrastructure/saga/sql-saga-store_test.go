@@ -0,0 +1,220 @@
+package infrastructure_saga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	application_saga "github.com/thebranchcrafter/go-kit/pkg/application/saga"
+	_ "modernc.org/sqlite"
+)
+
+// newTestSQLSagaStore creates the sagas table in an in-memory SQLite database, the store reading the
+// time from clock.
+func newTestSQLSagaStore(t *testing.T, clock *time.Time, options ...func(*SQLSagaStore)) *SQLSagaStore {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	// Every connection opens its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE sagas (
+		name       VARCHAR(255) NOT NULL,
+		id         VARCHAR(255) NOT NULL,
+		status     VARCHAR(16)  NOT NULL,
+		data       TEXT         NOT NULL,
+		deadline   BIGINT,
+		outbox     TEXT,
+		version    INT          NOT NULL,
+		updated_at BIGINT       NOT NULL,
+		PRIMARY KEY (name, id)
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create sagas table: %v", err)
+	}
+
+	store := NewSQLSagaStore(db, "sagas", options...)
+	store.now = func() time.Time { return *clock }
+	return store
+}
+
+func ids(states []*application_saga.State) []string {
+	ids := make([]string, len(states))
+	for i, state := range states {
+		ids[i] = state.ID
+	}
+	return ids
+}
+
+func TestSQLSagaStoreSaveAndLoad(t *testing.T) {
+	for name, options := range map[string][]func(*SQLSagaStore){
+		"question mark placeholders": nil,
+		"dollar placeholders":        {WithDollarPlaceholders()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := time.UnixMilli(time.Now().UnixMilli())
+			store := newTestSQLSagaStore(t, &clock, options...)
+
+			if state, err := store.Load(ctx, "order", "o1"); state != nil || err != nil {
+				t.Fatalf("Load of a missing instance = %v, %v, want nil, nil", state, err)
+			}
+
+			state := &application_saga.State{
+				Name:     "order",
+				ID:       "o1",
+				Status:   application_saga.StatusRunning,
+				Data:     []byte(`{"step":1}`),
+				Deadline: clock.Add(time.Hour),
+				Outbox:   []application_saga.OutboxCommand{{Name: "ship_order", Payload: []byte(`{"order_id":"o1"}`)}},
+			}
+			if err := store.Save(ctx, state); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if state.Version != 1 || !state.UpdatedAt.Equal(clock) {
+				t.Errorf("saved version %d at %v, want 1 at %v", state.Version, state.UpdatedAt, clock)
+			}
+
+			loaded, err := store.Load(ctx, "order", "o1")
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if loaded.Status != state.Status || string(loaded.Data) != string(state.Data) || !loaded.Deadline.Equal(state.Deadline) ||
+				loaded.Version != 1 || !loaded.UpdatedAt.Equal(clock) {
+				t.Errorf("Load = %+v, want %+v", loaded, state)
+			}
+			if len(loaded.Outbox) != 1 || loaded.Outbox[0].Name != "ship_order" || string(loaded.Outbox[0].Payload) != `{"order_id":"o1"}` {
+				t.Errorf("Outbox = %+v", loaded.Outbox)
+			}
+
+			loaded.Status = application_saga.StatusCompleted
+			loaded.Deadline = time.Time{}
+			loaded.Outbox = nil
+			if err := store.Save(ctx, loaded); err != nil {
+				t.Fatalf("Save of the loaded instance: %v", err)
+			}
+			if loaded, _ = store.Load(ctx, "order", "o1"); loaded.Version != 2 || loaded.Status != application_saga.StatusCompleted ||
+				!loaded.Deadline.IsZero() || loaded.Outbox != nil {
+				t.Errorf("Load = %+v, want the completed instance at version 2", loaded)
+			}
+		})
+	}
+}
+
+func TestSQLSagaStoreConcurrentSaves(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	store := newTestSQLSagaStore(t, &clock)
+
+	if err := store.Save(ctx, &application_saga.State{Name: "order", ID: "o1", Status: application_saga.StatusRunning}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// The instance started concurrently
+	var concurrencyErr application_saga.ConcurrencyError
+	err := store.Save(ctx, &application_saga.State{Name: "order", ID: "o1", Status: application_saga.StatusRunning})
+	if !errors.As(err, &concurrencyErr) {
+		t.Errorf("Save of a started instance = %v, want a ConcurrencyError", err)
+	}
+
+	// The instance advanced concurrently
+	first, _ := store.Load(ctx, "order", "o1")
+	second, _ := store.Load(ctx, "order", "o1")
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save(ctx, second); !errors.As(err, &concurrencyErr) {
+		t.Errorf("Save of a stale version = %v, want a ConcurrencyError", err)
+	}
+	if second.Version != 1 {
+		t.Errorf("stale instance version = %d, want it left at 1", second.Version)
+	}
+}
+
+func TestSQLSagaStoreExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := newTestSQLSagaStore(t, &now)
+
+	for _, state := range []*application_saga.State{
+		{Name: "order", ID: "late", Status: application_saga.StatusRunning, Deadline: now.Add(-time.Minute)},
+		{Name: "order", ID: "later", Status: application_saga.StatusRunning, Deadline: now.Add(-time.Hour)},
+		{Name: "order", ID: "latest", Status: application_saga.StatusRunning, Deadline: now.Add(-2 * time.Hour)},
+		{Name: "order", ID: "running", Status: application_saga.StatusRunning, Deadline: now.Add(time.Minute)},
+		{Name: "order", ID: "no deadline", Status: application_saga.StatusRunning},
+		{Name: "order", ID: "completed", Status: application_saga.StatusCompleted, Deadline: now.Add(-time.Hour)},
+		{Name: "payment", ID: "other saga", Status: application_saga.StatusRunning, Deadline: now.Add(-time.Hour)},
+	} {
+		if err := store.Save(ctx, state); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{2, []string{"latest", "later"}},
+		{10, []string{"latest", "later", "late"}},
+		{0, []string{"latest", "later", "late"}},
+	}
+	for _, tt := range tests {
+		expired, err := store.Expired(ctx, "order", now, tt.limit)
+		if err != nil {
+			t.Fatalf("Expired: %v", err)
+		}
+		if got := ids(expired); !slices.Equal(got, tt.want) {
+			t.Errorf("Expired(limit %d) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSQLSagaStorePending(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	store := newTestSQLSagaStore(t, &clock)
+	outbox := []application_saga.OutboxCommand{{Name: "ship_order", Payload: []byte(`{}`)}}
+
+	// The instances are saved a minute apart, the most recent one first
+	start := clock
+	for _, state := range []*application_saga.State{
+		{Name: "order", ID: "recent", Status: application_saga.StatusRunning, Outbox: outbox},
+		{Name: "order", ID: "old", Status: application_saga.StatusRunning, Outbox: outbox},
+		{Name: "order", ID: "oldest", Status: application_saga.StatusCompleted, Outbox: outbox},
+		{Name: "order", ID: "dispatched", Status: application_saga.StatusRunning},
+		{Name: "payment", ID: "other saga", Status: application_saga.StatusRunning, Outbox: outbox},
+	} {
+		clock = clock.Add(-time.Minute)
+		if err := store.Save(ctx, state); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	tests := []struct {
+		before time.Time
+		limit  int
+		want   []string
+	}{
+		{start, 2, []string{"oldest", "old"}},
+		{start, 0, []string{"oldest", "old", "recent"}},
+		{start.Add(-time.Minute), 10, []string{"oldest", "old"}},
+	}
+	for _, tt := range tests {
+		pending, err := store.Pending(ctx, "order", tt.before, tt.limit)
+		if err != nil {
+			t.Fatalf("Pending: %v", err)
+		}
+		if got := ids(pending); !slices.Equal(got, tt.want) {
+			t.Errorf("Pending(%v, limit %d) = %v, want %v", tt.before.Sub(start), tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package infrastructure_sql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Table writes the queries of a SQL store on its table.
+type Table struct {
+	name   string
+	dollar bool
+}
+
+// NewTable creates a Table writing ? placeholders. name is written verbatim in the queries, it panics
+// unless name is an identifier, optionally qualified by a schema.
+func NewTable(name string) Table {
+	if !identifier.MatchString(name) {
+		panic(fmt.Sprintf("infrastructure_sql: invalid table name %q", name))
+	}
+	return Table{name: name}
+}
+
+// WithDollarPlaceholders returns a copy of the table writing $1 placeholders, as PostgreSQL drivers
+// expect.
+func (t Table) WithDollarPlaceholders() Table {
+	t.dollar = true
+	return t
+}
+
+// Query replaces {table} with the table name in query and, with dollar placeholders, numbers its ?
+// placeholders.
+func (t Table) Query(query string) string {
+	query = strings.ReplaceAll(query, "{table}", t.name)
+	if !t.dollar {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
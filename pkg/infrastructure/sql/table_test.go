@@ -0,0 +1,35 @@
+package infrastructure_sql
+
+import "testing"
+
+func TestTableQuery(t *testing.T) {
+	query := "UPDATE {table} SET status = ? WHERE id = ? AND name LIKE '%s'"
+
+	if got := NewTable("sagas").Query(query); got != "UPDATE sagas SET status = ? WHERE id = ? AND name LIKE '%s'" {
+		t.Errorf("Query = %q", got)
+	}
+	if got := NewTable("public.sagas").WithDollarPlaceholders().Query(query); got != "UPDATE public.sagas SET status = $1 WHERE id = $2 AND name LIKE '%s'" {
+		t.Errorf("Query with dollar placeholders = %q", got)
+	}
+
+	many := "INSERT INTO {table} VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if got := NewTable("sagas").WithDollarPlaceholders().Query(many); got != "INSERT INTO sagas VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)" {
+		t.Errorf("Query with dollar placeholders = %q", got)
+	}
+	if got := NewTable("sagas").WithDollarPlaceholders().Query("SELECT 1 FROM {table}"); got != "SELECT 1 FROM sagas" {
+		t.Errorf("Query without placeholders = %q", got)
+	}
+}
+
+func TestNewTableRejectsInvalidNames(t *testing.T) {
+	for _, name := range []string{"", "1sagas", "sagas; DROP TABLE users", "a.b.c", "sa-gas"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewTable(%q) didn't panic", name)
+				}
+			}()
+			NewTable(name)
+		}()
+	}
+}